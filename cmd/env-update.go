@@ -1,5 +1,5 @@
 /*
-	Copyright © 2021-2022 Macaroni OS Linux
+	Copyright © 2021-2026 Macaroni OS Linux
 	See AUTHORS and LICENSE for the license details and contributors.
 */
 
@@ -56,7 +56,7 @@ $> macaronictl env-update
 			opts.WithLdConfig = config.GetEnvUpdate().Ldconfig
 			opts.Debug = config.GetGeneral().Debug
 
-			err := portage.EnvUpdate("/", opts)
+			err := portage.EnvUpdate(config.GetGeneral().GetRootfs(), opts)
 			if err != nil {
 				log.Error(err.Error())
 				os.Exit(1)
@@ -1,5 +1,5 @@
 /*
-	Copyright © 2021-2023 Macaroni OS Linux
+	Copyright © 2021-2026 Macaroni OS Linux
 	See AUTHORS and LICENSE for the license details and contributors.
 */
 
@@ -31,7 +31,7 @@ $> macaronictl etc-update
 		Run: func(cmd *cobra.Command, args []string) {
 			log := logger.GetDefaultLogger()
 
-			rootfs, _ := cmd.Flags().GetString("rootfs")
+			rootfs := config.GetGeneral().GetRootfs()
 			paths, _ := cmd.Flags().GetStringArray("path")
 			mpaths, _ := cmd.Flags().GetStringArray("mask-path")
 
@@ -48,8 +48,6 @@ $> macaronictl etc-update
 	}
 
 	flags := c.Flags()
-	flags.String("rootfs", "/",
-		"Override the default path where run etc-update. (experimental)")
 	flags.StringArrayP("path", "p", []string{},
 		"Scan one or more specific paths (CONFIG_PROTECT).")
 	flags.StringArrayP("mask-path", "m", []string{},
@@ -1,5 +1,5 @@
 /*
-	Copyright © 2021-2023 Macaroni OS Linux
+	Copyright © 2021-2026 Macaroni OS Linux
 	See AUTHORS and LICENSE for the license details and contributors.
 */
 
@@ -24,7 +24,21 @@ func kernelCmdCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
 		cmdkernel.NewAvailableCommand(config),
 		cmdkernel.NewModulesCommand(config),
 		cmdkernel.NewSwitchCommand(config),
+		cmdkernel.NewUpgradeCommand(config),
+		cmdkernel.NewRemoveCommand(config),
+		cmdkernel.NewPruneCommand(config),
 		cmdkernel.NewGeninitrdCommand(config),
+		cmdkernel.NewInitrdCommand(config),
+		cmdkernel.NewConfigCommand(config),
+		cmdkernel.NewCmdlineCommand(config),
+		cmdkernel.NewUKICommand(config),
+		cmdkernel.NewSignCommand(config),
+		cmdkernel.NewVerifySignatureCommand(config),
+		cmdkernel.NewRollbackCommand(config),
+		cmdkernel.NewDefaultCommand(config),
+		cmdkernel.NewBootOnceCommand(config),
+		cmdkernel.NewDoctorCommand(config),
+		cmdkernel.NewStatusCommand(config),
 		cmdkernel.NewProfilesCommand(config),
 	)
 
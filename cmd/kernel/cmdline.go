@@ -0,0 +1,199 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmdkernel
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/macaroni-os/macaronictl/pkg/kernel"
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+
+	"github.com/spf13/cobra"
+)
+
+func NewCmdlineCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "cmdline",
+		Short: "Manage the kernel command line.",
+		Long: `Manage the kernel command line used by grub, BLS, extlinux and UKI.
+
+The base command line is stored in /etc/kernel/cmdline. The options
+of the kernel profiles and of the kernel versions are stored in the
+drop-ins of the /etc/kernel/cmdline.d directory:
+
+  /etc/kernel/cmdline.d/profile-<profile>.conf
+  /etc/kernel/cmdline.d/version-<version>.conf
+
+The options of the drop-ins replace the options of the base with the
+same key and an option with the "-" prefix removes it.
+
+NOTE: grub-mkconfig doesn't support options for single kernels and
+      so with grub it's used only the base command line.`,
+	}
+
+	c.AddCommand(
+		newCmdlineGetCommand(config),
+		newCmdlineEditCommand(config, "set"),
+		newCmdlineEditCommand(config, "add"),
+		newCmdlineEditCommand(config, "remove"),
+	)
+
+	return c
+}
+
+func addCmdlineLayerFlags(c *cobra.Command) {
+	flags := c.Flags()
+	flags.String("profile", "", "Use the options of the kernel profile.")
+	flags.String("version", "", "Use the options of the kernel version.")
+}
+
+func newCmdlineGetCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "get",
+		Short: "Show the kernel command line.",
+		Long: `Show the effective kernel command line of a profile and a version.
+
+$> macaronictl kernel cmdline get
+
+$> macaronictl kernel cmdline get --profile Macaroni --version 6.1.12
+
+$> # Show only the options of the drop-in of the profile.
+$> macaronictl kernel cmdline get --profile Macaroni --layer
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+			profile, _ := cmd.Flags().GetString("profile")
+			version, _ := cmd.Flags().GetString("version")
+			layer, _ := cmd.Flags().GetBool("layer")
+
+			cmdline := kernel.NewCmdline(config.GetGeneral().GetRootfs())
+
+			if layer {
+				if profile != "" && version != "" {
+					fmt.Println("The options --profile and --version are exclusive with --layer.")
+					os.Exit(1)
+				}
+
+				opts, err := cmdline.ReadLayer(profile, version)
+				if err != nil {
+					fmt.Println(err.Error())
+					os.Exit(1)
+				}
+				fmt.Println(strings.Join(opts, " "))
+				return
+			}
+
+			ans, err := cmdline.Resolve(profile, version)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+			fmt.Println(ans)
+		},
+	}
+
+	addCmdlineLayerFlags(c)
+	c.Flags().Bool("layer", false, "Show only the options of the selected layer.")
+
+	return c
+}
+
+func newCmdlineEditCommand(config *specs.MacaroniCtlConfig, action string) *cobra.Command {
+	var short, long string
+
+	switch action {
+	case "set":
+		short = "Replace the options of the kernel command line."
+		long = `Replace the options of the base command line or of a drop-in.
+
+$> macaronictl kernel cmdline set root=/dev/sda2 quiet
+
+$> # Without options the drop-in is removed.
+$> macaronictl kernel cmdline set --version 6.1.12
+`
+	case "add":
+		short = "Add options to the kernel command line."
+		long = `Add options to the base command line or to a drop-in. The options
+with the same key and another value are kept, use remove or set to replace
+them. In a drop-in the options of the base with the same key are replaced.
+
+$> macaronictl kernel cmdline add --profile "Macaroni Zen Kernel" mitigations=off
+
+$> macaronictl kernel cmdline add console=tty0 console=ttyS0,115200
+`
+	default:
+		short = "Remove options from the kernel command line."
+		long = `Remove the options with the same key from the base command line or
+from a drop-in. The options not defined in the drop-in are removed
+from the base command line of the profile or of the version.
+
+$> macaronictl kernel cmdline remove quiet
+
+$> macaronictl kernel cmdline remove --version 6.1.12 console
+`
+	}
+
+	c := &cobra.Command{
+		Use:   action + " [OPTIONS] -- <kernel-options>...",
+		Short: short,
+		Long:  long,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			profile, _ := cmd.Flags().GetString("profile")
+			version, _ := cmd.Flags().GetString("version")
+			if profile != "" && version != "" {
+				fmt.Println("The options --profile and --version are exclusive.")
+				os.Exit(1)
+			}
+			if action != "set" && len(args) == 0 {
+				fmt.Println("Missing mandatory argument.")
+				os.Exit(1)
+			}
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			profile, _ := cmd.Flags().GetString("profile")
+			version, _ := cmd.Flags().GetString("version")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+			cmdline := kernel.NewCmdline(config.GetGeneral().GetRootfs())
+
+			opts, err := cmdline.ReadLayer(profile, version)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			switch action {
+			case "set":
+				opts = args
+			case "add":
+				opts = kernel.AddCmdlineOptions(opts, args)
+			default:
+				opts = kernel.RemoveCmdlineOptions(opts, args,
+					profile != "" || version != "")
+			}
+
+			file := cmdline.GetLayerFile(profile, version)
+			if dryRun {
+				fmt.Println(fmt.Sprintf("[dry-run mode] writing %s: %s",
+					file, strings.Join(opts, " ")))
+				return
+			}
+
+			err = cmdline.WriteLayer(profile, version, opts)
+			if err != nil {
+				fmt.Println(fmt.Sprintf("Error on write %s: %s", file, err.Error()))
+				os.Exit(1)
+			}
+
+			fmt.Println(fmt.Sprintf("Updated %s.", file))
+		},
+	}
+
+	addCmdlineLayerFlags(c)
+	c.Flags().Bool("dry-run", false, "Show the options without write them.")
+
+	return c
+}
@@ -0,0 +1,238 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmdkernel
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/macaroni-os/macaronictl/pkg/kernel"
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+
+	tablewriter "github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+func NewConfigCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "config",
+		Short: "Show and compare the configuration of the kernels.",
+		Long: `Show and compare the configuration of the kernels available on boot dir.
+
+The configuration is read from the file config-<release> of the boot dir
+or from the IKCONFIG data embedded in the kernel image.`,
+	}
+
+	c.AddCommand(
+		newConfigShowCommand(config),
+		newConfigDiffCommand(config),
+	)
+
+	return c
+}
+
+func readBootDirKernelConfig(bootFiles *kernelspecs.BootFiles,
+	version, ktype string) (*kernelspecs.KernelFiles, kernel.KernelConfig, string) {
+
+	kf, err := bootFiles.GetFile(version, ktype)
+	if err != nil {
+		fmt.Println(fmt.Sprintf("%s (version %s)", err.Error(), version))
+		os.Exit(1)
+	}
+
+	kconfig, source, err := kernel.ReadKernelConfig(kf, bootFiles.Dir)
+	if err != nil {
+		fmt.Println(fmt.Sprintf("Error on read configuration of kernel %s: %s",
+			kf.Kernel.GetFilename(), err.Error()))
+		os.Exit(1)
+	}
+
+	return kf, kconfig, source
+}
+
+func newConfigShowCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "show",
+		Short: "Show the configuration of a kernel.",
+		Long: `Show the configuration of a kernel.
+
+$> macaronictl kernel config show --version 6.1.12
+
+$> # Show only the symbols that contain the string.
+$> macaronictl kernel config show --version 6.1.12 --filter CONFIG_BTRFS
+`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			version, _ := cmd.Flags().GetString("version")
+			if version == "" {
+				fmt.Println("You need to use --version")
+				os.Exit(1)
+			}
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+			bootDir := getBootDir(cmd, config)
+			version, _ := cmd.Flags().GetString("version")
+			ktype, _ := cmd.Flags().GetString("ktype")
+			filter, _ := cmd.Flags().GetString("filter")
+			kernelProfilesDir, _ := cmd.Flags().GetString("kernel-profiles-dir")
+
+			types := loadKernelTypes(config, kernelProfilesDir)
+
+			bootFiles, err := kernel.ReadBootDir(bootDir, types)
+			if err != nil {
+				fmt.Println("Error on read boot directory: " + err.Error())
+				os.Exit(1)
+			}
+
+			_, kconfig, source := readBootDirKernelConfig(bootFiles, version, ktype)
+
+			if filter != "" {
+				for symbol := range kconfig {
+					if !strings.Contains(symbol, filter) {
+						delete(kconfig, symbol)
+					}
+				}
+			}
+
+			if jsonOutput {
+				data, err := json.Marshal(map[string]interface{}{
+					"source": source,
+					"config": kconfig,
+				})
+				if err != nil {
+					fmt.Println(fmt.Errorf("Error on convert data to json: %s", err.Error()))
+					os.Exit(1)
+				}
+				fmt.Println(string(data))
+			} else {
+				fmt.Print(kconfig.String())
+			}
+		},
+	}
+
+	flags := c.Flags()
+	flags.Bool("json", false, "JSON output")
+	flags.String("bootdir", "",
+		"Directory where analyze kernel files. Default is the kernel.bootdir option of the config.")
+	flags.String("version", "", "Specify the kernel version.")
+	flags.String("ktype", "", "Specify the kernel type.")
+	flags.String("filter", "", "Show only the symbols that contain the string.")
+	flags.String("kernel-profiles-dir", "",
+		"Specify the directory where read the kernel types profiles supported.")
+
+	return c
+}
+
+func newConfigDiffCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "diff",
+		Short: "Show the differences between the configurations of two kernels.",
+		Long: `Show the CONFIG_* symbols added, removed and changed between
+the configurations of two kernels.
+
+$> macaronictl kernel config diff --from 6.1.12 --to 6.6.5
+
+$> macaronictl kernel config diff --from 6.1.12 --from-type vanilla --to 6.6.5 --to-type zen
+`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			from, _ := cmd.Flags().GetString("from")
+			to, _ := cmd.Flags().GetString("to")
+			if from == "" || to == "" {
+				fmt.Println("You need to use --from and --to")
+				os.Exit(1)
+			}
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+			bootDir := getBootDir(cmd, config)
+			from, _ := cmd.Flags().GetString("from")
+			fromType, _ := cmd.Flags().GetString("from-type")
+			to, _ := cmd.Flags().GetString("to")
+			toType, _ := cmd.Flags().GetString("to-type")
+			kernelProfilesDir, _ := cmd.Flags().GetString("kernel-profiles-dir")
+
+			types := loadKernelTypes(config, kernelProfilesDir)
+
+			bootFiles, err := kernel.ReadBootDir(bootDir, types)
+			if err != nil {
+				fmt.Println("Error on read boot directory: " + err.Error())
+				os.Exit(1)
+			}
+
+			fromKf, fromConfig, _ := readBootDirKernelConfig(bootFiles, from, fromType)
+			toKf, toConfig, _ := readBootDirKernelConfig(bootFiles, to, toType)
+
+			diff := kernel.DiffKernelConfig(fromConfig, toConfig)
+
+			if jsonOutput {
+				data, err := json.Marshal(diff)
+				if err != nil {
+					fmt.Println(fmt.Errorf("Error on convert data to json: %s", err.Error()))
+					os.Exit(1)
+				}
+				fmt.Println(string(data))
+				return
+			}
+
+			if len(diff.Changes) == 0 {
+				fmt.Println("No differences found.")
+				return
+			}
+
+			fmt.Println(fmt.Sprintf("From: %s", fromKf.Kernel.GetFilename()))
+			fmt.Println(fmt.Sprintf("To:   %s", toKf.Kernel.GetFilename()))
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.Header(
+				"Symbol",
+				"Change",
+				"From",
+				"To",
+			)
+
+			nAdded, nRemoved, nChanged := 0, 0, 0
+			for _, change := range diff.Changes {
+				switch change.Change {
+				case kernel.KernelConfigAdded:
+					nAdded++
+				case kernel.KernelConfigRemoved:
+					nRemoved++
+				default:
+					nChanged++
+				}
+
+				table.Append([]string{
+					change.Symbol,
+					change.Change,
+					change.Old,
+					change.New,
+				})
+			}
+
+			table.Render()
+
+			fmt.Println(fmt.Sprintf("Symbols added: %d, removed: %d, changed: %d.",
+				nAdded, nRemoved, nChanged))
+		},
+	}
+
+	flags := c.Flags()
+	flags.Bool("json", false, "JSON output")
+	flags.String("bootdir", "",
+		"Directory where analyze kernel files. Default is the kernel.bootdir option of the config.")
+	flags.String("from", "", "Specify the version of the first kernel.")
+	flags.String("from-type", "", "Specify the type of the first kernel.")
+	flags.String("to", "", "Specify the version of the second kernel.")
+	flags.String("to-type", "", "Specify the type of the second kernel.")
+	flags.String("kernel-profiles-dir", "",
+		"Specify the directory where read the kernel types profiles supported.")
+
+	return c
+}
@@ -0,0 +1,154 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmdkernel
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/macaroni-os/macaronictl/pkg/bootloader"
+	"github.com/macaroni-os/macaronictl/pkg/kernel"
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// Set the grubenv variable with the grub entry of the kernel
+// of the selected version.
+func setGrubEnvKernel(cmd *cobra.Command, config *specs.MacaroniCtlConfig,
+	version, variable string) error {
+
+	bootDir := getBootDir(cmd, config)
+	ktype, _ := cmd.Flags().GetString("ktype")
+	grubEnvFile, _ := cmd.Flags().GetString("grubenv")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	kernelProfilesDir, _ := cmd.Flags().GetString("kernel-profiles-dir")
+
+	types := loadKernelTypes(config, kernelProfilesDir)
+
+	bootFiles, err := kernel.ReadBootDir(bootDir, types)
+	if err != nil {
+		return fmt.Errorf("Error on read boot directory: %s", err.Error())
+	}
+
+	kf, err := bootFiles.GetFile(version, ktype)
+	if err != nil {
+		return err
+	}
+	if kf.Kernel == nil {
+		return fmt.Errorf("No kernel image found for version %s", version)
+	}
+
+	grubCfgFile := newBootloaderOpts(config, dryRun).GrubCfgFile
+	if grubCfgFile == "" {
+		grubCfgFile = filepath.Join(bootFiles.Dir, "grub", "grub.cfg")
+	}
+
+	entries, err := bootloader.ReadGrubCfg(grubCfgFile)
+	if err != nil {
+		return err
+	}
+
+	entry := bootloader.FindGrubEntry(entries, kf.Kernel.GetFilename())
+	if entry == nil {
+		return fmt.Errorf("No grub entry found for kernel %s. Update grub.cfg with --grub.",
+			kf.Kernel.GetFilename())
+	}
+
+	if grubEnvFile == "" {
+		grubEnvFile = bootloader.GrubEnvFile(grubCfgFile)
+	} else {
+		grubEnvFile = utils.RootfsPath(config.GetGeneral().GetRootfs(), grubEnvFile)
+	}
+
+	if dryRun {
+		fmt.Println(fmt.Sprintf("[dry-run mode] set %s=%s on %s",
+			variable, entry.Path, grubEnvFile))
+		return nil
+	}
+
+	err = bootloader.SetGrubEnvEntry(grubEnvFile, variable, entry.Path)
+	if err != nil {
+		return fmt.Errorf("Error on update %s: %s", grubEnvFile, err.Error())
+	}
+
+	fmt.Println(fmt.Sprintf("Set %s to %s (%s).", variable, entry.Title, entry.Path))
+
+	return nil
+}
+
+func addGrubEnvFlags(c *cobra.Command) {
+	flags := c.Flags()
+	flags.Bool("dry-run", false, "Show the grub entry without update grubenv.")
+	flags.String("ktype", "", "Specify the kernel type of the kernel.")
+	flags.String("grubenv", "",
+		"Path of the grubenv file. Default is the grubenv file in the directory of grub.cfg.")
+	flags.String("bootdir", "",
+		"Directory where analyze kernel files. Default is the kernel.bootdir option of the config.")
+	flags.String("kernel-profiles-dir", "",
+		"Specify the directory where read the kernel types profiles supported.")
+}
+
+func NewDefaultCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "default <version> [OPTIONS]",
+		Short: "Set the default grub entry to a kernel.",
+		Long: `Set the saved_entry of grubenv to the grub entry of the kernel.
+
+$> macaronictl kernel default 6.1.12
+
+$> macaronictl kernel default 6.1.12 --ktype zen
+
+NOTE: The saved entry is used by grub only with GRUB_DEFAULT=saved
+      in /etc/default/grub.
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			defaultFile := utils.RootfsPath(config.GetGeneral().GetRootfs(), "/etc/default/grub")
+			if utils.Exists(defaultFile) && !bootloader.GrubDefaultIsSaved(defaultFile) {
+				fmt.Println(fmt.Sprintf(
+					"WARN: GRUB_DEFAULT=saved is not set in %s. The saved entry is ignored by grub.",
+					defaultFile))
+			}
+
+			err := setGrubEnvKernel(cmd, config, args[0], "saved_entry")
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+
+	addGrubEnvFlags(c)
+
+	return c
+}
+
+func NewBootOnceCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "boot-once <version> [OPTIONS]",
+		Short: "Boot a kernel only on the next boot.",
+		Long: `Set the next_entry of grubenv to the grub entry of the kernel.
+The kernel is used only for the next boot and then grub returns
+to the default entry.
+
+$> macaronictl kernel boot-once 6.6.5
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			err := setGrubEnvKernel(cmd, config, args[0], "next_entry")
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+
+	addGrubEnvFlags(c)
+
+	return c
+}
@@ -0,0 +1,227 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmdkernel
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/macaroni-os/macaronictl/pkg/initrd"
+	"github.com/macaroni-os/macaronictl/pkg/kernel"
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+
+	tablewriter "github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// Return the kernel files to use for the broken links: the kernel of
+// the bzImage link if valid, otherwise the newest kernel available.
+func doctorLinksKernelFiles(bootFiles *kernelspecs.BootFiles) *kernelspecs.KernelFiles {
+	var ans *kernelspecs.KernelFiles = nil
+
+	bzImage, _ := os.Readlink(filepath.Join(bootFiles.Dir, "bzImage"))
+
+	for idx, f := range bootFiles.Files {
+		if f.Kernel == nil {
+			continue
+		}
+
+		if bzImage != "" && f.Kernel.GetFilename() == bzImage &&
+			utils.Exists(filepath.Join(bootFiles.Dir, bzImage)) {
+			return bootFiles.Files[idx]
+		}
+
+		if ans == nil || kernel.CompareVersions(
+			f.Kernel.GetVersion(), ans.Kernel.GetVersion()) > 0 {
+			ans = bootFiles.Files[idx]
+		}
+	}
+
+	return ans
+}
+
+func doctorFixIssue(issue *kernel.DoctorIssue, bootFiles *kernelspecs.BootFiles,
+	builderSelector *initrd.InitrdBuilderSelector, grubCfgFile, rootfs string) error {
+
+	switch issue.Check {
+	case kernel.DoctorCheckBrokenLink:
+		link := filepath.Join(bootFiles.Dir, issue.File)
+		err := os.Remove(link)
+		if err != nil {
+			return err
+		}
+
+		// The fallback links are only removed.
+		kf := doctorLinksKernelFiles(bootFiles)
+		if kf == nil {
+			return nil
+		}
+
+		switch issue.File {
+		case "bzImage":
+			return os.Symlink(kf.Kernel.GetFilename(), link)
+		case "Initrd":
+			if kf.Initrd != nil {
+				return os.Symlink(kf.Initrd.GetFilename(), link)
+			}
+		}
+
+	case kernel.DoctorCheckMissingInitrd, kernel.DoctorCheckEmptyInitrd:
+		return builderSelector.Build(issue.KernelFiles, bootFiles.Dir)
+
+	case kernel.DoctorCheckStaleGrub:
+		if grubCfgFile == "" {
+			grubCfgFile = issue.File
+		}
+		return kernel.GrubMkconfig(grubCfgFile, rootfs, false)
+
+	default:
+		return fmt.Errorf("No fix available for check %s", issue.Check)
+	}
+
+	return nil
+}
+
+func NewDoctorCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the consistency of kernels, initrd and links.",
+		Long: `Analyze the boot directory, the modules directory and the links
+to find inconsistencies.
+
+$> macaronictl kernel doctor
+
+$> # Repair the safe cases: broken links, missing or empty initrd images
+$> # and stale grub.cfg. The orphan initrd images are only reported.
+$> macaronictl kernel doctor --fix
+
+The command exits with 0 if no problems are found, 1 if there are
+only warnings and 2 if there are errors.
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+			fix, _ := cmd.Flags().GetBool("fix")
+			bootDir := getBootDir(cmd, config)
+			modulesDir := getModulesDir(cmd, config)
+			grubCfgFile, _ := cmd.Flags().GetString("grub-cfg")
+			if grubCfgFile == "" {
+				grubCfgFile = config.GetKernel().GetGrubCfg()
+			}
+			grubCfgFile = utils.RootfsPath(config.GetGeneral().GetRootfs(), grubCfgFile)
+			dracutOpts, _ := cmd.Flags().GetString("dracut-opts")
+			initrdBuilder, _ := cmd.Flags().GetString("initrd-builder")
+			kernelProfilesDir, _ := cmd.Flags().GetString("kernel-profiles-dir")
+
+			types := loadKernelTypes(config, kernelProfilesDir)
+
+			bootFiles, err := kernel.ReadBootDir(bootDir, types)
+			if err != nil {
+				fmt.Println("Error on read boot directory: " + err.Error())
+				os.Exit(2)
+			}
+
+			report, err := kernel.Doctor(bootFiles, &kernel.DoctorOpts{
+				ModulesDir:  modulesDir,
+				GrubCfgFile: grubCfgFile,
+			})
+			if err != nil {
+				fmt.Println("Error on analyze kernel files: " + err.Error())
+				os.Exit(2)
+			}
+
+			if fix {
+				builderSelector := newInitrdBuilderSelector(config, initrdBuilder,
+					dracutOpts, false)
+
+				// The grub configuration is updated as last step.
+				var grubIssue *kernel.DoctorIssue = nil
+
+				for idx, issue := range report.Issues {
+					if !issue.Fixable {
+						continue
+					}
+
+					if issue.Check == kernel.DoctorCheckStaleGrub {
+						grubIssue = report.Issues[idx]
+						continue
+					}
+
+					err := doctorFixIssue(issue, bootFiles, builderSelector, grubCfgFile,
+						config.GetGeneral().GetRootfs())
+					if err != nil {
+						fmt.Println(fmt.Sprintf("Error on fix %s of %s: %s",
+							issue.Check, issue.File, err.Error()))
+					} else {
+						report.Issues[idx].Fixed = true
+					}
+				}
+
+				if grubIssue != nil {
+					err := doctorFixIssue(grubIssue, bootFiles, builderSelector, grubCfgFile,
+						config.GetGeneral().GetRootfs())
+					if err != nil {
+						fmt.Println(fmt.Sprintf("Error on fix %s of %s: %s",
+							grubIssue.Check, grubIssue.File, err.Error()))
+					} else {
+						grubIssue.Fixed = true
+					}
+				}
+			}
+
+			if jsonOutput {
+				fmt.Println(report)
+			} else if len(report.Issues) == 0 {
+				fmt.Println("No problems found.")
+			} else {
+				table := tablewriter.NewWriter(os.Stdout)
+				table.Header(
+					"Severity",
+					"Check",
+					"File",
+					"Message",
+					"Fixable",
+					"Fixed",
+				)
+
+				for _, issue := range report.Issues {
+					table.Append([]string{
+						issue.Severity,
+						issue.Check,
+						issue.File,
+						issue.Message,
+						fmt.Sprintf("%v", issue.Fixable),
+						fmt.Sprintf("%v", issue.Fixed),
+					})
+				}
+
+				table.Render()
+			}
+
+			os.Exit(report.ExitCode())
+		},
+	}
+
+	flags := c.Flags()
+	flags.Bool("json", false, "JSON output")
+	flags.Bool("fix", false, "Repair the safe cases.")
+	flags.String("bootdir", "",
+		"Directory where analyze kernel files. Default is the kernel.bootdir option of the config.")
+	flags.String("modules-dir", "/lib/modules", "Directory of the kernel modules.")
+	flags.String("grub-cfg", "",
+		"Path of the grub.cfg file. Default is the kernel.grub-cfg option of the config or <bootdir>/grub/grub.cfg.")
+	flags.String("initrd-builder", "",
+		"Override the backend used to build the initrd images (dracut, mkinitcpio, booster).")
+	flags.String("dracut-opts", "",
+		`Override the dracut options of the kernel profiles and of the configuration
+used on the initrd image generation. Set the MACARONICTL_DRACUT_ARGS env in alternative.`)
+	flags.String("kernel-profiles-dir", "",
+		"Specify the directory where read the kernel types profiles supported.")
+
+	return c
+}
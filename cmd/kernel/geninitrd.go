@@ -1,5 +1,5 @@
 /*
-	Copyright © 2021 Macaroni OS Linux
+	Copyright © 2021-2026 Macaroni OS Linux
 	See AUTHORS and LICENSE for the license details and contributors.
 */
 
@@ -10,30 +10,111 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/macaroni-os/macaronictl/pkg/bootloader"
 	"github.com/macaroni-os/macaronictl/pkg/initrd"
 	"github.com/macaroni-os/macaronictl/pkg/kernel"
 	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
 	"github.com/macaroni-os/macaronictl/pkg/logger"
-	"github.com/macaroni-os/macaronictl/pkg/profile"
+	"github.com/macaroni-os/macaronictl/pkg/secureboot"
 	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+	"github.com/macaroni-os/macaronictl/pkg/uki"
 	"github.com/macaroni-os/macaronictl/pkg/utils"
 
 	"github.com/spf13/cobra"
 )
 
+// Create the selector of the initrd builders. The builder and the dracut
+// options defined with the CLI options have the precedence over the kernel
+// profile and the configuration.
+func newInitrdBuilderSelector(config *specs.MacaroniCtlConfig,
+	forced, dracutOpts string, dryRun bool) *initrd.InitrdBuilderSelector {
+
+	ans := initrd.NewInitrdBuilderSelector(forced,
+		config.GetKernel().GetInitrdBuilder(), dryRun)
+	ans.Rootfs = config.GetGeneral().GetRootfs()
+
+	ans.SetArgs("dracut", config.GetKernel().GetDracutArgs())
+	if dracutOpts != "" {
+		ans.SetForcedArgs("dracut", dracutOpts)
+	}
+
+	microcode := config.GetKernel().GetMicrocode()
+	ans.Microcode = initrd.NewMicrocodeStage(microcode.Mode, dryRun)
+	if microcode.Cpuinfo != "" {
+		ans.Microcode.CpuinfoFile = microcode.Cpuinfo
+	}
+	if microcode.FirmwareDir != "" {
+		ans.Microcode.FirmwareDir = microcode.FirmwareDir
+	}
+	// The firmware files are installed in the rootfs. The cpuinfo file
+	// is of the running system and it isn't resolved under the rootfs.
+	ans.Microcode.FirmwareDir = utils.RootfsPath(ans.Rootfs, ans.Microcode.FirmwareDir)
+
+	return ans
+}
+
+// Return the boot directory defined with the --bootdir option or
+// in the configuration. The path is resolved under the --rootfs directory.
+func getBootDir(cmd *cobra.Command, config *specs.MacaroniCtlConfig) string {
+	bootDir, _ := cmd.Flags().GetString("bootdir")
+	if bootDir == "" {
+		bootDir = config.GetKernel().GetBootDir()
+	}
+	return utils.RootfsPath(config.GetGeneral().GetRootfs(), bootDir)
+}
+
+// Return the directory of the kernel modules defined with the
+// --modules-dir option resolved under the --rootfs directory.
+func getModulesDir(cmd *cobra.Command, config *specs.MacaroniCtlConfig) string {
+	modulesDir, _ := cmd.Flags().GetString("modules-dir")
+	return utils.RootfsPath(config.GetGeneral().GetRootfs(), modulesDir)
+}
+
+func newBootloaderOpts(config *specs.MacaroniCtlConfig, dryRun bool) *bootloader.BootloaderOpts {
+	ans := bootloader.NewBootloaderOpts()
+	ans.DryRun = dryRun
+	ans.Rootfs = config.GetGeneral().GetRootfs()
+	ans.GrubCfgFile = utils.RootfsPath(ans.Rootfs, config.GetKernel().GetGrubCfg())
+	ans.OsReleaseFile = utils.RootfsPath(ans.Rootfs, ans.OsReleaseFile)
+	return ans
+}
+
 func setFilesLinks(kf *kernelspecs.KernelFiles, bootDir, release string) error {
 
 	log := logger.GetDefaultLogger()
 
-	// Ignoring errors
-	os.Remove(filepath.Join(bootDir, "bzImage"))
-	os.Remove(filepath.Join(bootDir, "Initrd"))
-
 	err := os.Chdir(bootDir)
 	if err != nil {
 		return err
 	}
 
+	// Keep the previous selection as fallback links.
+	oldKernel, err := os.Readlink(filepath.Join(bootDir, "bzImage"))
+	if err == nil && oldKernel != kf.Kernel.GetFilename() {
+		// Ignoring errors
+		os.Remove(filepath.Join(bootDir, "bzImage.old"))
+		os.Remove(filepath.Join(bootDir, "Initrd.old"))
+
+		log.DebugC("Creating link bzImage.old to", oldKernel)
+		err = os.Symlink(oldKernel, filepath.Join(bootDir, "bzImage.old"))
+		if err != nil {
+			return err
+		}
+
+		oldInitrd, err := os.Readlink(filepath.Join(bootDir, "Initrd"))
+		if err == nil {
+			log.DebugC("Creating link Initrd.old to", oldInitrd)
+			err = os.Symlink(oldInitrd, filepath.Join(bootDir, "Initrd.old"))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Ignoring errors
+	os.Remove(filepath.Join(bootDir, "bzImage"))
+	os.Remove(filepath.Join(bootDir, "Initrd"))
+
 	log.DebugC("Creating link bzImage to", kf.Kernel.GetFilename())
 	err = os.Symlink(kf.Kernel.GetFilename(), filepath.Join(bootDir, "bzImage"))
 	if err != nil {
@@ -57,16 +138,59 @@ func setFilesLinks(kf *kernelspecs.KernelFiles, bootDir, release string) error {
 	return nil
 }
 
+// Sign the kernel image and build the UKI of the kernel files. The UKI
+// is built with the signed kernel and then it's signed too.
+func signKernelFiles(signer *secureboot.Signer, ukiBuilder *uki.UKIBuilder,
+	kf *kernelspecs.KernelFiles, bootDir string, dryRun bool) error {
+
+	if signer != nil {
+		err := signImage(signer, filepath.Join(bootDir, kf.Kernel.GetFilename()), dryRun)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ukiBuilder == nil {
+		return nil
+	}
+
+	ukiFile, err := ukiBuilder.Build(kf, bootDir)
+	if err != nil {
+		return fmt.Errorf("Error on build UKI for kernel %s: %s",
+			kf.Kernel.GetFilename(), err.Error())
+	}
+
+	if signer != nil {
+		return signImage(signer, ukiFile, dryRun)
+	}
+
+	return nil
+}
+
 func NewGeninitrdCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
 	c := &cobra.Command{
 		Use:     "geninitrd",
 		Aliases: []string{"gi"},
 		Short:   "Generate initrd image and set default kernel/initrd links.",
-		Long: `Rebuild Dracut initrd images.
+		Long: `Rebuild initrd images with dracut, mkinitcpio or booster.
+
+When the kernel.secureboot.sign option of the config is enabled the
+kernel images and the UKIs are signed for Secure Boot after the build.
+
+With the microcode stage the early microcode of the CPU is prepended
+to the initrd images or kept as separate image (intel-uc.img, amd-uc.img)
+loaded by grub, by the BLS entries and by the UKIs.
 
 $> # Generate all initrd images of the kernels available on boot dir.
 $> macaronictl kernel geninitrd --all
 
+$> # Generate all initrd images with 4 parallel jobs.
+$> macaronictl kernel geninitrd --all --jobs 4
+
+$> # Generate all initrd images of a chroot or of an image. The
+$> # builder is executed inside the target with chroot.
+$> macaronictl kernel geninitrd --all --rootfs /mnt/target
+
 $> # Generate all initrd images of the kernels available on boot dir
 $> # and set the bzImage, Initrd links to one of the kernel available
 $> # if not present or to the next release of the same kernel after the
@@ -79,9 +203,31 @@ $> # if not present or to the next release of the same kernel after the
 $> # upgrade. In addition, it purges old initrd images and update grub.cfg.
 $> macaronictl kernel geninitrd --all --set-links --purge --grub
 
+$> # Generate all initrd images and write the Boot Loader Specification
+$> # entries used by systemd-boot.
+$> macaronictl kernel geninitrd --all --set-links --bootloader bls
+
+$> # Generate all initrd images and the extlinux.conf used by U-Boot.
+$> macaronictl kernel geninitrd --all --set-links --bootloader extlinux
+
+$> # Generate all initrd images and the Unified Kernel Images.
+$> macaronictl kernel geninitrd --all --uki
+
+$> # Generate all initrd images with the early microcode of the CPU
+$> # prepended to the initrd images.
+$> macaronictl kernel geninitrd --all --microcode prepend
+
+$> # Generate all initrd images and the separate microcode image
+$> # (intel-uc.img, amd-uc.img) loaded by the bootloader. The CPU vendor
+$> # is read from the cpuinfo file of the target host.
+$> macaronictl kernel geninitrd --all --microcode separate --cpuinfo /tmp/cpuinfo
+
 $> # Just show what dracut commands will be executed for every initrd images.
 $> macaronictl kernel geninitrd --all --dry-run
 
+$> # Generate all initrd images with booster.
+$> macaronictl kernel geninitrd --all --initrd-builder booster
+
 $> # Generate the initrd image for the kernel 5.10.42
 $> macaronictl kernel geninitrd --version 5.10.42
 
@@ -99,10 +245,15 @@ $> macaronictl kernel geninitrd --version 5.10.42 --ktype vanilla
 			if !all && version == "" {
 				fmt.Println("You need to use --all or --version")
 			}
+			microcode, _ := cmd.Flags().GetString("microcode")
+			if !initrd.ValidMicrocodeMode(microcode) {
+				fmt.Println("Invalid microcode mode " + microcode)
+				os.Exit(1)
+			}
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 
-			bootDir, _ := cmd.Flags().GetString("bootDir")
+			bootDir := getBootDir(cmd, config)
 			all, _ := cmd.Flags().GetBool("all")
 			setLinks, _ := cmd.Flags().GetBool("set-links")
 			version, _ := cmd.Flags().GetString("version")
@@ -111,18 +262,18 @@ $> macaronictl kernel geninitrd --version 5.10.42 --ktype vanilla
 			dracutOpts, _ := cmd.Flags().GetString("dracut-opts")
 			purge, _ := cmd.Flags().GetBool("purge")
 			grub, _ := cmd.Flags().GetBool("grub")
+			bootloaderName, _ := cmd.Flags().GetString("bootloader")
+			withUki, _ := cmd.Flags().GetBool("uki")
+			dtbsLink, _ := cmd.Flags().GetBool("dtbs-link")
+			ukiStub, _ := cmd.Flags().GetString("uki-stub")
+			espDir, _ := cmd.Flags().GetString("esp")
 			kernelProfilesDir, _ := cmd.Flags().GetString("kernel-profiles-dir")
+			jobs, _ := cmd.Flags().GetInt("jobs")
+			initrdBuilder, _ := cmd.Flags().GetString("initrd-builder")
+			microcode, _ := cmd.Flags().GetString("microcode")
+			cpuinfo, _ := cmd.Flags().GetString("cpuinfo")
 
-			types := []kernelspecs.KernelType{}
-
-			if kernelProfilesDir != "" {
-				types, _ = profile.LoadKernelProfiles(kernelProfilesDir)
-			} else {
-				types, _ = profile.LoadKernelProfiles(config.GetKernelProfilesDir())
-			}
-			if len(types) == 0 {
-				types = profile.GetDefaultKernelProfiles()
-			}
+			types := loadKernelTypes(config, kernelProfilesDir)
 
 			bootFiles, err := kernel.ReadBootDir(bootDir, types)
 			if err != nil {
@@ -130,35 +281,62 @@ $> macaronictl kernel geninitrd --version 5.10.42 --ktype vanilla
 				os.Exit(1)
 			}
 
-			release, err := utils.OsRelease()
+			release, err := utils.OsReleaseRootfs(config.GetGeneral().GetRootfs())
 			if err != nil {
 				fmt.Println("Error on retrieve os release: " + err.Error())
 				os.Exit(1)
 			}
 
-			// TODO: default dracut options will be read from configuration.
+			builderSelector := newInitrdBuilderSelector(config, initrdBuilder,
+				dracutOpts, dryRun)
+			if microcode != "" {
+				builderSelector.Microcode.Mode = microcode
+			}
+			if cpuinfo != "" {
+				builderSelector.Microcode.CpuinfoFile = cpuinfo
+			}
+
+			signer, err := newAutoSigner(config)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
 
-			defaultDracutOpts :=
-				"-H -q -f -o systemd -o systemd-initrd -o systemd-networkd -o dracut-systemd"
-			if dracutOpts != "" {
-				defaultDracutOpts = dracutOpts
+			var ukiBuilder *uki.UKIBuilder = nil
+			if withUki {
+				ukiBuilder = newUKIBuilder(config, ukiStub, espDir, bootFiles.Dir, dryRun)
 			}
-			dracutBuilder := initrd.NewDracutBuilder(defaultDracutOpts, dryRun)
+
+			// Kernel files with errors on initrd or UKI generation.
+			failed := []*initrd.BuildResult{}
+			nBuilt := 0
 
 			if all {
+				kfiles := []*kernelspecs.KernelFiles{}
 				for idx, f := range bootFiles.Files {
 					if f.Kernel == nil {
 						// Ignore initrd without kernel image.
 						continue
 					}
+					kfiles = append(kfiles, bootFiles.Files[idx])
+				}
+
+				results := initrd.BuildAll(builderSelector, kfiles, bootFiles.Dir, jobs)
+				for _, r := range results {
+					if r.Error != nil {
+						failed = append(failed, r)
+						continue
+					}
 
-					err := dracutBuilder.Build(bootFiles.Files[idx], bootFiles.Dir)
+					err = signKernelFiles(signer, ukiBuilder, r.KernelFiles,
+						bootFiles.Dir, dryRun)
 					if err != nil {
-						fmt.Println(fmt.Sprintf("Error on generate initrd image for kernel %s: %s. I go ahead.",
-							f.Kernel.GetFilename(),
-							err.Error(),
-						))
+						r.Error = err
+						failed = append(failed, r)
+						continue
 					}
+
+					nBuilt++
 				}
 
 				var kf *kernelspecs.KernelFiles
@@ -201,12 +379,17 @@ $> macaronictl kernel geninitrd --version 5.10.42 --ktype vanilla
 					os.Exit(1)
 				}
 
-				err = dracutBuilder.Build(file, bootFiles.Dir)
+				err = builderSelector.Build(file, bootFiles.Dir)
 				if err != nil {
 					fmt.Println(fmt.Sprintf("Error on generate initrd image for kernel %s: %s. I go ahead.",
 						file.Kernel.GetFilename(),
 						err.Error(),
 					))
+				} else {
+					err = signKernelFiles(signer, ukiBuilder, file, bootFiles.Dir, dryRun)
+					if err != nil {
+						fmt.Println(fmt.Sprintf("%s. I go ahead.", err.Error()))
+					}
 				}
 
 				if setLinks {
@@ -230,31 +413,73 @@ $> macaronictl kernel geninitrd --version 5.10.42 --ktype vanilla
 				}
 			}
 
-			// Update grub config
-			if grub {
-				err = kernel.GrubMkconfig(filepath.Join(bootFiles.Dir, "/grub/grub.cfg"), dryRun)
+			if grub && bootloaderName == "" {
+				bootloaderName = "grub"
+			}
+
+			if all {
+				fmt.Println(fmt.Sprintf("Kernels processed: %d, succeeded: %d, failed: %d.",
+					nBuilt+len(failed), nBuilt, len(failed)))
+				for _, r := range failed {
+					fmt.Println(fmt.Sprintf("- %s: %s",
+						r.KernelFiles.Kernel.GetFilename(), r.Error.Error()))
+				}
+			}
+
+			// Update bootloader config
+			if bootloaderName != "" {
+				blOpts := newBootloaderOpts(config, dryRun)
+				blOpts.DtbsLink = dtbsLink
+				bl, err := bootloader.NewBootloader(bootloaderName, blOpts)
+				if err != nil {
+					fmt.Println(err.Error())
+					os.Exit(1)
+				}
+
+				err = bl.Update(bootFiles)
 				if err != nil {
-					fmt.Println(fmt.Sprintf("Error on update grub.cfg: %s", err.Error()))
+					fmt.Println(fmt.Sprintf("Error on update %s configuration: %s",
+						bl.GetName(), err.Error()))
 					// TODO: We need ignore it?
 					os.Exit(1)
 				}
 			}
 
+			if len(failed) > 0 {
+				os.Exit(1)
+			}
 		},
 	}
 
 	flags := c.Flags()
 	flags.Bool("all", false, "Rebuild all images with kernel.")
 	flags.Bool("dry-run", false, "Dry run commands.")
+	flags.IntP("jobs", "j", 1, "Number of initrd images to generate in parallel with --all.")
 	flags.Bool("set-links", false, "Set bzImage and Initrd links for the selected kernel or update links of the upgraded kernel.")
 	flags.Bool("purge", false, "Clean orphan initrd images without kernel.")
 	flags.Bool("grub", false, "Update grub.cfg.")
-	flags.String("bootdir", "/boot", "Directory where analyze kernel files.")
+	flags.String("bootloader", "",
+		"Update the configuration of the selected bootloader (grub, bls, extlinux).")
+	flags.Bool("dtbs-link", false,
+		"Link the device-tree blobs directories instead of copy them (extlinux).")
+	flags.Bool("uki", false, "Build the Unified Kernel Image after the initrd image.")
+	flags.String("uki-stub", uki.DefaultEfiStub, "Path of the EFI stub used to build the UKI.")
+	flags.String("esp", "", "Directory of the EFI System Partition. Default is the boot dir.")
+	flags.String("bootdir", "",
+		"Directory where analyze kernel files. Default is the kernel.bootdir option of the config.")
 	flags.String("version", "", "Specify the kernel version of the initrd image to build.")
 	flags.String("ktype", "", "Specify the kernel type of the initrd image to build.")
+	flags.String("initrd-builder", "",
+		`Override the backend used to build the initrd images (dracut, mkinitcpio, booster).
+By default is used the backend of the kernel profile or of the configuration.`)
 	flags.String("dracut-opts", "",
-		`Override the default dracut options used on the initrd image generation.
-Set the MACARONICTL_DRACUT_ARGS env in alternative.`)
+		`Override the dracut options of the kernel profiles and of the configuration
+used on the initrd image generation. Set the MACARONICTL_DRACUT_ARGS env in alternative.`)
+	flags.String("microcode", "",
+		`Override the mode of the early microcode (none, prepend, separate).
+By default is used the kernel.microcode.mode option of the config.`)
+	flags.String("cpuinfo", "",
+		"Path of the cpuinfo file used to detect the CPU vendor of the microcode.")
 	flags.String("kernel-profiles-dir", "",
 		"Specify the directory where read the kernel types profiles supported.")
 
@@ -0,0 +1,183 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmdkernel
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/macaroni-os/macaronictl/pkg/initrd"
+	"github.com/macaroni-os/macaronictl/pkg/kernel"
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+
+	tablewriter "github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+func NewInitrdCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "initrd",
+		Short: "Manage initrd images.",
+		Long:  `Analyze the initrd images available on boot dir.`,
+	}
+
+	c.AddCommand(
+		newInitrdInspectCommand(config),
+	)
+
+	return c
+}
+
+func newInitrdInspectCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
+	c := &cobra.Command{
+		Use:     "inspect",
+		Aliases: []string{"i"},
+		Short:   "List or extract the files of an initrd image.",
+		Long: `List or extract the files of the initrd image of a kernel.
+
+The compression (gzip, xz, zstd, lz4) is detected automatically and
+the early microcode archives before the main archive are read too.
+
+$> macaronictl kernel initrd inspect --version 6.1.12
+
+$> # Show only the files of the kernel modules.
+$> macaronictl kernel initrd inspect --version 6.1.12 --filter /lib/modules/
+
+$> # Extract the files under /tmp/initrd.
+$> macaronictl kernel initrd inspect --version 6.1.12 --extract /tmp/initrd
+
+`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			version, _ := cmd.Flags().GetString("version")
+			file, _ := cmd.Flags().GetString("file")
+			if version == "" && file == "" {
+				fmt.Println("You need to use --version or --file")
+				os.Exit(1)
+			}
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+			bootDir := getBootDir(cmd, config)
+			version, _ := cmd.Flags().GetString("version")
+			ktype, _ := cmd.Flags().GetString("ktype")
+			file, _ := cmd.Flags().GetString("file")
+			extractDir, _ := cmd.Flags().GetString("extract")
+			filter, _ := cmd.Flags().GetString("filter")
+			kernelProfilesDir, _ := cmd.Flags().GetString("kernel-profiles-dir")
+
+			if file == "" {
+				types := loadKernelTypes(config, kernelProfilesDir)
+
+				bootFiles, err := kernel.ReadBootDir(bootDir, types)
+				if err != nil {
+					fmt.Println("Error on read boot directory: " + err.Error())
+					os.Exit(1)
+				}
+
+				kf, err := bootFiles.GetFile(version, ktype)
+				if err != nil {
+					fmt.Println(err.Error())
+					os.Exit(1)
+				}
+
+				if kf.Initrd == nil {
+					fmt.Println(fmt.Sprintf("No initrd image found for kernel %s.",
+						kf.Kernel.GetFilename()))
+					os.Exit(1)
+				}
+
+				file = filepath.Join(bootFiles.Dir, kf.Initrd.GetFilename())
+			}
+
+			filterFn := func(e *initrd.InitrdEntry) bool {
+				return filter == "" || strings.Contains(e.Name, filter)
+			}
+
+			if extractDir != "" {
+				n, err := initrd.ExtractInitrdImage(file, extractDir, filterFn)
+				if err != nil {
+					fmt.Println(fmt.Sprintf("Error on extract %s: %s", file, err.Error()))
+					os.Exit(1)
+				}
+				fmt.Println(fmt.Sprintf("Extracted %d files of %s under %s.",
+					n, file, extractDir))
+				return
+			}
+
+			entries, archives, err := initrd.ListInitrdImage(file)
+			if err != nil {
+				fmt.Println(fmt.Sprintf("Error on read %s: %s", file, err.Error()))
+				os.Exit(1)
+			}
+
+			selected := []*initrd.InitrdEntry{}
+			for idx := range entries {
+				if filterFn(entries[idx]) {
+					selected = append(selected, entries[idx])
+				}
+			}
+
+			if jsonOutput {
+				data, err := json.Marshal(map[string]interface{}{
+					"file":     file,
+					"archives": archives,
+					"entries":  selected,
+				})
+				if err != nil {
+					fmt.Println(fmt.Errorf("Error on convert data to json: %s", err.Error()))
+					os.Exit(1)
+				}
+				fmt.Println(string(data))
+				return
+			}
+
+			for _, a := range archives {
+				fmt.Println(fmt.Sprintf("Archive %d: compression %s, %d entries.",
+					a.Index, a.Compression, a.Entries))
+			}
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.Header(
+				"Mode",
+				"Size",
+				"Name",
+				"Archive",
+			)
+
+			for _, e := range selected {
+				name := e.Name
+				if e.IsSymlink() {
+					name += " -> " + e.Linkname
+				}
+				table.Append([]string{
+					e.FileMode().String(),
+					fmt.Sprintf("%d", e.Size),
+					name,
+					fmt.Sprintf("%d", e.Archive),
+				})
+			}
+
+			table.Render()
+		},
+	}
+
+	flags := c.Flags()
+	flags.Bool("json", false, "JSON output")
+	flags.String("bootdir", "",
+		"Directory where analyze kernel files. Default is the kernel.bootdir option of the config.")
+	flags.String("version", "", "Specify the kernel version of the initrd image to inspect.")
+	flags.String("ktype", "", "Specify the kernel type of the initrd image to inspect.")
+	flags.String("file", "", "Path of the initrd image to inspect in alternative to --version.")
+	flags.String("extract", "", "Extract the files under the directory in input.")
+	flags.String("filter", "", "Process only the files with the path that contains the string.")
+	flags.String("kernel-profiles-dir", "",
+		"Specify the directory where read the kernel types profiles supported.")
+
+	return c
+}
@@ -1,5 +1,5 @@
 /*
-Copyright © 2021-2023 Macaroni OS Linux
+Copyright © 2021-2026 Macaroni OS Linux
 See AUTHORS and LICENSE for the license details and contributors.
 */
 package cmdkernel
@@ -9,8 +9,6 @@ import (
 	"os"
 
 	"github.com/macaroni-os/macaronictl/pkg/kernel"
-	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
-	"github.com/macaroni-os/macaronictl/pkg/profile"
 	specs "github.com/macaroni-os/macaronictl/pkg/specs"
 
 	tablewriter "github.com/olekukonko/tablewriter"
@@ -30,18 +28,10 @@ $ macaronictl kernel list
 		Run: func(cmd *cobra.Command, args []string) {
 
 			jsonOutput, _ := cmd.Flags().GetBool("json")
-			bootDir, _ := cmd.Flags().GetString("bootDir")
+			bootDir := getBootDir(cmd, config)
 			kernelProfilesDir, _ := cmd.Flags().GetString("kernel-profiles-dir")
 
-			types := []kernelspecs.KernelType{}
-			if kernelProfilesDir != "" {
-				types, _ = profile.LoadKernelProfiles(kernelProfilesDir)
-			} else {
-				types, _ = profile.LoadKernelProfiles(config.GetKernelProfilesDir())
-			}
-			if len(types) == 0 {
-				types = profile.GetDefaultKernelProfiles()
-			}
+			types := loadKernelTypes(config, kernelProfilesDir)
 
 			bootFiles, err := kernel.ReadBootDir(bootDir, types)
 			if err != nil {
@@ -50,6 +40,10 @@ $ macaronictl kernel list
 			}
 
 			if jsonOutput {
+				cmdline := kernel.NewCmdline(config.GetGeneral().GetRootfs())
+				for _, kf := range bootFiles.Files {
+					kf.Cmdline = cmdline.ResolveKernelFiles(kf)
+				}
 				fmt.Println(bootFiles)
 			} else {
 
@@ -109,13 +103,22 @@ $ macaronictl kernel list
 				}
 
 				table.Render()
+
+				for _, kf := range bootFiles.Files {
+					if kf.Kernel != nil && kf.Kernel.HasReleaseMismatch() {
+						fmt.Println(fmt.Sprintf(
+							"WARN: The kernel %s contains the release %s.",
+							kf.Kernel.GetFilename(), kf.Kernel.GetHeaderRelease()))
+					}
+				}
 			}
 		},
 	}
 
 	flags := c.Flags()
 	flags.Bool("json", false, "JSON output")
-	flags.String("bootdir", "/boot", "Directory where analyze kernel files.")
+	flags.String("bootdir", "",
+		"Directory where analyze kernel files. Default is the kernel.bootdir option of the config.")
 	flags.String("kernel-profiles-dir", "",
 		"Specify the directory where read the kernel types profiles supported.")
 
@@ -1,5 +1,5 @@
 /*
-Copyright © 2021-2023 Macaroni OS Linux
+Copyright © 2021-2026 Macaroni OS Linux
 See AUTHORS and LICENSE for the license details and contributors.
 */
 package cmdkernel
@@ -17,6 +17,161 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// Create the coverage report of the extra modules for every
+// branch of the installed kernels.
+func modulesCoverageReport(config *specs.MacaroniCtlConfig) ([]*kernel.ModulesCoverage, error) {
+	ans := []*kernel.ModulesCoverage{}
+
+	installed, err := kernel.InstalledKernels(config)
+	if err != nil {
+		return ans, fmt.Errorf("Error on retrieve installed kernels: %s", err.Error())
+	}
+
+	// Installed modules for kernel type
+	installedMods := make(map[string]*specs.StonesPack, 0)
+	branches := make(map[string]bool, 0)
+
+	for _, s := range installed.Stones {
+		a, err := kernel.ParseKernelAnnotations(s)
+		if err != nil {
+			return ans, err
+		}
+
+		branch := kernel.KernelBranch(s, a.Type)
+		if branches[a.Type+"/"+branch] {
+			continue
+		}
+		branches[a.Type+"/"+branch] = true
+
+		if _, present := installedMods[a.Type]; !present {
+			installedMods[a.Type], err = kernel.AvailableExtraModules(
+				"", a.Type, true, config,
+			)
+			if err != nil {
+				return ans, fmt.Errorf("Error on retrieve installed kernel modules: %s",
+					err.Error())
+			}
+		}
+
+		availableMods, err := kernel.AvailableExtraModules(
+			branch, a.Type, false, config,
+		)
+		if err != nil {
+			return ans, fmt.Errorf("Error on retrieve available kernel modules: %s",
+				err.Error())
+		}
+
+		ans = append(ans, kernel.NewModulesCoverage(branch, a.Type,
+			installedMods[a.Type], availableMods))
+	}
+
+	return ans, nil
+}
+
+// Print the extra modules that will be lost with the kernel branch.
+func printModulesCoverage(c *kernel.ModulesCoverage) {
+	if c.IsComplete() {
+		return
+	}
+
+	fmt.Println(fmt.Sprintf(
+		"WARN: Extra modules without a package for the kernel branch %s (%s):",
+		c.Branch, c.Type))
+	for _, m := range c.Missing {
+		fmt.Println(fmt.Sprintf("- %s (installed: %s)", m.Name,
+			strings.Join(m.Installed, ", ")))
+	}
+}
+
+// Show the matrix of the installed extra modules and remove the
+// orphan modules if required. Without yes the removal is confirmed
+// by the user.
+func modulesMatrix(config *specs.MacaroniCtlConfig, jsonOutput, removeOrphans, yes, dryRun bool) error {
+	installed, err := kernel.InstalledKernels(config)
+	if err != nil {
+		return fmt.Errorf("Error on retrieve installed kernels: %s", err.Error())
+	}
+
+	installedMods, err := kernel.AvailableExtraModules("", "", true, config)
+	if err != nil {
+		return fmt.Errorf("Error on retrieve installed kernel modules: %s", err.Error())
+	}
+
+	availableMods, err := kernel.AvailableExtraModules("", "", false, config)
+	if err != nil {
+		return fmt.Errorf("Error on retrieve available kernel modules: %s", err.Error())
+	}
+
+	matrix, err := kernel.NewModulesMatrix(installed, installedMods, availableMods)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		data, err := json.Marshal(matrix)
+		if err != nil {
+			return fmt.Errorf("Error on convert data to json: %s", err.Error())
+		}
+		fmt.Println(string(data))
+	} else if len(matrix.Rows) == 0 {
+		fmt.Println("No extra kernel modules installed.")
+	} else {
+		table := tablewriter.NewWriter(os.Stdout)
+		header := []any{"Module"}
+		for _, c := range matrix.Columns {
+			header = append(header, c.String())
+		}
+		table.Header(header...)
+
+		for _, r := range matrix.Rows {
+			module := r.Module
+			if r.Orphan {
+				module += " (orphan)"
+			}
+			table.Append(append([]string{module}, r.Cells...))
+		}
+
+		table.Render()
+
+		if len(matrix.Orphans) > 0 {
+			fmt.Println("Orphan modules of kernel branches not installed:")
+			for _, s := range matrix.Orphans {
+				fmt.Println("- " + s.HumanReadableString())
+			}
+			if !removeOrphans {
+				fmt.Println("Use --remove-orphans to remove them.")
+			}
+		}
+	}
+
+	if removeOrphans && len(matrix.Orphans) > 0 {
+		if dryRun {
+			for _, s := range matrix.Orphans {
+				fmt.Println("[dry-run mode] removing " + s.HumanReadableString())
+			}
+			return nil
+		}
+
+		if !yes {
+			resp := ""
+			fmt.Print(fmt.Sprintf("Remove %d orphan modules? (yes|y|n|no): ",
+				len(matrix.Orphans)))
+			fmt.Scanln(&resp)
+			if resp != "yes" && resp != "y" {
+				fmt.Println("Orphan modules not removed.")
+				return nil
+			}
+		}
+
+		err = kernel.RemovePackages(matrix.Orphans[0], matrix.Orphans[1:], config)
+		if err != nil {
+			return fmt.Errorf("Error on remove orphan modules: %s", err.Error())
+		}
+	}
+
+	return nil
+}
+
 func NewModulesCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
 	c := &cobra.Command{
 		Use:     "modules",
@@ -26,6 +181,17 @@ func NewModulesCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
 
 $ macaronictl kernel modules
 
+$ # Show the installed extra modules without a package for
+$ # the branches of the installed kernels.
+$ macaronictl kernel modules --coverage
+
+$ # Show the installed extra modules for every installed kernel
+$ # branch and remove the modules of the branches no more installed.
+$ macaronictl kernel modules --matrix --remove-orphans
+
+$ # Remove the orphan modules without confirmation.
+$ macaronictl kernel modules --remove-orphans --yes
+
 NOTE: It works only if the repositories are synced.
 `,
 		Run: func(cmd *cobra.Command, args []string) {
@@ -34,6 +200,64 @@ NOTE: It works only if the repositories are synced.
 			installed, _ := cmd.Flags().GetBool("installed")
 			kBranch, _ := cmd.Flags().GetString("kernel-branch")
 			kType, _ := cmd.Flags().GetString("kernel-type")
+			coverage, _ := cmd.Flags().GetBool("coverage")
+			matrix, _ := cmd.Flags().GetBool("matrix")
+			removeOrphans, _ := cmd.Flags().GetBool("remove-orphans")
+			yes, _ := cmd.Flags().GetBool("yes")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+			if matrix || removeOrphans {
+				err := modulesMatrix(config, jsonOutput, removeOrphans, yes, dryRun)
+				if err != nil {
+					fmt.Println(err.Error())
+					os.Exit(1)
+				}
+				return
+			}
+
+			if coverage {
+				report, err := modulesCoverageReport(config)
+				if err != nil {
+					fmt.Println(err.Error())
+					os.Exit(1)
+				}
+
+				if jsonOutput {
+					data, err := json.Marshal(report)
+					if err != nil {
+						fmt.Println(fmt.Errorf("Error on convert data to json: %s", err.Error()))
+						os.Exit(1)
+					}
+					fmt.Println(string(data))
+					return
+				}
+
+				table := tablewriter.NewWriter(os.Stdout)
+				table.Header(
+					"Kernel Branch",
+					"Kernel Type",
+					"Covered Modules",
+					"Missing Modules",
+				)
+
+				for _, c := range report {
+					missing := []string{}
+					for _, m := range c.Missing {
+						missing = append(missing, fmt.Sprintf("%s (%s)",
+							m.Name, strings.Join(m.Installed, ", ")))
+					}
+
+					table.Append([]string{
+						c.Branch,
+						c.Type,
+						strings.Join(c.Covered, "\n"),
+						strings.Join(missing, "\n"),
+					})
+				}
+
+				table.Render()
+				return
+			}
 
 			stones, err := kernel.AvailableExtraModules(
 				kBranch, kType, installed, config,
@@ -106,6 +330,14 @@ NOTE: It works only if the repositories are synced.
 	flags.BoolP("installed", "i", false, "Show only installed modules. (Requires root permission)")
 	flags.StringP("kernel-branch", "b", "", "Filter for a specific kernel branch.")
 	flags.StringP("kernel-type", "t", "", "Filter for a specific kernel type.")
+	flags.Bool("coverage", false,
+		"Show the installed extra modules without a package for the branches of the installed kernels.")
+	flags.Bool("matrix", false,
+		"Show the installed extra modules for every branch and type of the installed kernels.")
+	flags.Bool("remove-orphans", false,
+		"Remove the extra modules of the kernel branches no more installed. (Requires root permission)")
+	flags.BoolP("yes", "y", false, "Remove the orphan modules without confirmation.")
+	flags.Bool("dry-run", false, "Show the orphan modules to remove without remove them.")
 
 	return c
 }
@@ -1,5 +1,5 @@
 /*
-	Copyright © 2021 Macaroni OS Linux
+	Copyright © 2021-2026 Macaroni OS Linux
 	See AUTHORS and LICENSE for the license details and contributors.
 */
 
@@ -7,41 +7,80 @@ package cmdkernel
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
 	"github.com/macaroni-os/macaronictl/pkg/profile"
 	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
 
 	tablewriter "github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 )
 
+// Return the directories of the kernel profiles: the selected directory
+// or the directory defined in the configuration file and the extra
+// profiles directories. The paths are resolved under the --rootfs directory.
+func getKernelProfilesDirs(config *specs.MacaroniCtlConfig, kernelProfilesDir string) []string {
+	if kernelProfilesDir == "" {
+		kernelProfilesDir = config.GetKernelProfilesDir()
+	}
+
+	ans := []string{}
+	for _, dir := range append([]string{kernelProfilesDir},
+		config.GetKernel().GetExtraProfilesDirs()...) {
+		if dir != "" {
+			ans = append(ans, utils.RootfsPath(config.GetGeneral().GetRootfs(), dir))
+		}
+	}
+
+	return ans
+}
+
+// Load the kernel types profiles of the profiles directories layered
+// over the default profiles.
+func loadKernelTypes(config *specs.MacaroniCtlConfig, kernelProfilesDir string) []kernelspecs.KernelType {
+	return profile.LoadLayeredKernelProfiles(
+		getKernelProfilesDirs(config, kernelProfilesDir))
+}
+
 func NewProfilesCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
 	c := &cobra.Command{
 		Use:     "profiles",
 		Aliases: []string{"p"},
-		Short:   "List available kernels profiles.",
-		Long: `Shows kernels available in your system
+		Short:   "List and manage the kernels profiles.",
+		Long: `Shows kernels profiles available in your system
 
 $ macaronictl kernel profiles
 
+The profiles of the profiles directory and of the directories of the
+kernel.extra-profiles-dirs option are layered over the default profiles.
+A profile replaces the profile with the same name of the lower layers.
+
+The filenames of the kernel and initrd images could be described with
+templates with the placeholders {prefix}, {type}, {arch}, {version} and
+{suffix}:
+
+  name: Debian
+  type: debian
+  kernel_template: vmlinuz-{version}
+  initrd_template: initrd.img-{version}
+
+The templates are paths relative to the boot directory and could contain
+directories, for example to use a layout like kernel-install:
+
+  kernel_template: {version}/vmlinuz
+  initrd_template: {version}/initrd
 `,
 		Run: func(cmd *cobra.Command, args []string) {
 
 			jsonOutput, _ := cmd.Flags().GetBool("json")
 			kernelProfilesDir, _ := cmd.Flags().GetString("kernel-profiles-dir")
 
-			types := []kernelspecs.KernelType{}
-			if kernelProfilesDir != "" {
-				types, _ = profile.LoadKernelProfiles(kernelProfilesDir)
-			} else {
-				types, _ = profile.LoadKernelProfiles(config.GetKernelProfilesDir())
-			}
-			if len(types) == 0 {
-				types = profile.GetDefaultKernelProfiles()
-			}
+			types := loadKernelTypes(config, kernelProfilesDir)
 
 			if jsonOutput {
 				data, err := json.Marshal(types)
@@ -85,6 +124,377 @@ $ macaronictl kernel profiles
 		},
 	}
 
+	flags := c.Flags()
+	flags.Bool("json", false, "JSON output")
+	flags.String("kernel-profiles-dir", "",
+		"Specify the directory where read the kernel types profiles supported.")
+
+	c.AddCommand(
+		newProfilesAddCommand(config),
+		newProfilesRemoveCommand(config),
+		newProfilesValidateCommand(config),
+		newProfilesTestCommand(config),
+	)
+
+	return c
+}
+
+// Return the directory where add and remove the kernel profiles.
+// The extra profiles directories are never modified.
+func getKernelProfilesDir(cmd *cobra.Command, config *specs.MacaroniCtlConfig) (string, error) {
+	kernelProfilesDir, _ := cmd.Flags().GetString("kernel-profiles-dir")
+	if kernelProfilesDir == "" {
+		kernelProfilesDir = config.GetKernelProfilesDir()
+	}
+
+	if kernelProfilesDir == "" {
+		return "", errors.New(
+			"No kernel profiles directory defined. Use --kernel-profiles-dir or the kernel-profiles-dir option of the config.")
+	}
+
+	return utils.RootfsPath(config.GetGeneral().GetRootfs(), kernelProfilesDir), nil
+}
+
+func printProfileErrors(file string, errs []error) {
+	for _, err := range errs {
+		fmt.Println(fmt.Sprintf("%s: %s", file, err.Error()))
+	}
+}
+
+func newProfilesAddCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "add <filename> [OPTIONS]",
+		Short: "Add a kernel profile.",
+		Long: `Validate the kernel profile file and copy it in the profiles directory.
+
+$> macaronictl kernel profiles add ./macaroni-lts.yml
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			force, _ := cmd.Flags().GetBool("force")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			file := args[0]
+
+			if !profile.IsKernelProfileFile(file) {
+				fmt.Println(fmt.Sprintf("The file %s must have the .yml or .yaml extension.", file))
+				os.Exit(1)
+			}
+
+			errs := profile.ValidateKernelProfileFile(file)
+			if len(errs) > 0 {
+				printProfileErrors(file, errs)
+				os.Exit(1)
+			}
+
+			ktype, err := profile.LoadKernelProfileFile(file)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			dir, err := getKernelProfilesDir(cmd, config)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			target := filepath.Join(dir, filepath.Base(file))
+			if utils.Exists(target) && !force {
+				fmt.Println(fmt.Sprintf(
+					"The kernel profile %s is already present. Use --force to replace it.",
+					target))
+				os.Exit(1)
+			}
+
+			for _, t := range profile.GetDefaultKernelProfiles() {
+				if t.GetName() == ktype.GetName() {
+					fmt.Println(fmt.Sprintf("The profile %s replaces the default profile.",
+						ktype.GetName()))
+				}
+			}
+
+			if dryRun {
+				fmt.Println("[dry-run mode] copying " + file + " to " + target)
+				return
+			}
+
+			content, err := os.ReadFile(file)
+			if err == nil {
+				err = os.MkdirAll(filepath.Dir(target), 0755)
+			}
+			if err == nil {
+				err = os.WriteFile(target, content, 0644)
+			}
+			if err != nil {
+				fmt.Println(fmt.Sprintf("Error on write %s: %s", target, err.Error()))
+				os.Exit(1)
+			}
+
+			fmt.Println(fmt.Sprintf("Kernel profile %s added.", ktype.GetName()))
+		},
+	}
+
+	flags := c.Flags()
+	flags.Bool("force", false, "Replace the profile file if already present.")
+	flags.Bool("dry-run", false, "Validate the profile without copy it.")
+	flags.String("kernel-profiles-dir", "",
+		"Specify the directory where add the kernel profile.")
+
+	return c
+}
+
+func newProfilesRemoveCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
+	c := &cobra.Command{
+		Use:     "remove <filename> [OPTIONS]",
+		Aliases: []string{"rm"},
+		Short:   "Remove a kernel profile.",
+		Long: `Remove the kernel profile file from the profiles directory.
+
+$> macaronictl kernel profiles remove macaroni-lts.yml
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+			dir, err := getKernelProfilesDir(cmd, config)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			target := filepath.Join(dir, filepath.Base(args[0]))
+			if !profile.IsKernelProfileFile(target) || !utils.Exists(target) {
+				fmt.Println(fmt.Sprintf("The kernel profile %s is not present.", target))
+				os.Exit(1)
+			}
+
+			if dryRun {
+				fmt.Println("[dry-run mode] removing " + target)
+				return
+			}
+
+			err = os.Remove(target)
+			if err != nil {
+				fmt.Println(fmt.Sprintf("Error on remove %s: %s", target, err.Error()))
+				os.Exit(1)
+			}
+
+			fmt.Println(fmt.Sprintf("Kernel profile %s removed.", target))
+		},
+	}
+
+	flags := c.Flags()
+	flags.Bool("dry-run", false, "Show the profile file without remove it.")
+	flags.String("kernel-profiles-dir", "",
+		"Specify the directory where remove the kernel profile.")
+
+	return c
+}
+
+func newProfilesValidateCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "validate [<filename>...] [OPTIONS]",
+		Short: "Validate the kernel profiles.",
+		Long: `Validate the kernel profile files in input or all the files of the
+profiles directories.
+
+$> macaronictl kernel profiles validate
+
+$> macaronictl kernel profiles validate ./macaroni-lts.yml
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+			kernelProfilesDir, _ := cmd.Flags().GetString("kernel-profiles-dir")
+
+			files := args
+			if len(files) == 0 {
+				for _, dir := range getKernelProfilesDirs(config, kernelProfilesDir) {
+					entries, err := os.ReadDir(dir)
+					if err != nil {
+						if !os.IsNotExist(err) {
+							fmt.Println(fmt.Sprintf("Error on read directory %s: %s",
+								dir, err.Error()))
+							os.Exit(1)
+						}
+						continue
+					}
+
+					for _, e := range entries {
+						if !e.IsDir() && profile.IsKernelProfileFile(e.Name()) {
+							files = append(files, filepath.Join(dir, e.Name()))
+						}
+					}
+				}
+			}
+
+			if len(files) == 0 {
+				fmt.Println("No kernel profiles found.")
+				return
+			}
+
+			// The profiles with the same name of the same directory
+			// are ignored.
+			names := make(map[string]string, 0)
+			nErrors := 0
+
+			for _, file := range files {
+				errs := profile.ValidateKernelProfileFile(file)
+				if len(errs) == 0 {
+					ktype, _ := profile.LoadKernelProfileFile(file)
+					key := filepath.Join(filepath.Dir(file), ktype.GetName())
+					if other, present := names[key]; present {
+						errs = append(errs, fmt.Errorf(
+							"The profile %s is already defined in %s", ktype.GetName(), other))
+					} else {
+						names[key] = file
+					}
+				}
+
+				if len(errs) > 0 {
+					printProfileErrors(file, errs)
+					nErrors++
+					continue
+				}
+
+				fmt.Println(fmt.Sprintf("%s: OK", file))
+			}
+
+			if nErrors > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	c.Flags().String("kernel-profiles-dir", "",
+		"Specify the directory where read the kernel types profiles supported.")
+
+	return c
+}
+
+type profileMatch struct {
+	Profile  string                   `json:"profile" yaml:"profile"`
+	Match    bool                     `json:"match" yaml:"match"`
+	Selected bool                     `json:"selected,omitempty" yaml:"selected,omitempty"`
+	Kernel   *kernelspecs.KernelImage `json:"kernel,omitempty" yaml:"kernel,omitempty"`
+	Initrd   *kernelspecs.InitrdImage `json:"initrd,omitempty" yaml:"initrd,omitempty"`
+	Error    string                   `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Parse the filename with the kernel type like on the analysis of the
+// boot directory.
+func parseBootFilename(t *kernelspecs.KernelType, file string) *profileMatch {
+	ans := &profileMatch{
+		Profile: t.GetName(),
+		Match:   t.GetRegex().MatchString(file),
+	}
+
+	if !ans.Match {
+		return ans
+	}
+
+	var err error
+	isInitrd, _ := t.IsInitrdFile(file)
+	if isInitrd {
+		ans.Initrd, err = kernelspecs.NewInitrdImageFromFile(t, file)
+	} else {
+		ans.Kernel, err = kernelspecs.NewKernelImageFromFile(t, file)
+	}
+	if err != nil {
+		ans.Error = err.Error()
+	}
+
+	return ans
+}
+
+func newProfilesTestCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "test <filename> [OPTIONS]",
+		Short: "Show how a boot filename is parsed by the kernel profiles.",
+		Long: `Show how the kernel profiles parse a filename of the boot directory.
+The file is assigned to the first profile that matches it.
+
+$> macaronictl kernel profiles test kernel-vanilla-x86_64-6.1.12-macaroni
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+			kernelProfilesDir, _ := cmd.Flags().GetString("kernel-profiles-dir")
+
+			file := filepath.Base(args[0])
+			types := loadKernelTypes(config, kernelProfilesDir)
+
+			matches := []*profileMatch{}
+			selected := false
+			for idx := range types {
+				m := parseBootFilename(&types[idx], file)
+				if m.Match && !selected {
+					m.Selected = true
+					selected = true
+				}
+				matches = append(matches, m)
+			}
+
+			if jsonOutput {
+				data, err := json.Marshal(matches)
+				if err != nil {
+					fmt.Println(fmt.Errorf("Error on convert data to json: %s", err.Error()))
+					os.Exit(1)
+				}
+				fmt.Println(string(data))
+				return
+			}
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.Header(
+				"Profile",
+				"Match",
+				"File Type",
+				"Prefix",
+				"Type",
+				"Arch",
+				"Version",
+				"Suffix",
+			)
+
+			for _, m := range matches {
+				row := []string{m.Profile, "no", "", "", "", "", "", ""}
+				if m.Match {
+					row[1] = "yes"
+					if m.Selected {
+						row[1] = "yes (selected)"
+					}
+				}
+
+				switch {
+				case m.Error != "":
+					row[2] = m.Error
+				case m.Kernel != nil:
+					row[2] = "kernel"
+					row[3] = m.Kernel.GetPrefix()
+					row[4] = m.Kernel.GetType()
+					row[5] = m.Kernel.GetArch()
+					row[6] = m.Kernel.GetVersion()
+					row[7] = m.Kernel.GetSuffix()
+				case m.Initrd != nil:
+					row[2] = "initrd"
+					row[3] = m.Initrd.GetPrefix()
+					row[4] = m.Initrd.GetKernelType()
+					row[5] = m.Initrd.GetArch()
+					row[6] = m.Initrd.GetVersion()
+					row[7] = m.Initrd.GetSuffix()
+				}
+
+				table.Append(row)
+			}
+
+			table.Render()
+
+			if !selected {
+				fmt.Println(fmt.Sprintf("No kernel profile matches %s.", file))
+				os.Exit(1)
+			}
+		},
+	}
+
 	flags := c.Flags()
 	flags.Bool("json", false, "JSON output")
 	flags.String("kernel-profiles-dir", "",
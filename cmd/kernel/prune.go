@@ -0,0 +1,181 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmdkernel
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/macaroni-os/macaronictl/pkg/bootloader"
+	"github.com/macaroni-os/macaronictl/pkg/kernel"
+	"github.com/macaroni-os/macaronictl/pkg/logger"
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewPruneCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "prune [OPTIONS]",
+		Short: "Remove the old kernels from the boot directory.",
+		Long: `Remove the old kernels from the boot directory with the retention
+policy defined in the kernel.retention section of the config:
+
+kernel:
+  retention:
+    # Number of the newest kernels to keep for every kernel type.
+    keep: 2
+    # Keep the kernel of the bzImage link.
+    keep-current: true
+    # Keep the kernel of the bzImage.old link.
+    keep-old: true
+
+The running kernel is never removed. For every kernel removed are
+deleted the kernel image, the initrd image, the System.map and config
+files and the directory of the modules.
+
+$ macaronictl kernel prune --dry-run
+
+$ macaronictl kernel prune --keep 1 --grub
+
+NOTE: The kernel packages are not uninstalled.
+      This command requires root privilege.
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			modulesDir := getModulesDir(cmd, config)
+			grub, _ := cmd.Flags().GetBool("grub")
+			bootloaderName, _ := cmd.Flags().GetString("bootloader")
+			bootDir := getBootDir(cmd, config)
+			kernelProfilesDir, _ := cmd.Flags().GetString("kernel-profiles-dir")
+
+			log := logger.GetDefaultLogger()
+			retention := config.GetKernel().GetRetention()
+
+			opts := &kernel.PruneOpts{
+				Keep:        retention.Keep,
+				KeepCurrent: retention.KeepCurrent,
+				KeepOld:     retention.KeepOld,
+				ModulesDir:  modulesDir,
+				DryRun:      dryRun,
+			}
+
+			if cmd.Flags().Changed("keep") {
+				opts.Keep, _ = cmd.Flags().GetInt("keep")
+			}
+
+			// The running kernel is not related to the kernels of a chroot.
+			if !utils.IsChrootRootfs(config.GetGeneral().GetRootfs()) {
+				running, err := utils.RunningKernelRelease()
+				if err != nil {
+					log.Warning("Error on retrieve the running kernel release: " + err.Error())
+				} else {
+					opts.RunningRelease = running
+				}
+			}
+
+			types := loadKernelTypes(config, kernelProfilesDir)
+
+			bootFiles, err := kernel.ReadBootDir(bootDir, types)
+			if err != nil {
+				fmt.Println("Error on read boot directory: " + err.Error())
+				os.Exit(1)
+			}
+
+			report := kernel.NewPruneReport(bootFiles, opts)
+			err = report.Apply(bootFiles.Dir, opts)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			pruned := report.GetPruned()
+
+			if jsonOutput {
+				data, err := json.Marshal(report)
+				if err != nil {
+					fmt.Println(fmt.Errorf("Error on convert data to json: %s", err.Error()))
+					os.Exit(1)
+				}
+				fmt.Println(string(data))
+			} else {
+				prefix := ""
+				if dryRun {
+					prefix = "[dry-run mode] "
+				}
+
+				for _, pk := range report.Kernels {
+					if pk.Keep {
+						log.Debug(fmt.Sprintf("Keeping kernel %s (%s).", pk.Kernel, pk.Reason))
+					}
+				}
+
+				if len(pruned) == 0 {
+					fmt.Println("No kernels to prune.")
+					return
+				}
+
+				for _, pk := range pruned {
+					fmt.Println(fmt.Sprintf("%sPruning kernel %s...", prefix, pk.Kernel))
+					for _, f := range pk.Files {
+						fmt.Println(fmt.Sprintf("%s- %s (%s)", prefix, f.Path,
+							kernel.HumanReadableSize(f.Size)))
+					}
+				}
+
+				fmt.Println(fmt.Sprintf("%sSpace freed: %s.", prefix,
+					kernel.HumanReadableSize(report.Freed)))
+			}
+
+			if grub && bootloaderName == "" {
+				bootloaderName = "grub"
+			}
+
+			if bootloaderName != "" && len(pruned) > 0 {
+				bl, err := bootloader.NewBootloader(bootloaderName,
+					newBootloaderOpts(config, dryRun))
+				if err != nil {
+					fmt.Println(err.Error())
+					os.Exit(1)
+				}
+
+				if !dryRun {
+					bootFiles, err = kernel.ReadBootDir(bootDir, types)
+					if err != nil {
+						fmt.Println("Error on read boot directory: " + err.Error())
+						os.Exit(1)
+					}
+				}
+
+				err = bl.Update(bootFiles)
+				if err != nil {
+					fmt.Println(fmt.Sprintf("Error on update %s configuration: %s",
+						bootloaderName, err.Error()))
+					os.Exit(1)
+				}
+			}
+		},
+	}
+
+	flags := c.Flags()
+	flags.Bool("json", false, "JSON output")
+	flags.Bool("dry-run", false, "Show the files to remove without remove them.")
+	flags.Int("keep", 0,
+		"Number of the newest kernels to keep for every kernel type. Default is the kernel.retention.keep option of the config.")
+	flags.Bool("grub", false, "Update grub.cfg after the prune.")
+	flags.String("bootloader", "",
+		"Update the configuration of the selected bootloader (grub, bls, extlinux).")
+	flags.String("bootdir", "",
+		"Directory where analyze kernel files. Default is the kernel.bootdir option of the config.")
+	flags.String("modules-dir", "/lib/modules", "Directory of the kernel modules.")
+	flags.String("kernel-profiles-dir", "",
+		"Specify the directory where read the kernel types profiles supported.")
+
+	return c
+}
@@ -0,0 +1,223 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmdkernel
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/macaroni-os/macaronictl/pkg/kernel"
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+	"github.com/macaroni-os/macaronictl/pkg/logger"
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// Error of the kernels that can't be removed because they are
+// the running kernel or the target of the bzImage link.
+type kernelInUseError struct {
+	msg string
+}
+
+func (e *kernelInUseError) Error() string {
+	return e.msg
+}
+
+// Return true if the error is related to a kernel in use.
+func isKernelInUse(err error) bool {
+	var e *kernelInUseError
+	return errors.As(err, &e)
+}
+
+// Uninstall the kernel of the selected branch and all the extra modules
+// installed for the same branch. The branch is without the category
+// prefix of the kernel type (6.1 and not zen-6.1). The running kernel and the kernel
+// selected by the bzImage link are never removed.
+func removeKernel(config *specs.MacaroniCtlConfig,
+	kernelName, branch, kType, bootDir string,
+	types []kernelspecs.KernelType, dryRun bool) error {
+
+	log := logger.GetDefaultLogger()
+
+	installed, err := kernel.InstalledKernels(config)
+	if err != nil {
+		return fmt.Errorf("Error on retrieve installed kernels: %s", err.Error())
+	}
+
+	var target *specs.Stone = nil
+	var annotation *specs.KernelAnnotation = nil
+	for _, s := range installed.Stones {
+		a, err := kernel.ParseKernelAnnotations(s)
+		if err != nil {
+			return fmt.Errorf("[%s/%s] Error on parse annotation: %s",
+				s.Category, s.Name, err.Error())
+		}
+
+		if a.Suffix != kernelName || a.Type != kType {
+			continue
+		}
+
+		if s.Category == kernel.KernelCategory(branch, kType) {
+			target = s
+			annotation = a
+			break
+		}
+	}
+
+	if target == nil {
+		return fmt.Errorf("The kernel %s and branch %s is not installed.",
+			kernelName, branch)
+	}
+
+	kversion := target.GetLabelValue("package.version")
+	if kversion == "" {
+		kversion = target.GetVersion()
+	}
+
+	running := ""
+	if !utils.IsChrootRootfs(config.GetGeneral().GetRootfs()) {
+		// The running kernel is not related to the kernels of a chroot.
+		running, err = utils.RunningKernelRelease()
+		if err != nil {
+			log.Warning("Error on retrieve the running kernel release: " + err.Error())
+		}
+	}
+
+	if running != "" && running == kversion+"-"+annotation.Suffix {
+		return &kernelInUseError{fmt.Sprintf("The kernel %s is the running kernel. I can't remove it.",
+			target.HumanReadableString())}
+	}
+
+	bootFiles, err := kernel.ReadBootDir(bootDir, types)
+	if err != nil {
+		return fmt.Errorf("Error on read boot directory: %s", err.Error())
+	}
+
+	// The kernel files could be already removed from the boot directory.
+	kf, _ := bootFiles.GetFile(kversion, kType)
+	if kf != nil && kf.Kernel != nil {
+		if running != "" && kf.Kernel.GetRelease() == running {
+			return &kernelInUseError{fmt.Sprintf("The kernel %s is the running kernel. I can't remove it.",
+				target.HumanReadableString())}
+		}
+
+		if bootFiles.BzImageLink == kf.Kernel.GetFilename() {
+			return &kernelInUseError{fmt.Sprintf(
+				"The kernel %s is the current bzImage target. Switch the links before remove it.",
+				target.HumanReadableString())}
+		}
+	}
+
+	// Retrieve installed extra modules of the same branch.
+	modules, err := kernel.AvailableExtraModules(branch, kType, true, config)
+	if err != nil {
+		return fmt.Errorf("Error on retrieve installed kernel modules: %s", err.Error())
+	}
+
+	fmt.Println(fmt.Sprintf("Kernel to remove %s...", target.HumanReadableString()))
+	if len(modules.Stones) > 0 {
+		fmt.Println("Modules extra to remove:")
+		for _, m := range modules.Stones {
+			fmt.Println("- " + m.HumanReadableString())
+		}
+	}
+
+	if dryRun {
+		if kf != nil && kf.Initrd != nil {
+			fmt.Println("[dry-run mode] removing initrd image " +
+				filepath.Join(bootFiles.Dir, kf.Initrd.GetFilename()))
+		}
+		return nil
+	}
+
+	err = kernel.RemovePackages(target, modules.Stones, config)
+	if err != nil {
+		return err
+	}
+
+	// The initrd image is generated by macaronictl and
+	// it's not part of the kernel package.
+	if kf != nil && kf.Initrd != nil {
+		initrdFile := filepath.Join(bootFiles.Dir, kf.Initrd.GetFilename())
+		if utils.Exists(initrdFile) {
+			fmt.Print(fmt.Sprintf("Removing initrd %s...", initrdFile))
+			err = os.Remove(initrdFile)
+			if err != nil {
+				return err
+			}
+			fmt.Println("DONE.")
+		}
+	}
+
+	return nil
+}
+
+func NewRemoveCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
+	c := &cobra.Command{
+		Use:     "remove <kernel>@<kernel-branch> [OPTIONS]",
+		Aliases: []string{"rm"},
+		Short:   "Remove an installed kernel branch.",
+		Long: `Uninstall an installed kernel branch and the related extra modules.
+
+$ macaronictl kernel remove macaroni@5.15
+
+$ macaronictl kernel remove macaroni@6.1 --type zen --dry-run
+
+NOTE: The running kernel and the kernel selected by the bzImage
+      link are not removed.
+      This command requires root privilege.
+`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			if len(args) > 1 {
+				fmt.Println("More of one kernel defined. Only one is accepted.")
+				os.Exit(1)
+			} else if len(args) == 0 {
+				fmt.Println("Missing mandatory argument.")
+				os.Exit(1)
+			}
+
+			if strings.Index(args[0], "@") < 0 {
+				fmt.Println("Malformed argument.")
+				os.Exit(1)
+			}
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			kType, _ := cmd.Flags().GetString("type")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			bootDir := getBootDir(cmd, config)
+			kernelProfilesDir, _ := cmd.Flags().GetString("kernel-profiles-dir")
+
+			// Parse input argument
+			param := args[0]
+			requiredKernel := param[0:strings.Index(param, "@")]
+			requiredBranch := param[strings.Index(param, "@")+1:]
+
+			types := loadKernelTypes(config, kernelProfilesDir)
+
+			err := removeKernel(config, requiredKernel, requiredBranch, kType,
+				bootDir, types, dryRun)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+
+	flags := c.Flags()
+	flags.Bool("dry-run", false, "Dry run and show the packages to remove.")
+	flags.String("type", "vanilla", "Define the kernel type to remove.")
+	flags.String("bootdir", "",
+		"Directory where analyze kernel files. Default is the kernel.bootdir option of the config.")
+	flags.String("kernel-profiles-dir", "",
+		"Specify the directory where read the kernel types profiles supported.")
+
+	return c
+}
@@ -0,0 +1,150 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmdkernel
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/macaroni-os/macaronictl/pkg/bootloader"
+	"github.com/macaroni-os/macaronictl/pkg/kernel"
+	"github.com/macaroni-os/macaronictl/pkg/logger"
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// Swap the link <name> with the link <name>.old. If the link
+// <name>.old doesn't exist the link <name> is moved as fallback.
+func swapLink(bootDir, name string, dryRun bool) error {
+	log := logger.GetDefaultLogger()
+
+	link := filepath.Join(bootDir, name)
+	oldLink := filepath.Join(bootDir, name+".old")
+
+	current, _ := os.Readlink(link)
+	previous, _ := os.Readlink(oldLink)
+
+	if current == "" && previous == "" {
+		return nil
+	}
+
+	if dryRun {
+		fmt.Println(fmt.Sprintf("[dry-run mode] %s -> %s, %s.old -> %s",
+			name, previous, name, current))
+		return nil
+	}
+
+	// Ignoring errors
+	os.Remove(link)
+	os.Remove(oldLink)
+
+	if previous != "" {
+		log.DebugC("Creating link", name, "to", previous)
+		err := os.Symlink(previous, link)
+		if err != nil {
+			return err
+		}
+	}
+
+	if current != "" {
+		log.DebugC("Creating link", name+".old", "to", current)
+		err := os.Symlink(current, oldLink)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func NewRollbackCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "rollback",
+		Short: "Restore the previous bzImage and Initrd links.",
+		Long: `Swap the bzImage, Initrd links with the fallback links
+bzImage.old, Initrd.old created on the last links update.
+
+$> macaronictl kernel rollback
+
+$> # Swap the links and update grub.cfg
+$> macaronictl kernel rollback --grub
+
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+
+			bootDir := getBootDir(cmd, config)
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			grub, _ := cmd.Flags().GetBool("grub")
+			bootloaderName, _ := cmd.Flags().GetString("bootloader")
+			kernelProfilesDir, _ := cmd.Flags().GetString("kernel-profiles-dir")
+
+			types := loadKernelTypes(config, kernelProfilesDir)
+
+			bootFiles, err := kernel.ReadBootDir(bootDir, types)
+			if err != nil {
+				fmt.Println("Error on read boot directory: " + err.Error())
+				os.Exit(1)
+			}
+
+			if bootFiles.BzImageOldLink == "" {
+				fmt.Println("No fallback links available.")
+				os.Exit(1)
+			}
+
+			if !utils.Exists(filepath.Join(bootFiles.Dir, bootFiles.BzImageOldLink)) {
+				fmt.Println(fmt.Sprintf("The fallback kernel %s doesn't exist.",
+					bootFiles.BzImageOldLink))
+				os.Exit(1)
+			}
+
+			for _, name := range []string{"bzImage", "Initrd"} {
+				err = swapLink(bootFiles.Dir, name, dryRun)
+				if err != nil {
+					fmt.Println(fmt.Sprintf("Error on swap %s links: %s",
+						name, err.Error()))
+					os.Exit(1)
+				}
+			}
+
+			fmt.Println(fmt.Sprintf("Links restored to kernel %s.",
+				bootFiles.BzImageOldLink))
+
+			if grub && bootloaderName == "" {
+				bootloaderName = "grub"
+			}
+
+			if bootloaderName != "" {
+				blOpts := newBootloaderOpts(config, dryRun)
+				bl, err := bootloader.NewBootloader(bootloaderName, blOpts)
+				if err != nil {
+					fmt.Println(err.Error())
+					os.Exit(1)
+				}
+
+				err = bl.Update(bootFiles)
+				if err != nil {
+					fmt.Println(fmt.Sprintf("Error on update %s configuration: %s",
+						bl.GetName(), err.Error()))
+					os.Exit(1)
+				}
+			}
+		},
+	}
+
+	flags := c.Flags()
+	flags.Bool("dry-run", false, "Dry run commands.")
+	flags.Bool("grub", false, "Update grub.cfg.")
+	flags.String("bootloader", "",
+		"Update the configuration of the selected bootloader (grub, bls, extlinux).")
+	flags.String("bootdir", "",
+		"Directory where analyze kernel files. Default is the kernel.bootdir option of the config.")
+	flags.String("kernel-profiles-dir", "",
+		"Specify the directory where read the kernel types profiles supported.")
+
+	return c
+}
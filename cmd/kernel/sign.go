@@ -0,0 +1,189 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmdkernel
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/macaroni-os/macaronictl/pkg/kernel"
+	"github.com/macaroni-os/macaronictl/pkg/logger"
+	"github.com/macaroni-os/macaronictl/pkg/secureboot"
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+
+	"github.com/spf13/cobra"
+)
+
+// Return the signer used to sign automatically the kernel images
+// or nil if the kernel.secureboot.sign option is disabled.
+func newAutoSigner(config *specs.MacaroniCtlConfig) (*secureboot.Signer, error) {
+	sb := config.GetKernel().GetSecureBoot()
+	if !sb.Sign {
+		return nil, nil
+	}
+
+	signer, err := secureboot.NewSigner(sb.Key, sb.Cert)
+	if err != nil {
+		return nil, fmt.Errorf("Error on load Secure Boot key: %s", err.Error())
+	}
+
+	return signer, nil
+}
+
+// Sign the PE image of the file. The images that aren't PE
+// images are skipped with a warning.
+func signImage(signer *secureboot.Signer, file string, dryRun bool) error {
+	log := logger.GetDefaultLogger()
+
+	if dryRun {
+		fmt.Println(fmt.Sprintf("[dry-run mode] signing %s with %s",
+			file, signer.Cert.Subject.String()))
+		return nil
+	}
+
+	signed, err := signer.SignFile(file)
+	if err != nil {
+		if errors.Is(err, secureboot.ErrNotPEImage) {
+			fmt.Println(fmt.Sprintf(
+				"WARN: The file %s is not a PE image. Signing skipped.", file))
+			return nil
+		}
+		return fmt.Errorf("Error on sign %s: %s", file, err.Error())
+	}
+
+	if signed {
+		fmt.Println(fmt.Sprintf("Signed %s.", file))
+	} else {
+		log.Debug(fmt.Sprintf("The file %s is already signed.", file))
+	}
+
+	return nil
+}
+
+func NewSignCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "sign [OPTIONS]",
+		Short: "Sign kernel images and UKIs for Secure Boot.",
+		Long: `Authenticode-sign the kernel images or the Unified Kernel Images
+with the key and the certificate of the kernel.secureboot section of
+the config:
+
+kernel:
+  secureboot:
+    # Sign the kernel images automatically on switch, upgrade,
+    # geninitrd and uki commands.
+    sign: true
+    key: /etc/macaroni/secureboot/db.key
+    cert: /etc/macaroni/secureboot/db.crt
+
+$> # Sign all kernel images of the boot directory.
+$> macaronictl kernel sign --all
+
+$> # Sign the kernel 6.1.12 with a specific key.
+$> macaronictl kernel sign --version 6.1.12 --key db.key --cert db.crt
+
+$> # Sign an UKI.
+$> macaronictl kernel sign --file /boot/EFI/Linux/macaroni-kernel-6.1.12.efi
+
+NOTE: The images already signed with the same certificate are skipped.
+      The key and the certificate are read from the host also with
+      the --rootfs option.
+`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			all, _ := cmd.Flags().GetBool("all")
+			version, _ := cmd.Flags().GetString("version")
+			files, _ := cmd.Flags().GetStringSlice("file")
+			if !all && version == "" && len(files) == 0 {
+				fmt.Println("You need to use --all, --version or --file")
+				os.Exit(1)
+			}
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			bootDir := getBootDir(cmd, config)
+			all, _ := cmd.Flags().GetBool("all")
+			version, _ := cmd.Flags().GetString("version")
+			ktype, _ := cmd.Flags().GetString("ktype")
+			files, _ := cmd.Flags().GetStringSlice("file")
+			keyFile, _ := cmd.Flags().GetString("key")
+			certFile, _ := cmd.Flags().GetString("cert")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			kernelProfilesDir, _ := cmd.Flags().GetString("kernel-profiles-dir")
+
+			sb := config.GetKernel().GetSecureBoot()
+			if keyFile == "" {
+				keyFile = sb.Key
+			}
+			if certFile == "" {
+				certFile = sb.Cert
+			}
+
+			signer, err := secureboot.NewSigner(keyFile, certFile)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			if all || version != "" {
+				types := loadKernelTypes(config, kernelProfilesDir)
+
+				bootFiles, err := kernel.ReadBootDir(bootDir, types)
+				if err != nil {
+					fmt.Println("Error on read boot directory: " + err.Error())
+					os.Exit(1)
+				}
+
+				if all {
+					for _, f := range bootFiles.Files {
+						if f.Kernel != nil {
+							files = append(files,
+								filepath.Join(bootFiles.Dir, f.Kernel.GetFilename()))
+						}
+					}
+				} else {
+					file, err := bootFiles.GetFile(version, ktype)
+					if err != nil {
+						fmt.Println(err.Error())
+						os.Exit(1)
+					}
+					files = append(files,
+						filepath.Join(bootFiles.Dir, file.Kernel.GetFilename()))
+				}
+			}
+
+			nErrors := 0
+			for _, f := range files {
+				err = signImage(signer, f, dryRun)
+				if err != nil {
+					fmt.Println(err.Error())
+					nErrors++
+				}
+			}
+
+			if nErrors > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	flags := c.Flags()
+	flags.Bool("all", false, "Sign all kernel images of the boot directory.")
+	flags.Bool("dry-run", false, "Show the files to sign without sign them.")
+	flags.String("version", "", "Specify the kernel version of the image to sign.")
+	flags.String("ktype", "", "Specify the kernel type of the image to sign.")
+	flags.StringSlice("file", []string{}, "Path of a PE image or UKI to sign.")
+	flags.String("key", "",
+		"Path of the PEM private key. Default is the kernel.secureboot.key option of the config.")
+	flags.String("cert", "",
+		"Path of the certificate. Default is the kernel.secureboot.cert option of the config.")
+	flags.String("bootdir", "",
+		"Directory where analyze kernel files. Default is the kernel.bootdir option of the config.")
+	flags.String("kernel-profiles-dir", "",
+		"Specify the directory where read the kernel types profiles supported.")
+
+	return c
+}
@@ -0,0 +1,104 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmdkernel
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/macaroni-os/macaronictl/pkg/kernel"
+	"github.com/macaroni-os/macaronictl/pkg/logger"
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+
+	tablewriter "github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+func NewStatusCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "status",
+		Short: "Show the EOL status of the installed kernels.",
+		Long: `Check the EOL of the installed kernels.
+
+$> macaronictl kernel status
+
+$> # Flag as near EOL the kernels with less than 30 days of support.
+$> macaronictl kernel status --near-days 30 --json
+
+The command exits with 0 if all kernels are supported, 1 if there are
+kernels near the EOL, 2 if there are kernels EOL and 3 if the installed
+kernels can't be retrieved.
+
+NOTE: The EOL is read from the repositories. It works better if the
+repositories are synced.
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+
+			log := logger.GetDefaultLogger()
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+			nearDays, _ := cmd.Flags().GetInt("near-days")
+
+			installed, err := kernel.InstalledKernels(config)
+			if err != nil {
+				fmt.Println("Error on retrieve installed kernel: " + err.Error())
+				// The exit codes 1 and 2 are used for the EOL status.
+				os.Exit(3)
+			}
+
+			availables, err := kernel.AvailableKernels(config)
+			if err != nil {
+				log.Warning(fmt.Sprintf("Error on retrieve available kernels: %s", err.Error()))
+				availables = nil
+			}
+
+			status := kernel.InstalledKernelsStatus(installed, availables,
+				time.Now(), nearDays)
+
+			if jsonOutput {
+				fmt.Println(status)
+			} else if len(status.Kernels) == 0 {
+				fmt.Println("No kernels installed.")
+			} else {
+				table := tablewriter.NewWriter(os.Stdout)
+				table.Header(
+					"Package",
+					"Kernel Version",
+					"LTS",
+					"Eol",
+					"Days Left",
+					"Status",
+				)
+
+				for _, k := range status.Kernels {
+					daysLeft := ""
+					if k.EoLDate != "" {
+						daysLeft = fmt.Sprintf("%d", k.DaysLeft)
+					}
+
+					table.Append([]string{
+						k.Package,
+						k.Kernel,
+						fmt.Sprintf("%v", k.Lts),
+						k.EoL,
+						daysLeft,
+						k.Status,
+					})
+				}
+
+				table.Render()
+			}
+
+			os.Exit(status.ExitCode())
+		},
+	}
+
+	flags := c.Flags()
+	flags.Bool("json", false, "JSON output")
+	flags.Int("near-days", kernel.DefaultNearEolDays,
+		"Number of days before the EOL to flag a kernel as near EOL.")
+
+	return c
+}
@@ -1,21 +1,178 @@
 /*
-Copyright © 2021-2024 Macaroni OS Linux
+Copyright © 2021-2026 Macaroni OS Linux
 See AUTHORS and LICENSE for the license details and contributors.
 */
 package cmdkernel
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/macaroni-os/macaronictl/pkg/bootloader"
+	"github.com/macaroni-os/macaronictl/pkg/initrd"
 	"github.com/macaroni-os/macaronictl/pkg/kernel"
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
 	"github.com/macaroni-os/macaronictl/pkg/logger"
+	"github.com/macaroni-os/macaronictl/pkg/secureboot"
 	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
 
 	"github.com/spf13/cobra"
 )
 
+type switchPostInstallOpts struct {
+	BootDir string
+	Rootfs  string
+	KType   string
+	Types   []kernelspecs.KernelType
+
+	BuilderSelector *initrd.InitrdBuilderSelector
+	BootloaderOpts  *bootloader.BootloaderOpts
+	// Signer of the kernel image. nil if the signing is disabled.
+	Signer *secureboot.Signer
+
+	Geninitrd  bool
+	SetLinks   bool
+	Bootloader string
+	DryRun     bool
+}
+
+type switchStep struct {
+	Name string
+	Fn   func() error
+}
+
+// Run the post install steps of the switched kernel: initrd generation,
+// Secure Boot signing, bzImage/Initrd links and grub configuration.
+// Every step reports its own result and the pipeline stops on the
+// first failure.
+func switchPostInstall(candidate *specs.Stone, opts *switchPostInstallOpts) error {
+	var bootFiles *kernelspecs.BootFiles
+	var kf *kernelspecs.KernelFiles
+
+	steps := []switchStep{
+		{
+			Name: "Read boot directory",
+			Fn: func() error {
+				var err error
+
+				// The boot directory is read also in dry-run mode
+				// to show the changes of the next steps.
+				bootFiles, err = kernel.ReadBootDir(opts.BootDir, opts.Types)
+				if err != nil {
+					return err
+				}
+
+				version := candidate.GetLabelValue("package.version")
+				if version == "" {
+					return fmt.Errorf("No package.version label found for %s",
+						candidate.HumanReadableString())
+				}
+
+				kf, err = bootFiles.GetFile(version, opts.KType)
+				if err != nil && opts.DryRun {
+					// POST: the kernel isn't installed in dry-run mode.
+					fmt.Println(fmt.Sprintf(
+						"[dry-run mode] kernel %s not available in %s",
+						version, bootFiles.Dir))
+					return nil
+				}
+				return err
+			},
+		},
+	}
+
+	if opts.Geninitrd {
+		steps = append(steps, switchStep{
+			Name: "Generate initrd image",
+			Fn: func() error {
+				if opts.DryRun {
+					fmt.Println("[dry-run mode] initrd builder: " +
+						opts.BuilderSelector.GetBuilderName(kf))
+					return nil
+				}
+				return opts.BuilderSelector.Build(kf, bootFiles.Dir)
+			},
+		})
+	}
+
+	if opts.Signer != nil {
+		steps = append(steps, switchStep{
+			Name: "Sign kernel image",
+			Fn: func() error {
+				if opts.DryRun {
+					fmt.Println("[dry-run mode] signing kernel image with " +
+						opts.Signer.Cert.Subject.String())
+					return nil
+				}
+				return signImage(opts.Signer,
+					filepath.Join(bootFiles.Dir, kf.Kernel.GetFilename()), false)
+			},
+		})
+	}
+
+	if opts.SetLinks {
+		steps = append(steps, switchStep{
+			Name: "Set bzImage and Initrd links",
+			Fn: func() error {
+				if opts.DryRun {
+					if kf != nil {
+						fmt.Println("[dry-run mode] setting links to " +
+							kf.Kernel.GetFilename())
+					}
+					return nil
+				}
+
+				release, err := utils.OsReleaseRootfs(opts.Rootfs)
+				if err != nil {
+					return err
+				}
+				return setFilesLinks(kf, bootFiles.Dir, release)
+			},
+		})
+	}
+
+	if opts.Bootloader != "" {
+		steps = append(steps, switchStep{
+			Name: fmt.Sprintf("Update %s configuration", opts.Bootloader),
+			Fn: func() error {
+				bl, err := bootloader.NewBootloader(opts.Bootloader, opts.BootloaderOpts)
+				if err != nil {
+					return err
+				}
+
+				return bl.Update(bootFiles)
+			},
+		})
+	}
+
+	for idx, step := range steps {
+		err := step.Fn()
+		if err != nil {
+			fmt.Println(fmt.Sprintf("[%d/%d] %s: FAILED (%s)",
+				idx+1, len(steps), step.Name, err.Error()))
+
+			for _, skipped := range steps[idx+1:] {
+				fmt.Println(fmt.Sprintf("[-/%d] %s: SKIPPED", len(steps), skipped.Name))
+			}
+
+			return errors.New("Post install pipeline failed.")
+		}
+
+		if opts.DryRun {
+			fmt.Println(fmt.Sprintf("[%d/%d] %s: [dry-run mode]",
+				idx+1, len(steps), step.Name))
+		} else {
+			fmt.Println(fmt.Sprintf("[%d/%d] %s: DONE", idx+1, len(steps), step.Name))
+		}
+	}
+
+	return nil
+}
+
 func NewSwitchCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
 	c := &cobra.Command{
 		Use:     "switch <kernel>@<kernel-branch> [OPTIONS]",
@@ -27,6 +184,13 @@ $ macaronictl kernel switch macaroni@6.1 --purge
 
 $ macaronictl kernel switch macaroni@6.1 --from 5.15
 
+$ # Abort if an installed extra module isn't available for 6.1.
+$ macaronictl kernel switch macaroni@6.1 --strict
+
+$ # Switch the kernel and generate the initrd image, set the
+$ # bzImage, Initrd links and update grub.cfg.
+$ macaronictl kernel switch macaroni@6.1 --geninitrd --set-links --grub
+
 NOTE: It works only if the repositories are synced and the branch
       is not yet installed.
       Please, use --purge carefully. Often on switch it's better
@@ -53,6 +217,16 @@ NOTE: It works only if the repositories are synced and the branch
 			dryRun, _ := cmd.Flags().GetBool("dry-run")
 			from, _ := cmd.Flags().GetString("from")
 			fromType, _ := cmd.Flags().GetString("from-type")
+			purge, _ := cmd.Flags().GetBool("purge")
+			strict, _ := cmd.Flags().GetBool("strict")
+			geninitrd, _ := cmd.Flags().GetBool("geninitrd")
+			setLinks, _ := cmd.Flags().GetBool("set-links")
+			grub, _ := cmd.Flags().GetBool("grub")
+			bootloaderName, _ := cmd.Flags().GetString("bootloader")
+			dracutOpts, _ := cmd.Flags().GetString("dracut-opts")
+			initrdBuilder, _ := cmd.Flags().GetString("initrd-builder")
+			bootDir := getBootDir(cmd, config)
+			kernelProfilesDir, _ := cmd.Flags().GetString("kernel-profiles-dir")
 
 			// Parse input argument
 			param := args[0]
@@ -68,6 +242,9 @@ NOTE: It works only if the repositories are synced and the branch
 				os.Exit(1)
 			}
 
+			// Branches of the same kernel to purge when --from is not used.
+			purgeBranches := []string{}
+
 			for _, s := range installed.Stones {
 				a, err := kernel.ParseKernelAnnotations(s)
 				if err != nil {
@@ -85,13 +262,16 @@ NOTE: It works only if the repositories are synced and the branch
 					continue
 				}
 
-				if s.Category == "kernel-"+requiredBranch {
+				if kernel.KernelBranch(s, a.Type) == requiredBranch {
 					log.Error(fmt.Sprintf(
 						"The kernel %s and branch %s is already installed.",
 						requiredKernel, requiredBranch,
 					))
 					os.Exit(1)
 				}
+
+				purgeBranches = append(purgeBranches,
+					kernel.KernelBranch(s, a.Type))
 			}
 
 			available, err := kernel.AvailableKernels(config)
@@ -119,7 +299,7 @@ NOTE: It works only if the repositories are synced and the branch
 					continue
 				}
 
-				if s.Category == "kernel-"+requiredBranch {
+				if s.Category == kernel.KernelCategory(requiredBranch, kType) {
 					candidate = s
 					break
 				}
@@ -140,15 +320,10 @@ NOTE: It works only if the repositories are synced and the branch
 			}
 
 			kextraModsMap := make(map[string]*specs.Stone, 0)
-			sourceCategoryPrefix := "kernel-"
-			switch fromType {
-			case "zen":
-				sourceCategoryPrefix = "kernel-zen-"
-			}
 
 			// Prepare map of all installed module
 			for _, s := range availableInstMods.Stones {
-				if from != "" && s.Category != sourceCategoryPrefix+from {
+				if from != "" && s.Category != kernel.KernelCategory(from, fromType) {
 					continue
 				}
 				kextraModsMap[s.Name] = s
@@ -170,6 +345,19 @@ NOTE: It works only if the repositories are synced and the branch
 				}
 			}
 
+			kextraMods := &specs.StonesPack{Stones: []*specs.Stone{}}
+			for _, s := range kextraModsMap {
+				kextraMods.Stones = append(kextraMods.Stones, s)
+			}
+
+			coverage := kernel.NewModulesCoverage(requiredBranch, kType,
+				kextraMods, availableModules)
+			printModulesCoverage(coverage)
+			if strict && !coverage.IsComplete() {
+				fmt.Println("Switch aborted: extra modules would be lost with --strict.")
+				os.Exit(1)
+			}
+
 			fmt.Println(fmt.Sprintf(
 				"Found kernel candidate %s...",
 				candidate.HumanReadableString()))
@@ -182,18 +370,92 @@ NOTE: It works only if the repositories are synced and the branch
 			}
 
 			if !dryRun {
-				err = kernel.InstallPackages(candidate, candidateModules)
+				err = kernel.InstallPackages(candidate, candidateModules, config)
 				if err != nil {
 					fmt.Println(err.Error())
 					os.Exit(1)
 				}
 			}
 
+			types := loadKernelTypes(config, kernelProfilesDir)
+
+			signer, err := newAutoSigner(config)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			if grub && bootloaderName == "" {
+				bootloaderName = "grub"
+			}
+
+			if geninitrd || setLinks || bootloaderName != "" || signer != nil {
+				err = switchPostInstall(candidate, &switchPostInstallOpts{
+					BootDir: bootDir,
+					Rootfs:  config.GetGeneral().GetRootfs(),
+					KType:   kType,
+					BuilderSelector: newInitrdBuilderSelector(config,
+						initrdBuilder, dracutOpts, dryRun),
+					BootloaderOpts: newBootloaderOpts(config, dryRun),
+					Signer:         signer,
+					Types:          types,
+					Geninitrd:      geninitrd,
+					SetLinks:       setLinks,
+					Bootloader:     bootloaderName,
+					DryRun:         dryRun,
+				})
+				if err != nil {
+					fmt.Println(err.Error())
+					os.Exit(1)
+				}
+			}
+
+			if purge {
+				purgeType := kType
+				if from != "" {
+					purgeBranches = []string{from}
+					purgeType = fromType
+				}
+
+				for _, branch := range purgeBranches {
+					err = removeKernel(config, requiredKernel, branch, purgeType,
+						bootDir, types, dryRun)
+					if err != nil && isKernelInUse(err) {
+						// POST: the new kernel is already installed.
+						fmt.Println(fmt.Sprintf(
+							"WARN: Skipping purge of kernel %s@%s: %s",
+							requiredKernel, branch, err.Error()))
+						continue
+					} else if err != nil {
+						fmt.Println(fmt.Sprintf(
+							"Error on purge kernel %s@%s: %s",
+							requiredKernel, branch, err.Error()))
+						os.Exit(1)
+					}
+				}
+			}
+
 		},
 	}
 
 	flags := c.Flags()
 	flags.Bool("purge", false, "Purge the installed kernels.")
+	flags.Bool("strict", false,
+		"Abort the switch if an installed extra module has no package for the new branch.")
+	flags.Bool("geninitrd", false, "Generate the initrd image of the installed kernel.")
+	flags.Bool("set-links", false, "Set bzImage and Initrd links to the installed kernel.")
+	flags.Bool("grub", false, "Update grub.cfg after the installation.")
+	flags.String("bootloader", "",
+		"Update the configuration of the selected bootloader (grub, bls, extlinux).")
+	flags.String("initrd-builder", "",
+		"Override the backend used to build the initrd image (dracut, mkinitcpio, booster).")
+	flags.String("dracut-opts", "",
+		`Override the dracut options of the kernel profiles and of the configuration
+used on the initrd image generation. Set the MACARONICTL_DRACUT_ARGS env in alternative.`)
+	flags.String("bootdir", "",
+		"Directory where analyze kernel files. Default is the kernel.bootdir option of the config.")
+	flags.String("kernel-profiles-dir", "",
+		"Specify the directory where read the kernel types profiles supported.")
 	flags.Bool("dry-run", false, "Dry run installation and show candidates.")
 	flags.String("type", "vanilla", "Define the kernel type to use.")
 	flags.String("from", "", "Define the kernel branch to replace.")
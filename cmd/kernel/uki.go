@@ -0,0 +1,160 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmdkernel
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/macaroni-os/macaronictl/pkg/kernel"
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+	"github.com/macaroni-os/macaronictl/pkg/uki"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// Return the UKI builder with the stub, the ESP directory and the
+// os-release file resolved under the rootfs of the config. Without
+// the ESP directory the images are written under the boot directory.
+func newUKIBuilder(config *specs.MacaroniCtlConfig, stub, espDir, bootDir string,
+	dryRun bool) *uki.UKIBuilder {
+
+	rootfs := config.GetGeneral().GetRootfs()
+	if espDir == "" {
+		espDir = bootDir
+	} else {
+		espDir = utils.RootfsPath(rootfs, espDir)
+	}
+
+	ans := uki.NewUKIBuilder(utils.RootfsPath(rootfs, stub), espDir, dryRun)
+	ans.Rootfs = rootfs
+	ans.OsReleaseFile = utils.RootfsPath(rootfs, ans.OsReleaseFile)
+
+	return ans
+}
+
+func NewUKICommand(config *specs.MacaroniCtlConfig) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "uki",
+		Short: "Build Unified Kernel Images.",
+		Long: `Build Unified Kernel Images (UKI) from the kernel and initrd images
+available on boot dir. The images are written under the EFI/Linux directory
+of the ESP. The UKIs are signed for Secure Boot when the
+kernel.secureboot.sign option of the config is enabled.
+
+$> # Build the UKI of the kernel 6.1.12.
+$> macaronictl kernel uki --version 6.1.12
+
+$> # Build the UKI of all kernels with a custom command line.
+$> macaronictl kernel uki --all --cmdline "root=/dev/sda2 quiet"
+
+$> # Just show what UKI will be created.
+$> macaronictl kernel uki --all --dry-run
+
+`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			all, _ := cmd.Flags().GetBool("all")
+			version, _ := cmd.Flags().GetString("version")
+			if !all && version == "" {
+				fmt.Println("You need to use --all or --version")
+				os.Exit(1)
+			}
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			bootDir := getBootDir(cmd, config)
+			espDir, _ := cmd.Flags().GetString("esp")
+			all, _ := cmd.Flags().GetBool("all")
+			version, _ := cmd.Flags().GetString("version")
+			ktype, _ := cmd.Flags().GetString("ktype")
+			stub, _ := cmd.Flags().GetString("stub")
+			cmdline, _ := cmd.Flags().GetString("cmdline")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			kernelProfilesDir, _ := cmd.Flags().GetString("kernel-profiles-dir")
+
+			types := loadKernelTypes(config, kernelProfilesDir)
+
+			bootFiles, err := kernel.ReadBootDir(bootDir, types)
+			if err != nil {
+				fmt.Println("Error on read boot directory: " + err.Error())
+				os.Exit(1)
+			}
+
+			builder := newUKIBuilder(config, stub, espDir, bootFiles.Dir, dryRun)
+			builder.Cmdline = cmdline
+
+			signer, err := newAutoSigner(config)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			if all {
+				nErrors := 0
+				for idx, f := range bootFiles.Files {
+					if f.Kernel == nil {
+						continue
+					}
+
+					ukiFile, err := builder.Build(bootFiles.Files[idx], bootFiles.Dir)
+					if err != nil {
+						fmt.Println(fmt.Sprintf("Error on build UKI for kernel %s: %s",
+							f.Kernel.GetFilename(), err.Error()))
+						nErrors++
+					} else if signer != nil {
+						err = signImage(signer, ukiFile, dryRun)
+						if err != nil {
+							fmt.Println(err.Error())
+							nErrors++
+						}
+					}
+				}
+
+				if nErrors > 0 {
+					os.Exit(1)
+				}
+
+			} else {
+				file, err := bootFiles.GetFile(version, ktype)
+				if err != nil {
+					fmt.Println(err.Error())
+					os.Exit(1)
+				}
+
+				ukiFile, err := builder.Build(file, bootFiles.Dir)
+				if err != nil {
+					fmt.Println(fmt.Sprintf("Error on build UKI for kernel %s: %s",
+						file.Kernel.GetFilename(), err.Error()))
+					os.Exit(1)
+				}
+
+				if signer != nil {
+					err = signImage(signer, ukiFile, dryRun)
+					if err != nil {
+						fmt.Println(err.Error())
+						os.Exit(1)
+					}
+				}
+			}
+		},
+	}
+
+	flags := c.Flags()
+	flags.Bool("all", false, "Build the UKI of all kernels.")
+	flags.Bool("dry-run", false, "Dry run commands.")
+	flags.String("bootdir", "",
+		"Directory where analyze kernel files. Default is the kernel.bootdir option of the config.")
+	flags.String("esp", "", "Directory of the EFI System Partition. Default is the boot dir.")
+	flags.String("version", "", "Specify the kernel version of the UKI to build.")
+	flags.String("ktype", "", "Specify the kernel type of the UKI to build.")
+	flags.String("stub", uki.DefaultEfiStub, "Path of the EFI stub to use.")
+	flags.String("cmdline", "",
+		"Kernel command line to embed. Default is the command line resolved with kernel cmdline.")
+	flags.String("kernel-profiles-dir", "",
+		"Specify the directory where read the kernel types profiles supported.")
+
+	return c
+}
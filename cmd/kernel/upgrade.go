@@ -0,0 +1,272 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmdkernel
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/macaroni-os/macaronictl/pkg/kernel"
+	"github.com/macaroni-os/macaronictl/pkg/logger"
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+
+	tablewriter "github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// Prepare the upgrades of the installed kernels with the
+// extra modules to install.
+func upgradePlan(config *specs.MacaroniCtlConfig, lts bool) ([]*kernel.KernelUpgrade, error) {
+	log := logger.GetDefaultLogger()
+	ans := []*kernel.KernelUpgrade{}
+
+	installed, err := kernel.InstalledKernels(config)
+	if err != nil {
+		return ans, fmt.Errorf("Error on retrieve installed kernels: %s", err.Error())
+	}
+
+	available, err := kernel.AvailableKernels(config)
+	if err != nil {
+		return ans, fmt.Errorf("Error on retrieve available kernels: %s", err.Error())
+	}
+	log.Debug(fmt.Sprintf(
+		"Found %d available kernels.", len(available.Stones)))
+
+	installedMap := make(map[string]bool, 0)
+	for _, s := range installed.Stones {
+		installedMap[s.GetName()] = true
+	}
+
+	plannedMap := make(map[string]bool, 0)
+
+	for _, s := range installed.Stones {
+		a, err := kernel.ParseKernelAnnotations(s)
+		if err != nil {
+			return ans, err
+		}
+
+		candidate, err := kernel.FindUpgradeCandidate(s, available, lts)
+		if err != nil {
+			return ans, err
+		}
+
+		if candidate == nil {
+			log.Debug(fmt.Sprintf("No upgrade available for %s.",
+				s.HumanReadableString()))
+			continue
+		}
+
+		if candidate.Category != s.Category && installedMap[candidate.GetName()] {
+			// POST: the LTS branch is already installed and it's
+			//       upgraded as installed kernel.
+			continue
+		}
+
+		if plannedMap[candidate.GetName()] {
+			continue
+		}
+		plannedMap[candidate.GetName()] = true
+
+		upgrade := &kernel.KernelUpgrade{
+			Installed: s,
+			Candidate: candidate,
+			Type:      a.Type,
+			Suffix:    a.Suffix,
+		}
+
+		installedMods, err := kernel.AvailableExtraModules(
+			kernel.KernelBranch(s, a.Type), a.Type, true, config,
+		)
+		if err != nil {
+			return ans, fmt.Errorf("Error on retrieve installed kernel modules: %s",
+				err.Error())
+		}
+
+		if len(installedMods.Stones) > 0 {
+			availableMods, err := kernel.AvailableExtraModules(
+				kernel.KernelBranch(candidate, a.Type), a.Type, false, config,
+			)
+			if err != nil {
+				return ans, fmt.Errorf("Error on retrieve available kernel modules: %s",
+					err.Error())
+			}
+
+			upgrade.Modules = kernel.SelectUpgradeModules(installedMods, availableMods,
+				!upgrade.IsBranchChange())
+			upgrade.InstalledModules = installedMods.Stones
+		}
+
+		ans = append(ans, upgrade)
+	}
+
+	return ans, nil
+}
+
+func NewUpgradeCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
+	c := &cobra.Command{
+		Use:     "upgrade [OPTIONS]",
+		Aliases: []string{"u", "up"},
+		Short:   "Upgrade the installed kernels to the newest release.",
+		Long: `Upgrade every installed kernel to the highest version available
+in the same branch or to the newest LTS branch with --lts.
+
+The extra modules installed are upgraded together with the kernel.
+
+$ macaronictl kernel upgrade --dry-run
+
+$ # Upgrade the kernels, generate the initrd image, set the
+$ # bzImage, Initrd links and update grub.cfg.
+$ macaronictl kernel upgrade --geninitrd --set-links --grub
+
+$ # Move to the newest LTS branch and remove the old branch.
+$ macaronictl kernel upgrade --lts --purge
+
+NOTE: It works only if the repositories are synced.
+      The --purge option is used only when the kernel is moved
+      to another branch.
+      This command requires root privilege.
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			lts, _ := cmd.Flags().GetBool("lts")
+			purge, _ := cmd.Flags().GetBool("purge")
+			geninitrd, _ := cmd.Flags().GetBool("geninitrd")
+			setLinks, _ := cmd.Flags().GetBool("set-links")
+			grub, _ := cmd.Flags().GetBool("grub")
+			bootloaderName, _ := cmd.Flags().GetString("bootloader")
+			dracutOpts, _ := cmd.Flags().GetString("dracut-opts")
+			initrdBuilder, _ := cmd.Flags().GetString("initrd-builder")
+			bootDir := getBootDir(cmd, config)
+			kernelProfilesDir, _ := cmd.Flags().GetString("kernel-profiles-dir")
+
+			upgrades, err := upgradePlan(config, lts)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			if len(upgrades) == 0 {
+				fmt.Println("No kernel upgrades available.")
+				return
+			}
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.Header(
+				"Kernel",
+				"Installed",
+				"Candidate",
+				"Extra Modules",
+			)
+
+			for _, u := range upgrades {
+				modules := []string{}
+				for _, m := range u.Modules {
+					modules = append(modules, m.HumanReadableString())
+				}
+
+				table.Append([]string{
+					u.Candidate.Name,
+					u.Installed.HumanReadableString(),
+					u.Candidate.HumanReadableString(),
+					strings.Join(modules, "\n"),
+				})
+			}
+
+			fmt.Println("Kernel upgrades plan:")
+			table.Render()
+
+			types := loadKernelTypes(config, kernelProfilesDir)
+
+			signer, err := newAutoSigner(config)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			if grub && bootloaderName == "" {
+				bootloaderName = "grub"
+			}
+
+			for _, u := range upgrades {
+				if !dryRun {
+					if u.IsBranchChange() {
+						err = kernel.InstallPackages(u.Candidate, u.Modules, config)
+					} else {
+						err = kernel.ReplacePackages(
+							append([]*specs.Stone{u.Installed}, u.GetReplacedModules()...),
+							append([]*specs.Stone{u.Candidate}, u.Modules...),
+							config,
+						)
+					}
+					if err != nil {
+						fmt.Println(err.Error())
+						os.Exit(1)
+					}
+				}
+
+				if geninitrd || setLinks || bootloaderName != "" || signer != nil {
+					err = switchPostInstall(u.Candidate, &switchPostInstallOpts{
+						BootDir: bootDir,
+						Rootfs:  config.GetGeneral().GetRootfs(),
+						KType:   u.Type,
+						BuilderSelector: newInitrdBuilderSelector(config,
+							initrdBuilder, dracutOpts, dryRun),
+						BootloaderOpts: newBootloaderOpts(config, dryRun),
+						Signer:         signer,
+						Types:          types,
+						Geninitrd:      geninitrd,
+						SetLinks:       setLinks,
+						Bootloader:     bootloaderName,
+						DryRun:         dryRun,
+					})
+					if err != nil {
+						fmt.Println(err.Error())
+						os.Exit(1)
+					}
+				}
+
+				if purge && u.IsBranchChange() {
+					branch := kernel.KernelBranch(u.Installed, u.Type)
+					err = removeKernel(config, u.Suffix, branch, u.Type,
+						bootDir, types, dryRun)
+					if err != nil && isKernelInUse(err) {
+						// POST: the new kernel is already installed.
+						fmt.Println(fmt.Sprintf(
+							"WARN: Skipping purge of kernel %s@%s: %s",
+							u.Suffix, branch, err.Error()))
+					} else if err != nil {
+						fmt.Println(fmt.Sprintf(
+							"Error on purge kernel %s@%s: %s",
+							u.Suffix, branch, err.Error()))
+						os.Exit(1)
+					}
+				}
+			}
+		},
+	}
+
+	flags := c.Flags()
+	flags.Bool("lts", false, "Upgrade the kernels to the newest LTS branch.")
+	flags.Bool("purge", false, "Purge the kernels of the old branch when the branch is changed.")
+	flags.Bool("geninitrd", false, "Generate the initrd image of the upgraded kernels.")
+	flags.Bool("set-links", false, "Set bzImage and Initrd links to the upgraded kernel.")
+	flags.Bool("grub", false, "Update grub.cfg after the upgrade.")
+	flags.String("bootloader", "",
+		"Update the configuration of the selected bootloader (grub, bls, extlinux).")
+	flags.String("initrd-builder", "",
+		"Override the backend used to build the initrd image (dracut, mkinitcpio, booster).")
+	flags.String("dracut-opts", "",
+		`Override the dracut options of the kernel profiles and of the configuration
+used on the initrd image generation. Set the MACARONICTL_DRACUT_ARGS env in alternative.`)
+	flags.String("bootdir", "",
+		"Directory where analyze kernel files. Default is the kernel.bootdir option of the config.")
+	flags.String("kernel-profiles-dir", "",
+		"Specify the directory where read the kernel types profiles supported.")
+	flags.Bool("dry-run", false, "Dry run upgrade and show candidates.")
+
+	return c
+}
@@ -0,0 +1,176 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmdkernel
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/macaroni-os/macaronictl/pkg/kernel"
+	"github.com/macaroni-os/macaronictl/pkg/secureboot"
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+
+	tablewriter "github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+func NewVerifySignatureCommand(config *specs.MacaroniCtlConfig) *cobra.Command {
+	c := &cobra.Command{
+		Use:     "verify-signature [OPTIONS]",
+		Aliases: []string{"vs"},
+		Short:   "Verify the Secure Boot signature of the kernel images.",
+		Long: `Verify the Authenticode signatures of the kernel images and of
+the UKIs available in the boot directory.
+
+The signatures are checked with the certificates of the --cert option
+or with the kernel.secureboot.cert option of the config if the file exists.
+Without certificates it's only checked that the signatures are valid.
+
+$> macaronictl kernel verify-signature
+
+$> macaronictl kernel verify-signature --version 6.1.12 --cert db.crt
+
+$> macaronictl kernel verify-signature --file /boot/EFI/Linux/macaroni-kernel-6.1.12.efi --json
+
+The command exits with 1 if there are images not signed or with
+signatures not valid or not trusted.
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+
+			bootDir := getBootDir(cmd, config)
+			espDir, _ := cmd.Flags().GetString("esp")
+			version, _ := cmd.Flags().GetString("version")
+			ktype, _ := cmd.Flags().GetString("ktype")
+			files, _ := cmd.Flags().GetStringSlice("file")
+			certFiles, _ := cmd.Flags().GetStringSlice("cert")
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+			kernelProfilesDir, _ := cmd.Flags().GetString("kernel-profiles-dir")
+
+			if len(certFiles) == 0 {
+				certFile := config.GetKernel().GetSecureBoot().Cert
+				if utils.Exists(certFile) {
+					certFiles = append(certFiles, certFile)
+				}
+			}
+
+			certs := []*x509.Certificate{}
+			for _, f := range certFiles {
+				cert, err := secureboot.LoadCertificate(f)
+				if err != nil {
+					fmt.Println(err.Error())
+					os.Exit(1)
+				}
+				certs = append(certs, cert)
+			}
+
+			if len(files) == 0 {
+				types := loadKernelTypes(config, kernelProfilesDir)
+
+				bootFiles, err := kernel.ReadBootDir(bootDir, types)
+				if err != nil {
+					fmt.Println("Error on read boot directory: " + err.Error())
+					os.Exit(1)
+				}
+
+				for _, f := range bootFiles.Files {
+					if f.Kernel == nil {
+						continue
+					}
+					if version != "" && f.Kernel.GetVersion() != version {
+						continue
+					}
+					if ktype != "" && f.Kernel.GetType() != ktype {
+						continue
+					}
+
+					files = append(files,
+						filepath.Join(bootFiles.Dir, f.Kernel.GetFilename()))
+				}
+
+				if version == "" {
+					if espDir == "" {
+						espDir = bootFiles.Dir
+					} else {
+						espDir = utils.RootfsPath(config.GetGeneral().GetRootfs(), espDir)
+					}
+
+					ukis, _ := filepath.Glob(filepath.Join(espDir, "EFI", "Linux", "*.efi"))
+					files = append(files, ukis...)
+				}
+			}
+
+			statuses := []*secureboot.SignatureStatus{}
+			trusted := true
+			for _, f := range files {
+				s := secureboot.VerifyFile(f, certs)
+				if !s.Trusted {
+					trusted = false
+				}
+				statuses = append(statuses, s)
+			}
+
+			if jsonOutput {
+				data, err := json.Marshal(statuses)
+				if err != nil {
+					fmt.Println(fmt.Errorf("Error on convert data to json: %s", err.Error()))
+					os.Exit(1)
+				}
+				fmt.Println(string(data))
+			} else if len(statuses) == 0 {
+				fmt.Println("No images found.")
+			} else {
+				table := tablewriter.NewWriter(os.Stdout)
+				table.Header(
+					"File",
+					"Signed",
+					"Valid",
+					"Trusted",
+					"Signer",
+				)
+
+				for _, s := range statuses {
+					signer := strings.Join(s.Subjects, "\n")
+					if s.Error != "" {
+						signer = s.Error
+					}
+
+					table.Append([]string{
+						s.File,
+						fmt.Sprintf("%v", s.Signed),
+						fmt.Sprintf("%v", s.Valid),
+						fmt.Sprintf("%v", s.Trusted),
+						signer,
+					})
+				}
+
+				table.Render()
+			}
+
+			if !trusted {
+				os.Exit(1)
+			}
+		},
+	}
+
+	flags := c.Flags()
+	flags.Bool("json", false, "JSON output")
+	flags.String("version", "", "Specify the kernel version of the image to verify.")
+	flags.String("ktype", "", "Specify the kernel type of the image to verify.")
+	flags.StringSlice("file", []string{}, "Path of a PE image or UKI to verify.")
+	flags.StringSlice("cert", []string{},
+		"Path of the trusted certificates. Default is the kernel.secureboot.cert option of the config.")
+	flags.String("esp", "", "Directory of the EFI System Partition. Default is the boot dir.")
+	flags.String("bootdir", "",
+		"Directory where analyze kernel files. Default is the kernel.bootdir option of the config.")
+	flags.String("kernel-profiles-dir", "",
+		"Specify the directory where read the kernel types profiles supported.")
+
+	return c
+}
@@ -1,5 +1,5 @@
 /*
-Copyright © 2020-2023 Macaroni OS Linux
+Copyright © 2020-2026 Macaroni OS Linux
 See AUTHORS and LICENSE for the license details and contributors.
 */
 package cmd
@@ -54,9 +54,12 @@ func initCommand(rootCmd *cobra.Command, config *specs.MacaroniCtlConfig) {
 	pflags.StringP("config", "c", "", "Macaronictl configuration file")
 	pflags.BoolP("debug", "d", config.Viper.GetBool("general.debug"),
 		"Enable debug output.")
+	pflags.String("rootfs", config.Viper.GetString("general.rootfs"),
+		"Root directory of the system to manage. Used to manage chroots and images.")
 
 	config.Viper.BindPFlag("config", pflags.Lookup("config"))
 	config.Viper.BindPFlag("general.debug", pflags.Lookup("debug"))
+	config.Viper.BindPFlag("general.rootfs", pflags.Lookup("rootfs"))
 
 	rootCmd.AddCommand(
 		envUpdateCommand(config),
@@ -0,0 +1,246 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package bootloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/macaroni-os/macaronictl/pkg/kernel"
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+	"github.com/macaroni-os/macaronictl/pkg/logger"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+)
+
+// Boot Loader Specification Type #1 entries generator
+// used by systemd-boot.
+type BLSBootloader struct {
+	EntriesDir    string
+	LoaderConf    string
+	OsReleaseFile string
+	Cmdline       string
+	Rootfs        string
+	DryRun        bool
+}
+
+type BLSEntry struct {
+	Id      string
+	Title   string
+	Version string
+	Linux   string
+	Initrd  []string
+	Options string
+}
+
+func NewBLSBootloader(opts *BootloaderOpts) *BLSBootloader {
+	return &BLSBootloader{
+		EntriesDir:    opts.EntriesDir,
+		LoaderConf:    opts.LoaderConf,
+		OsReleaseFile: opts.OsReleaseFile,
+		Cmdline:       opts.Cmdline,
+		Rootfs:        opts.Rootfs,
+		DryRun:        opts.DryRun,
+	}
+}
+
+func (e *BLSEntry) String() string {
+	ans := fmt.Sprintf("title %s\n", e.Title)
+	if e.Version != "" {
+		ans += fmt.Sprintf("version %s\n", e.Version)
+	}
+	ans += fmt.Sprintf("linux %s\n", e.Linux)
+	for _, i := range e.Initrd {
+		ans += fmt.Sprintf("initrd %s\n", i)
+	}
+	if e.Options != "" {
+		ans += fmt.Sprintf("options %s\n", e.Options)
+	}
+
+	return ans
+}
+
+func (b *BLSBootloader) GetName() string { return "bls" }
+
+func (b *BLSBootloader) getEntriesDir(bootDir string) string {
+	if b.EntriesDir != "" {
+		return b.EntriesDir
+	}
+	return filepath.Join(bootDir, "loader", "entries")
+}
+
+func (b *BLSBootloader) getLoaderConf(bootDir string) string {
+	if b.LoaderConf != "" {
+		return b.LoaderConf
+	}
+	return filepath.Join(bootDir, "loader", "loader.conf")
+}
+
+// Return the command line of the kernel. The command line of the
+// options has the precedence over the resolved command line.
+func (b *BLSBootloader) getCmdline(kf *kernelspecs.KernelFiles) string {
+	if b.Cmdline != "" {
+		return b.Cmdline
+	}
+	return kernel.NewCmdline(b.Rootfs).ResolveKernelFiles(kf)
+}
+
+func (b *BLSBootloader) GetEntries(bootFiles *kernelspecs.BootFiles) []*BLSEntry {
+	ans := []*BLSEntry{}
+
+	osName, osId := osReleaseInfo(b.OsReleaseFile)
+
+	for _, kf := range bootFiles.Files {
+		if kf.Kernel == nil {
+			continue
+		}
+
+		// The filenames of the templates could contain directories.
+		entry := &BLSEntry{
+			Id: osId + "-" + strings.ReplaceAll(kf.Kernel.GetFilename(), "/", "-"),
+			Title: fmt.Sprintf("%s (%s %s)", osName,
+				kf.Type.GetName(), kf.Kernel.GetVersion()),
+			Version: kf.Kernel.GetRelease(),
+			Linux:   "/" + kf.Kernel.GetFilename(),
+			Initrd:  []string{},
+			Options: b.getCmdline(kf),
+		}
+
+		// The early microcode must be loaded before the initrd image.
+		if kf.GetMicrocodeFile() != "" {
+			entry.Initrd = append(entry.Initrd, "/"+kf.GetMicrocodeFile())
+		}
+		if kf.Initrd != nil {
+			entry.Initrd = append(entry.Initrd, "/"+kf.Initrd.GetFilename())
+		}
+
+		ans = append(ans, entry)
+	}
+
+	return ans
+}
+
+func (b *BLSBootloader) Update(bootFiles *kernelspecs.BootFiles) error {
+	log := logger.GetDefaultLogger()
+
+	entriesDir := b.getEntriesDir(bootFiles.Dir)
+	entries := b.GetEntries(bootFiles)
+	entriesMap := make(map[string]*BLSEntry, 0)
+
+	if !b.DryRun && !utils.Exists(entriesDir) {
+		err := os.MkdirAll(entriesDir, 0755)
+		if err != nil {
+			return fmt.Errorf("Error on create directory %s: %s",
+				entriesDir, err.Error())
+		}
+	}
+
+	for _, e := range entries {
+		entryFile := filepath.Join(entriesDir, e.Id+".conf")
+		entriesMap[e.Id+".conf"] = e
+
+		if b.DryRun {
+			fmt.Println(fmt.Sprintf("[dry-run mode] writing entry %s:\n%s",
+				entryFile, e.String()))
+			continue
+		}
+
+		log.DebugC("Writing BLS entry", entryFile)
+		err := os.WriteFile(entryFile, []byte(e.String()), 0644)
+		if err != nil {
+			return fmt.Errorf("Error on write entry %s: %s",
+				entryFile, err.Error())
+		}
+	}
+
+	// Remove the entries of the kernels no more available.
+	if utils.Exists(entriesDir) {
+		files, err := os.ReadDir(entriesDir)
+		if err != nil {
+			return err
+		}
+
+		_, osId := osReleaseInfo(b.OsReleaseFile)
+		prefix := osId + "-"
+
+		for _, f := range files {
+			if f.IsDir() || !strings.HasPrefix(f.Name(), prefix) ||
+				!strings.HasSuffix(f.Name(), ".conf") {
+				continue
+			}
+
+			if _, present := entriesMap[f.Name()]; present {
+				continue
+			}
+
+			if b.DryRun {
+				fmt.Println("[dry-run mode] removing stale entry " +
+					filepath.Join(entriesDir, f.Name()))
+				continue
+			}
+
+			fmt.Println(fmt.Sprintf("Removing stale entry %s...", f.Name()))
+			err = os.Remove(filepath.Join(entriesDir, f.Name()))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Set the default entry to the bzImage selection. The link
+	// is read again because could be changed after the boot dir analysis.
+	bzImage := bootFiles.BzImageLink
+	link, err := os.Readlink(filepath.Join(bootFiles.Dir, "bzImage"))
+	if err == nil {
+		bzImage = link
+	}
+
+	if bzImage != "" {
+		for _, e := range entries {
+			if e.Linux == "/"+bzImage {
+				return b.setDefault(bootFiles.Dir, e.Id+".conf")
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b *BLSBootloader) setDefault(bootDir, entry string) error {
+	loaderConf := b.getLoaderConf(bootDir)
+	lines := []string{}
+	replaced := false
+
+	if b.DryRun {
+		fmt.Println(fmt.Sprintf("[dry-run mode] set default entry %s on %s",
+			entry, loaderConf))
+		return nil
+	}
+
+	content, err := os.ReadFile(loaderConf)
+	if err == nil {
+		for _, line := range strings.Split(strings.TrimRight(string(content), "\n"), "\n") {
+			if strings.HasPrefix(strings.TrimSpace(line), "default ") {
+				line = "default " + entry
+				replaced = true
+			}
+			lines = append(lines, line)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if !replaced {
+		lines = append(lines, "default "+entry)
+	}
+
+	err = os.MkdirAll(filepath.Dir(loaderConf), 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(loaderConf, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
@@ -0,0 +1,82 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package bootloader_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/macaroni-os/macaronictl/pkg/bootloader"
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BLS Test", func() {
+
+	Context("Entries generation", func() {
+
+		ktype := &kernelspecs.KernelType{
+			Name:     "Macaroni",
+			Suffix:   "macaroni",
+			Type:     "vanilla",
+			WithArch: true,
+		}
+
+		It("Write entries and default", func() {
+			bootDir, err := os.MkdirTemp("", "macaronictl-bls")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(bootDir)
+
+			osRelease := filepath.Join(bootDir, "os-release")
+			err = os.WriteFile(osRelease,
+				[]byte("NAME=\"Macaroni OS\"\nID=macaroni\n"), 0644)
+			Expect(err).Should(BeNil())
+
+			entriesDir := filepath.Join(bootDir, "loader", "entries")
+			Expect(os.MkdirAll(entriesDir, 0755)).Should(BeNil())
+			stale := filepath.Join(entriesDir,
+				"macaroni-kernel-vanilla-x86_64-5.10.1-macaroni.conf")
+			Expect(os.WriteFile(stale, []byte("title old\n"), 0644)).Should(BeNil())
+			other := filepath.Join(entriesDir, "other-os.conf")
+			Expect(os.WriteFile(other, []byte("title other\n"), 0644)).Should(BeNil())
+
+			kfile := "kernel-vanilla-x86_64-6.1.12-macaroni"
+			Expect(os.Symlink(kfile, filepath.Join(bootDir, "bzImage"))).Should(BeNil())
+
+			bootFiles := kernelspecs.NewBootFiles(bootDir)
+			kimage, err := kernelspecs.NewKernelImageFromFile(ktype, kfile)
+			Expect(err).Should(BeNil())
+			Expect(bootFiles.AddKernelImage(kimage, ktype)).Should(BeNil())
+
+			opts := NewBootloaderOpts()
+			opts.OsReleaseFile = osRelease
+			opts.Cmdline = "root=/dev/sda2 quiet"
+			bl, err := NewBootloader("bls", opts)
+			Expect(err).Should(BeNil())
+			Expect(bl.Update(bootFiles)).Should(BeNil())
+
+			data, err := os.ReadFile(filepath.Join(entriesDir,
+				"macaroni-"+kfile+".conf"))
+			Expect(err).Should(BeNil())
+			Expect(string(data)).To(Equal(`title Macaroni OS (Macaroni 6.1.12)
+version 6.1.12-macaroni
+linux /kernel-vanilla-x86_64-6.1.12-macaroni
+options root=/dev/sda2 quiet
+`))
+
+			_, err = os.Stat(stale)
+			Expect(os.IsNotExist(err)).To(Equal(true))
+			_, err = os.Stat(other)
+			Expect(err).Should(BeNil())
+
+			data, err = os.ReadFile(filepath.Join(bootDir, "loader", "loader.conf"))
+			Expect(err).Should(BeNil())
+			Expect(string(data)).To(Equal("default macaroni-" + kfile + ".conf\n"))
+		})
+	})
+
+})
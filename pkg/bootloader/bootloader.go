@@ -0,0 +1,91 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package bootloader
+
+import (
+	"fmt"
+
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+)
+
+type Bootloader interface {
+	GetName() string
+	Update(bootFiles *kernelspecs.BootFiles) error
+}
+
+type BootloaderOpts struct {
+	DryRun bool
+
+	// Root directory of the system to manage. The commands of the
+	// bootloaders are executed inside the rootfs with chroot.
+	Rootfs string
+
+	// Path of the grub.cfg file. If empty it's used <bootdir>/grub/grub.cfg
+	GrubCfgFile string
+
+	// Directory of the BLS entries. If empty it's used <bootdir>/loader/entries
+	EntriesDir string
+	// Path of the systemd-boot loader.conf. If empty it's used <bootdir>/loader/loader.conf
+	LoaderConf string
+	// Path of the os-release file used to generate the entries title.
+	OsReleaseFile string
+	// Path of the extlinux.conf file. If empty it's used <bootdir>/extlinux/extlinux.conf
+	ExtlinuxConf string
+	// Create a symlink of the dtbs directory instead of copy it.
+	DtbsLink bool
+
+	// Path of the grub defaults file. If empty it's used /etc/default/grub
+	GrubDefaultFile string
+
+	// Kernel command line to use. If empty it's used the command line
+	// resolved from /etc/kernel/cmdline and the drop-ins or the command
+	// line of the running kernel.
+	Cmdline string
+}
+
+func NewBootloaderOpts() *BootloaderOpts {
+	return &BootloaderOpts{
+		OsReleaseFile: "/etc/os-release",
+	}
+}
+
+func NewBootloader(name string, opts *BootloaderOpts) (Bootloader, error) {
+	if opts == nil {
+		opts = NewBootloaderOpts()
+	}
+
+	switch name {
+	case "grub":
+		return NewGrubBootloader(opts), nil
+	case "bls":
+		return NewBLSBootloader(opts), nil
+	case "extlinux":
+		return NewExtlinuxBootloader(opts), nil
+	default:
+		return nil, fmt.Errorf("Unsupported bootloader %s", name)
+	}
+}
+
+// Return the name and the id of the OS from the os-release file.
+func osReleaseInfo(file string) (string, string) {
+	osName := "Linux"
+	osId := "linux"
+	if file != "" {
+		fields, err := utils.ParseOsReleaseFile(file)
+		if err == nil {
+			if v, ok := fields["PRETTY_NAME"]; ok && v != "" {
+				osName = v
+			} else if v, ok := fields["NAME"]; ok && v != "" {
+				osName = v
+			}
+			if v, ok := fields["ID"]; ok && v != "" {
+				osId = v
+			}
+		}
+	}
+
+	return osName, osId
+}
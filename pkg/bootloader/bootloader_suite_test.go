@@ -0,0 +1,23 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package bootloader_test
+
+import (
+	"testing"
+
+	"github.com/macaroni-os/macaronictl/pkg/logger"
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestBootloader(t *testing.T) {
+	log := logger.NewMacaroniCtlLogger(specs.NewMacaroniCtlConfig(nil))
+	log.SetAsDefault()
+
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Bootloader Suite")
+}
@@ -0,0 +1,315 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package bootloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/macaroni-os/macaronictl/pkg/kernel"
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+	"github.com/macaroni-os/macaronictl/pkg/logger"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+)
+
+// extlinux.conf generator used by U-Boot on ARM boards.
+type ExtlinuxBootloader struct {
+	ExtlinuxConf  string
+	OsReleaseFile string
+	Cmdline       string
+	Rootfs        string
+	DtbsLink      bool
+	DryRun        bool
+}
+
+type ExtlinuxEntry struct {
+	Label     string
+	MenuLabel string
+	Linux     string
+	Initrd    []string
+	FdtDir    string
+	Append    string
+}
+
+func NewExtlinuxBootloader(opts *BootloaderOpts) *ExtlinuxBootloader {
+	return &ExtlinuxBootloader{
+		ExtlinuxConf:  opts.ExtlinuxConf,
+		OsReleaseFile: opts.OsReleaseFile,
+		Cmdline:       opts.Cmdline,
+		Rootfs:        opts.Rootfs,
+		DtbsLink:      opts.DtbsLink,
+		DryRun:        opts.DryRun,
+	}
+}
+
+func (e *ExtlinuxEntry) String() string {
+	ans := fmt.Sprintf("LABEL %s\n", e.Label)
+	ans += fmt.Sprintf("  MENU LABEL %s\n", e.MenuLabel)
+	ans += fmt.Sprintf("  LINUX %s\n", e.Linux)
+	if len(e.Initrd) > 0 {
+		ans += fmt.Sprintf("  INITRD %s\n", strings.Join(e.Initrd, ","))
+	}
+	if e.FdtDir != "" {
+		ans += fmt.Sprintf("  FDTDIR %s\n", e.FdtDir)
+	}
+	if e.Append != "" {
+		ans += fmt.Sprintf("  APPEND %s\n", e.Append)
+	}
+
+	return ans
+}
+
+func (x *ExtlinuxBootloader) GetName() string { return "extlinux" }
+
+func (x *ExtlinuxBootloader) getExtlinuxConf(bootDir string) string {
+	if x.ExtlinuxConf != "" {
+		return x.ExtlinuxConf
+	}
+	return filepath.Join(bootDir, "extlinux", "extlinux.conf")
+}
+
+// Return the command line of the kernel. The command line of the
+// options has the precedence over the resolved command line.
+func (x *ExtlinuxBootloader) getCmdline(kf *kernelspecs.KernelFiles) string {
+	if x.Cmdline != "" {
+		return x.Cmdline
+	}
+	return kernel.NewCmdline(x.Rootfs).ResolveKernelFiles(kf)
+}
+
+func (x *ExtlinuxBootloader) GetEntries(bootFiles *kernelspecs.BootFiles) []*ExtlinuxEntry {
+	ans := []*ExtlinuxEntry{}
+
+	osName, _ := osReleaseInfo(x.OsReleaseFile)
+
+	for _, kf := range bootFiles.Files {
+		if kf.Kernel == nil {
+			continue
+		}
+
+		entry := &ExtlinuxEntry{
+			Label: kf.Kernel.GetFilename(),
+			MenuLabel: fmt.Sprintf("%s (%s %s)", osName,
+				kf.Type.GetName(), kf.Kernel.GetVersion()),
+			Linux:  "/" + kf.Kernel.GetFilename(),
+			Initrd: []string{},
+			Append: x.getCmdline(kf),
+		}
+
+		// The early microcode must be loaded before the initrd image.
+		if kf.GetMicrocodeFile() != "" {
+			entry.Initrd = append(entry.Initrd, "/"+kf.GetMicrocodeFile())
+		}
+		if kf.Initrd != nil {
+			entry.Initrd = append(entry.Initrd, "/"+kf.Initrd.GetFilename())
+		}
+
+		if kf.Type.GetDtbsDir() != "" {
+			entry.FdtDir = "/dtbs/" + kf.Kernel.GetRelease()
+		}
+
+		ans = append(ans, entry)
+	}
+
+	return ans
+}
+
+// File of the dtbs directory with the releases synced by macaronictl.
+// Only these directories are refreshed and removed by macaronictl.
+const DtbsManifestFile = ".macaronictl-dtbs"
+
+func readDtbsManifest(dtbsDir string) map[string]bool {
+	ans := make(map[string]bool, 0)
+
+	content, err := os.ReadFile(filepath.Join(dtbsDir, DtbsManifestFile))
+	if err != nil {
+		return ans
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			ans[line] = true
+		}
+	}
+
+	return ans
+}
+
+func writeDtbsManifest(dtbsDir string, releases map[string]bool) error {
+	file := filepath.Join(dtbsDir, DtbsManifestFile)
+
+	if len(releases) == 0 {
+		err := os.Remove(file)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	lines := []string{}
+	for r := range releases {
+		lines = append(lines, r)
+	}
+	sort.Strings(lines)
+
+	return os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+// Copy or link the device-tree blobs of the kernels under <bootdir>/dtbs
+// and remove the directories synced by macaronictl of the kernels no more
+// available. The directories not created by macaronictl are never touched.
+func (x *ExtlinuxBootloader) syncDtbs(bootFiles *kernelspecs.BootFiles) error {
+	log := logger.GetDefaultLogger()
+	dtbsDir := filepath.Join(bootFiles.Dir, "dtbs")
+	managed := readDtbsManifest(dtbsDir)
+	synced := make(map[string]bool, 0)
+	// The releases with an installed kernel. Their dtbs are never stale
+	// even when the source directory is missing.
+	kept := make(map[string]bool, 0)
+
+	for _, kf := range bootFiles.Files {
+		if kf.Kernel == nil {
+			continue
+		}
+
+		release := kf.Kernel.GetRelease()
+		kept[release] = true
+
+		if kf.Type.GetDtbsDir() == "" {
+			continue
+		}
+
+		// The path of the link is the path visible inside the rootfs.
+		link := filepath.Join(kf.Type.GetDtbsDir(), release)
+		src := utils.RootfsPath(x.Rootfs, link)
+		dst := filepath.Join(dtbsDir, release)
+
+		if filepath.Clean(src) == filepath.Clean(dst) {
+			// POST: the dtbs are already installed in the boot directory.
+			continue
+		}
+
+		if !utils.Exists(src) {
+			fmt.Println(fmt.Sprintf(
+				"WARN: No dtbs directory %s found for kernel %s.",
+				src, kf.Kernel.GetFilename()))
+			continue
+		}
+
+		if _, err := os.Lstat(dst); err == nil && !managed[release] {
+			fmt.Println(fmt.Sprintf(
+				"WARN: The dtbs directory %s is not managed by macaronictl. Skipped.", dst))
+			continue
+		}
+
+		synced[release] = true
+
+		if x.DryRun {
+			fmt.Println(fmt.Sprintf("[dry-run mode] sync dtbs %s -> %s", src, dst))
+			continue
+		}
+
+		// Always refresh the dtbs because the kernel could be rebuilt.
+		err := os.RemoveAll(dst)
+		if err != nil {
+			return err
+		}
+
+		err = os.MkdirAll(dtbsDir, 0755)
+		if err != nil {
+			return err
+		}
+
+		if x.DtbsLink {
+			log.DebugC("Creating link", dst, "to", link)
+			err = os.Symlink(link, dst)
+		} else {
+			log.DebugC("Copying dtbs", src, "to", dst)
+			err = utils.CopyDir(src, dst)
+		}
+		if err != nil {
+			return fmt.Errorf("Error on sync dtbs of kernel %s: %s",
+				kf.Kernel.GetFilename(), err.Error())
+		}
+	}
+
+	for release := range managed {
+		if kept[release] {
+			synced[release] = true
+			continue
+		}
+
+		dir := filepath.Join(dtbsDir, release)
+
+		if x.DryRun {
+			fmt.Println("[dry-run mode] removing stale dtbs " + dir)
+			continue
+		}
+
+		fmt.Println(fmt.Sprintf("Removing stale dtbs %s...", release))
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	if x.DryRun || !utils.Exists(dtbsDir) {
+		return nil
+	}
+
+	return writeDtbsManifest(dtbsDir, synced)
+}
+
+func (x *ExtlinuxBootloader) Update(bootFiles *kernelspecs.BootFiles) error {
+	extlinuxConf := x.getExtlinuxConf(bootFiles.Dir)
+	entries := x.GetEntries(bootFiles)
+	osName, _ := osReleaseInfo(x.OsReleaseFile)
+
+	err := x.syncDtbs(bootFiles)
+	if err != nil {
+		return err
+	}
+
+	// The default entry is the bzImage selection. The link
+	// is read again because could be changed after the boot dir analysis.
+	bzImage := bootFiles.BzImageLink
+	link, err := os.Readlink(filepath.Join(bootFiles.Dir, "bzImage"))
+	if err == nil {
+		bzImage = link
+	}
+
+	content := "# Generated by macaronictl. Don't edit this file manually.\n"
+	content += fmt.Sprintf("MENU TITLE %s\n", osName)
+	content += "TIMEOUT 30\n"
+	for _, e := range entries {
+		if e.Linux == "/"+bzImage {
+			content += fmt.Sprintf("DEFAULT %s\n", e.Label)
+			break
+		}
+	}
+
+	for _, e := range entries {
+		content += "\n" + e.String()
+	}
+
+	if x.DryRun {
+		fmt.Println(fmt.Sprintf("[dry-run mode] writing %s:\n%s",
+			extlinuxConf, content))
+		return nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(extlinuxConf), 0755)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(fmt.Sprintf("Creating extlinux config file %s...", extlinuxConf))
+
+	return os.WriteFile(extlinuxConf, []byte(content), 0644)
+}
@@ -0,0 +1,178 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package bootloader_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/macaroni-os/macaronictl/pkg/bootloader"
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Extlinux Test", func() {
+
+	Context("Config generation", func() {
+
+		It("Write extlinux.conf and dtbs", func() {
+			bootDir, err := os.MkdirTemp("", "macaronictl-extlinux")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(bootDir)
+
+			dtbsDir := filepath.Join(bootDir, "usr-dtbs")
+			Expect(os.MkdirAll(filepath.Join(dtbsDir, "6.1.12-macaroni", "rockchip"),
+				0755)).Should(BeNil())
+			Expect(os.WriteFile(
+				filepath.Join(dtbsDir, "6.1.12-macaroni", "rockchip", "board.dtb"),
+				[]byte("dtb"), 0644)).Should(BeNil())
+
+			ktype := &kernelspecs.KernelType{
+				Name:     "Macaroni",
+				Suffix:   "macaroni",
+				Type:     "vanilla",
+				WithArch: true,
+				DtbsDir:  dtbsDir,
+			}
+
+			kfile := "kernel-vanilla-arm64-6.1.12-macaroni"
+			Expect(os.Symlink(kfile, filepath.Join(bootDir, "bzImage"))).Should(BeNil())
+
+			bootFiles := kernelspecs.NewBootFiles(bootDir)
+			kimage, err := kernelspecs.NewKernelImageFromFile(ktype, kfile)
+			Expect(err).Should(BeNil())
+			Expect(bootFiles.AddKernelImage(kimage, ktype)).Should(BeNil())
+			bootFiles.Files[0].Microcode = &kernelspecs.MicrocodeImage{
+				Filename: "amd-uc.img",
+				Vendor:   "AuthenticAMD",
+			}
+
+			opts := NewBootloaderOpts()
+			opts.OsReleaseFile = ""
+			opts.Cmdline = "root=/dev/mmcblk0p2"
+			bl, err := NewBootloader("extlinux", opts)
+			Expect(err).Should(BeNil())
+			Expect(bl.Update(bootFiles)).Should(BeNil())
+
+			data, err := os.ReadFile(filepath.Join(bootDir, "extlinux", "extlinux.conf"))
+			Expect(err).Should(BeNil())
+			Expect(string(data)).To(Equal(`# Generated by macaronictl. Don't edit this file manually.
+MENU TITLE Linux
+TIMEOUT 30
+DEFAULT kernel-vanilla-arm64-6.1.12-macaroni
+
+LABEL kernel-vanilla-arm64-6.1.12-macaroni
+  MENU LABEL Linux (Macaroni 6.1.12)
+  LINUX /kernel-vanilla-arm64-6.1.12-macaroni
+  INITRD /amd-uc.img
+  FDTDIR /dtbs/6.1.12-macaroni
+  APPEND root=/dev/mmcblk0p2
+`))
+
+			_, err = os.Stat(filepath.Join(bootDir, "dtbs", "6.1.12-macaroni",
+				"rockchip", "board.dtb"))
+			Expect(err).Should(BeNil())
+		})
+
+		It("Keep the dtbs not managed by macaronictl", func() {
+			bootDir, err := os.MkdirTemp("", "macaronictl-extlinux")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(bootDir)
+
+			dtbsDir := filepath.Join(bootDir, "dtbs")
+			for _, release := range []string{"6.1.12-macaroni", "5.10.1-vendor"} {
+				Expect(os.MkdirAll(filepath.Join(dtbsDir, release), 0755)).Should(BeNil())
+				Expect(os.WriteFile(filepath.Join(dtbsDir, release, "board.dtb"),
+					[]byte("dtb"), 0644)).Should(BeNil())
+			}
+			// Stale directory synced by macaronictl.
+			Expect(os.MkdirAll(filepath.Join(dtbsDir, "6.0.1-macaroni"), 0755)).Should(BeNil())
+			Expect(os.WriteFile(filepath.Join(dtbsDir, DtbsManifestFile),
+				[]byte("6.0.1-macaroni\n"), 0644)).Should(BeNil())
+
+			// The dtbs of the profile are already in the boot directory.
+			ktype := &kernelspecs.KernelType{
+				Name:     "Macaroni",
+				Suffix:   "macaroni",
+				Type:     "vanilla",
+				WithArch: true,
+				DtbsDir:  dtbsDir,
+			}
+
+			bootFiles := kernelspecs.NewBootFiles(bootDir)
+			kimage, err := kernelspecs.NewKernelImageFromFile(ktype,
+				"kernel-vanilla-arm64-6.1.12-macaroni")
+			Expect(err).Should(BeNil())
+			Expect(bootFiles.AddKernelImage(kimage, ktype)).Should(BeNil())
+
+			opts := NewBootloaderOpts()
+			opts.OsReleaseFile = ""
+			opts.Cmdline = "root=/dev/mmcblk0p2"
+			bl, err := NewBootloader("extlinux", opts)
+			Expect(err).Should(BeNil())
+			Expect(bl.Update(bootFiles)).Should(BeNil())
+
+			_, err = os.Stat(filepath.Join(dtbsDir, "6.1.12-macaroni", "board.dtb"))
+			Expect(err).Should(BeNil())
+			_, err = os.Stat(filepath.Join(dtbsDir, "5.10.1-vendor", "board.dtb"))
+			Expect(err).Should(BeNil())
+			Expect(utils.Exists(filepath.Join(dtbsDir, "6.0.1-macaroni"))).To(BeFalse())
+			Expect(utils.Exists(filepath.Join(dtbsDir, DtbsManifestFile))).To(BeFalse())
+		})
+
+		It("Keep the dtbs of the installed kernels without source", func() {
+			bootDir, err := os.MkdirTemp("", "macaronictl-extlinux")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(bootDir)
+
+			dtbsDir := filepath.Join(bootDir, "dtbs")
+			for _, release := range []string{"6.1.12-macaroni", "6.0.1-macaroni"} {
+				Expect(os.MkdirAll(filepath.Join(dtbsDir, release), 0755)).Should(BeNil())
+				Expect(os.WriteFile(filepath.Join(dtbsDir, release, "board.dtb"),
+					[]byte("dtb"), 0644)).Should(BeNil())
+			}
+			Expect(os.WriteFile(filepath.Join(dtbsDir, DtbsManifestFile),
+				[]byte("6.0.1-macaroni\n6.1.12-macaroni\n"), 0644)).Should(BeNil())
+
+			// The source dtbs directory of the kernel is removed.
+			ktype := &kernelspecs.KernelType{
+				Name:     "Macaroni",
+				Suffix:   "macaroni",
+				Type:     "vanilla",
+				WithArch: true,
+				DtbsDir:  filepath.Join(bootDir, "usr-dtbs"),
+			}
+
+			bootFiles := kernelspecs.NewBootFiles(bootDir)
+			kimage, err := kernelspecs.NewKernelImageFromFile(ktype,
+				"kernel-vanilla-arm64-6.1.12-macaroni")
+			Expect(err).Should(BeNil())
+			Expect(bootFiles.AddKernelImage(kimage, ktype)).Should(BeNil())
+
+			opts := NewBootloaderOpts()
+			opts.OsReleaseFile = ""
+			opts.Cmdline = "root=/dev/mmcblk0p2"
+			bl, err := NewBootloader("extlinux", opts)
+			Expect(err).Should(BeNil())
+			Expect(bl.Update(bootFiles)).Should(BeNil())
+
+			_, err = os.Stat(filepath.Join(dtbsDir, "6.1.12-macaroni", "board.dtb"))
+			Expect(err).Should(BeNil())
+			Expect(utils.Exists(filepath.Join(dtbsDir, "6.0.1-macaroni"))).To(BeFalse())
+
+			data, err := os.ReadFile(filepath.Join(dtbsDir, DtbsManifestFile))
+			Expect(err).Should(BeNil())
+			Expect(string(data)).To(Equal("6.1.12-macaroni\n"))
+
+			data, err = os.ReadFile(filepath.Join(bootDir, "extlinux", "extlinux.conf"))
+			Expect(err).Should(BeNil())
+			Expect(string(data)).To(ContainSubstring("FDTDIR /dtbs/6.1.12-macaroni\n"))
+		})
+	})
+
+})
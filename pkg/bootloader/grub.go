@@ -0,0 +1,148 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package bootloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/macaroni-os/macaronictl/pkg/kernel"
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+)
+
+type GrubBootloader struct {
+	GrubCfgFile     string
+	GrubDefaultFile string
+	Cmdline         string
+	Rootfs          string
+	DryRun          bool
+}
+
+func NewGrubBootloader(opts *BootloaderOpts) *GrubBootloader {
+	return &GrubBootloader{
+		GrubCfgFile:     opts.GrubCfgFile,
+		GrubDefaultFile: opts.GrubDefaultFile,
+		Cmdline:         opts.Cmdline,
+		Rootfs:          opts.Rootfs,
+		DryRun:          opts.DryRun,
+	}
+}
+
+func (g *GrubBootloader) GetName() string { return "grub" }
+
+func (g *GrubBootloader) Update(bootFiles *kernelspecs.BootFiles) error {
+	grubCfgFile := g.GrubCfgFile
+	if grubCfgFile == "" {
+		grubCfgFile = filepath.Join(bootFiles.Dir, "grub/grub.cfg")
+	}
+
+	err := g.updateDefaultCmdline()
+	if err != nil {
+		return err
+	}
+
+	err = kernel.GrubMkconfig(grubCfgFile, g.Rootfs, g.DryRun)
+	if err != nil || g.DryRun || !GrubDefaultIsSaved(g.getDefaultFile()) {
+		return err
+	}
+
+	// Select the kernel of the bzImage link as saved entry. The link
+	// is read again because could be changed after the boot dir analysis.
+	bzImage := bootFiles.BzImageLink
+	link, err := os.Readlink(filepath.Join(bootFiles.Dir, "bzImage"))
+	if err == nil {
+		bzImage = link
+	}
+	if bzImage == "" {
+		return nil
+	}
+
+	entries, err := ReadGrubCfg(grubCfgFile)
+	if err != nil {
+		return err
+	}
+
+	entry := FindGrubEntry(entries, filepath.Base(bzImage))
+	if entry == nil {
+		fmt.Println(fmt.Sprintf("WARN: No grub entry found for kernel %s.", bzImage))
+		return nil
+	}
+
+	return SetGrubEnvEntry(GrubEnvFile(grubCfgFile), "saved_entry", entry.Path)
+}
+
+func (g *GrubBootloader) getDefaultFile() string {
+	if g.GrubDefaultFile != "" {
+		return g.GrubDefaultFile
+	}
+	return utils.RootfsPath(g.Rootfs, "/etc/default/grub")
+}
+
+// Return the path of the grubenv file in the directory of grub.cfg.
+func GrubEnvFile(grubCfgFile string) string {
+	return filepath.Join(filepath.Dir(grubCfgFile), "grubenv")
+}
+
+// Return true if the grub defaults file uses the saved entry
+// as default entry.
+func GrubDefaultIsSaved(file string) bool {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return false
+	}
+
+	ans := false
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "GRUB_DEFAULT=") {
+			value := strings.Trim(strings.TrimPrefix(line, "GRUB_DEFAULT="), `"'`)
+			ans = value == "saved"
+		}
+	}
+
+	return ans
+}
+
+// Set the variable of the grubenv file with the path of the entry.
+func SetGrubEnvEntry(file, variable, entry string) error {
+	env, err := ReadGrubEnv(file)
+	if err != nil {
+		return err
+	}
+
+	env.Set(variable, entry)
+
+	return env.Write(file)
+}
+
+// Write the base command line on the grub defaults file when it's
+// managed by /etc/kernel/cmdline. The drop-ins of the profiles and
+// the versions are not supported by grub-mkconfig.
+func (g *GrubBootloader) updateDefaultCmdline() error {
+	cmdline := g.Cmdline
+	if cmdline == "" {
+		if !utils.Exists(utils.RootfsPath(g.Rootfs, kernel.CmdlineFile)) {
+			return nil
+		}
+		cmdline = kernel.DefaultCmdline(g.Rootfs)
+	}
+
+	defaultFile := g.getDefaultFile()
+
+	if !utils.Exists(defaultFile) {
+		return nil
+	}
+
+	if g.DryRun {
+		fmt.Println(fmt.Sprintf("[dry-run mode] set GRUB_CMDLINE_LINUX=\"%s\" on %s",
+			cmdline, defaultFile))
+		return nil
+	}
+
+	return kernel.SetGrubDefaultCmdline(defaultFile, cmdline)
+}
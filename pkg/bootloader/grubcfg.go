@@ -0,0 +1,192 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package bootloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Menu entry of grub.cfg.
+type GrubMenuEntry struct {
+	Title string `json:"title" yaml:"title"`
+	Id    string `json:"id,omitempty" yaml:"id,omitempty"`
+	// Path of the entry used by saved_entry and next_entry. The
+	// entries inside a submenu are separated by >.
+	Path string `json:"path" yaml:"path"`
+	// Kernel image of the linux command.
+	Linux string `json:"linux,omitempty" yaml:"linux,omitempty"`
+}
+
+type grubBlock struct {
+	// menuentry, submenu or empty for the other blocks
+	Kind  string
+	Entry *GrubMenuEntry
+}
+
+// Split the line in words with the quoting rules of the grub scripts.
+func grubWords(line string) []string {
+	ans := []string{}
+	var word strings.Builder
+	inWord := false
+	quote := byte(0)
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				word.WriteByte(c)
+			}
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else if c == '\\' && i+1 < len(line) {
+				i++
+				word.WriteByte(line[i])
+			} else {
+				word.WriteByte(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inWord = true
+		case c == '\\' && i+1 < len(line):
+			i++
+			word.WriteByte(line[i])
+			inWord = true
+		case c == ' ' || c == '\t':
+			if inWord {
+				ans = append(ans, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+
+	if inWord {
+		ans = append(ans, word.String())
+	}
+
+	return ans
+}
+
+// Parse the menuentry and submenu line and return the entry.
+func newGrubMenuEntry(words []string) *GrubMenuEntry {
+	ans := &GrubMenuEntry{}
+	if len(words) > 1 {
+		ans.Title = words[1]
+	}
+
+	for i := 2; i+1 < len(words); i++ {
+		if words[i] == "--id" || words[i] == "$menuentry_id_option" {
+			ans.Id = words[i+1]
+		}
+	}
+
+	return ans
+}
+
+func (e *GrubMenuEntry) key() string {
+	if e.Id != "" {
+		return e.Id
+	}
+	return e.Title
+}
+
+// Parse the menu entries of grub.cfg.
+func ParseGrubCfg(content string) []*GrubMenuEntry {
+	ans := []*GrubMenuEntry{}
+	stack := []*grubBlock{}
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if line == "}" {
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+
+		words := grubWords(line)
+		if len(words) == 0 {
+			continue
+		}
+
+		if !strings.HasSuffix(line, "{") {
+			if (words[0] == "linux" || words[0] == "linuxefi") && len(words) > 1 {
+				for idx := len(stack) - 1; idx >= 0; idx-- {
+					if stack[idx].Kind == "menuentry" {
+						stack[idx].Entry.Linux = words[1]
+						break
+					}
+				}
+			}
+			continue
+		}
+
+		block := &grubBlock{}
+		if words[0] == "menuentry" || words[0] == "submenu" {
+			block.Kind = words[0]
+			block.Entry = newGrubMenuEntry(words)
+
+			path := []string{}
+			for _, b := range stack {
+				if b.Kind == "submenu" {
+					path = append(path, b.Entry.key())
+				}
+			}
+			block.Entry.Path = strings.Join(append(path, block.Entry.key()), ">")
+
+			if block.Kind == "menuentry" {
+				ans = append(ans, block.Entry)
+			}
+		}
+
+		stack = append(stack, block)
+	}
+
+	return ans
+}
+
+func ReadGrubCfg(file string) ([]*GrubMenuEntry, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("Error on read %s: %s", file, err.Error())
+	}
+
+	return ParseGrubCfg(string(content)), nil
+}
+
+// Return the first menu entry that boots the kernel image.
+// The entries of the recovery mode are ignored when possible.
+func FindGrubEntry(entries []*GrubMenuEntry, kernelFile string) *GrubMenuEntry {
+	var ans *GrubMenuEntry
+
+	for idx, e := range entries {
+		if e.Linux == "" || filepath.Base(e.Linux) != kernelFile {
+			continue
+		}
+
+		if ans == nil {
+			ans = entries[idx]
+		} else if strings.Contains(ans.Title, "recovery") &&
+			!strings.Contains(e.Title, "recovery") {
+			ans = entries[idx]
+		}
+	}
+
+	return ans
+}
@@ -0,0 +1,156 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package bootloader
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	GrubEnvSize   = 1024
+	GrubEnvHeader = "# GRUB Environment Block\n"
+)
+
+// GRUB environment block: a file of 1024 bytes with the header,
+// the variables and the padding with #.
+type GrubEnv struct {
+	keys   []string
+	values map[string]string
+}
+
+func NewGrubEnv() *GrubEnv {
+	return &GrubEnv{
+		keys:   []string{},
+		values: make(map[string]string, 0),
+	}
+}
+
+func ParseGrubEnv(data []byte) (*GrubEnv, error) {
+	if !bytes.HasPrefix(data, []byte(GrubEnvHeader)) {
+		return nil, fmt.Errorf("Invalid grubenv header")
+	}
+
+	ans := NewGrubEnv()
+	body := data[len(GrubEnvHeader):]
+
+	for i := 0; i < len(body); i++ {
+		if body[i] == '#' || body[i] == '\n' {
+			// Skip comments and padding.
+			for i < len(body) && body[i] != '\n' {
+				i++
+			}
+			continue
+		}
+
+		pos := bytes.IndexByte(body[i:], '=')
+		if pos <= 0 {
+			break
+		}
+		key := string(body[i : i+pos])
+
+		// The backslashes and the newlines of the values are
+		// escaped with a backslash.
+		var value strings.Builder
+		for i += pos + 1; i < len(body) && body[i] != '\n'; i++ {
+			if body[i] == '\\' && i+1 < len(body) {
+				i++
+			}
+			value.WriteByte(body[i])
+		}
+
+		ans.Set(key, value.String())
+	}
+
+	return ans, nil
+}
+
+// Read the grubenv file. If the file doesn't exist it's
+// returned an empty environment.
+func ReadGrubEnv(file string) (*GrubEnv, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NewGrubEnv(), nil
+		}
+		return nil, fmt.Errorf("Error on read %s: %s", file, err.Error())
+	}
+
+	ans, err := ParseGrubEnv(data)
+	if err != nil {
+		return nil, fmt.Errorf("Error on parse %s: %s", file, err.Error())
+	}
+
+	return ans, nil
+}
+
+func (e *GrubEnv) Get(key string) string { return e.values[key] }
+
+func (e *GrubEnv) Set(key, value string) {
+	if _, present := e.values[key]; !present {
+		e.keys = append(e.keys, key)
+	}
+	e.values[key] = value
+}
+
+func (e *GrubEnv) Unset(key string) {
+	if _, present := e.values[key]; !present {
+		return
+	}
+
+	delete(e.values, key)
+	keys := []string{}
+	for _, k := range e.keys {
+		if k != key {
+			keys = append(keys, k)
+		}
+	}
+	e.keys = keys
+}
+
+func (e *GrubEnv) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteString(GrubEnvHeader)
+	for _, k := range e.keys {
+		value := strings.ReplaceAll(e.values[k], "\\", "\\\\")
+		value = strings.ReplaceAll(value, "\n", "\\\n")
+		buf.WriteString(k + "=" + value + "\n")
+	}
+
+	if buf.Len() > GrubEnvSize {
+		return nil, fmt.Errorf("The variables exceed the grubenv size of %d bytes",
+			GrubEnvSize)
+	}
+
+	buf.Write(bytes.Repeat([]byte("#"), GrubEnvSize-buf.Len()))
+
+	return buf.Bytes(), nil
+}
+
+// Write the environment block. The file is replaced with a
+// temporary file to avoid a truncated block.
+func (e *GrubEnv) Write(file string) error {
+	data, err := e.Bytes()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return err
+	}
+
+	tmp := file + ".new"
+	err = os.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return fmt.Errorf("Error on write %s: %s", tmp, err.Error())
+	}
+
+	return os.Rename(tmp, file)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package bootloader_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/macaroni-os/macaronictl/pkg/bootloader"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Grubenv Test", func() {
+
+	Context("Environment block", func() {
+
+		It("Write and read grubenv", func() {
+			dir, err := os.MkdirTemp("", "macaronictl-grubenv")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(dir)
+
+			file := filepath.Join(dir, "grub", "grubenv")
+
+			Expect(SetGrubEnvEntry(file, "saved_entry",
+				"gnulinux-advanced>gnulinux-6.1.12-advanced")).Should(BeNil())
+			Expect(SetGrubEnvEntry(file, "next_entry", "Macaroni\\OS")).Should(BeNil())
+
+			data, err := os.ReadFile(file)
+			Expect(err).Should(BeNil())
+			Expect(len(data)).To(Equal(GrubEnvSize))
+
+			env, err := ReadGrubEnv(file)
+			Expect(err).Should(BeNil())
+			Expect(env.Get("saved_entry")).To(Equal("gnulinux-advanced>gnulinux-6.1.12-advanced"))
+			Expect(env.Get("next_entry")).To(Equal("Macaroni\\OS"))
+
+			env.Unset("next_entry")
+			Expect(env.Write(file)).Should(BeNil())
+
+			env, err = ReadGrubEnv(file)
+			Expect(err).Should(BeNil())
+			Expect(env.Get("next_entry")).To(Equal(""))
+			Expect(env.Get("saved_entry")).ToNot(Equal(""))
+		})
+
+	})
+
+	Context("Menu entries", func() {
+
+		It("Find the entry of the kernel", func() {
+			cfg := `
+menuentry 'Macaroni OS' --class gnu-linux $menuentry_id_option 'gnulinux-simple-uuid' {
+	linux	/boot/kernel-vanilla-x86_64-6.6.5 root=/dev/sda2
+}
+submenu 'Advanced options for Macaroni OS' $menuentry_id_option 'gnulinux-advanced-uuid' {
+	menuentry 'Macaroni OS, with Linux 6.1.12 (recovery mode)' $menuentry_id_option 'gnulinux-6.1.12-recovery-uuid' {
+		linux	/boot/kernel-vanilla-x86_64-6.1.12 root=/dev/sda2 single
+	}
+	menuentry 'Macaroni OS, with Linux 6.1.12' $menuentry_id_option 'gnulinux-6.1.12-advanced-uuid' {
+		if [ x$feature_all_video_module = xy ]; then
+			insmod all_video
+		fi
+		linux	/boot/kernel-vanilla-x86_64-6.1.12 root=/dev/sda2
+	}
+}
+`
+			entries := ParseGrubCfg(cfg)
+			Expect(len(entries)).To(Equal(3))
+
+			entry := FindGrubEntry(entries, "kernel-vanilla-x86_64-6.1.12")
+			Expect(entry).ToNot(BeNil())
+			Expect(entry.Title).To(Equal("Macaroni OS, with Linux 6.1.12"))
+			Expect(entry.Path).To(Equal("gnulinux-advanced-uuid>gnulinux-6.1.12-advanced-uuid"))
+
+			entry = FindGrubEntry(entries, "kernel-vanilla-x86_64-6.6.5")
+			Expect(entry).ToNot(BeNil())
+			Expect(entry.Path).To(Equal("gnulinux-simple-uuid"))
+
+			Expect(FindGrubEntry(entries, "kernel-vanilla-x86_64-5.15.1")).To(BeNil())
+		})
+
+	})
+
+})
@@ -0,0 +1,66 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package initrd
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+)
+
+type BoosterBuilder struct {
+	DryRun bool
+	Args   string
+	Rootfs string
+}
+
+func NewBoosterBuilder(args string, dryRun bool) *BoosterBuilder {
+	ans := &BoosterBuilder{
+		Args:   args,
+		DryRun: dryRun,
+	}
+
+	if os.Getenv("MACARONICTL_BOOSTER_ARGS") != "" {
+		ans.Args = os.Getenv("MACARONICTL_BOOSTER_ARGS")
+	}
+
+	return ans
+}
+
+func (b *BoosterBuilder) GetName() string { return "booster" }
+
+func (b *BoosterBuilder) SetRootfs(rootfs string) { b.Rootfs = rootfs }
+
+func (b *BoosterBuilder) GetCommand(kf *kernelspecs.KernelFiles, bootDir string) (string, []string, error) {
+	initrdFile, err := getInitrdFile(kf, bootDir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	args := []string{"build", "--force"}
+	args = append(args, strings.Fields(b.Args)...)
+	args = append(args, []string{
+		"--kernel-version", kf.Kernel.GetRelease(), initrdFile,
+	}...)
+
+	return utils.TryResolveBinaryAbsPath("booster"), args, nil
+}
+
+func (b *BoosterBuilder) Build(kf *kernelspecs.KernelFiles, bootDir string) error {
+	return b.BuildWithWriter(kf, bootDir, os.Stdout)
+}
+
+func (b *BoosterBuilder) BuildWithWriter(kf *kernelspecs.KernelFiles, bootDir string, w io.Writer) error {
+	booster, args, err := b.GetCommand(kf, utils.RootfsRelPath(b.Rootfs, bootDir))
+	if err != nil {
+		return err
+	}
+
+	return runBuildCommand(b.GetName(), booster, args, args[len(args)-1],
+		b.Rootfs, b.DryRun, w)
+}
@@ -0,0 +1,214 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package initrd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+)
+
+const (
+	DefaultInitrdBuilder = "dracut"
+)
+
+type InitrdBuilder interface {
+	GetName() string
+	Build(kf *kernelspecs.KernelFiles, bootDir string) error
+	BuildWithWriter(kf *kernelspecs.KernelFiles, bootDir string, w io.Writer) error
+	// Return the command and the arguments used to build the initrd image.
+	GetCommand(kf *kernelspecs.KernelFiles, bootDir string) (string, []string, error)
+	// Set the rootfs where run the builder with chroot.
+	SetRootfs(rootfs string)
+}
+
+func NewInitrdBuilder(name, args string, dryRun bool) (InitrdBuilder, error) {
+	switch name {
+	case "", "dracut":
+		return NewDracutBuilder(args, dryRun), nil
+	case "mkinitcpio":
+		return NewMkinitcpioBuilder(args, dryRun), nil
+	case "booster":
+		return NewBoosterBuilder(args, dryRun), nil
+	default:
+		return nil, fmt.Errorf("Unsupported initrd builder %s", name)
+	}
+}
+
+// Select the initrd builder to use for a specific kernel. The forced
+// builder has the precedence over the builder defined in the kernel
+// profile that has the precedence over the default builder.
+// The same logic is used for the options of the builder.
+type InitrdBuilderSelector struct {
+	Forced     string
+	Default    string
+	Args       map[string]string
+	ForcedArgs map[string]string
+	DryRun     bool
+	// Root directory of the system. The builders are executed
+	// inside the rootfs with chroot.
+	Rootfs string
+	// Early microcode stage executed after the build.
+	Microcode *MicrocodeStage
+}
+
+func NewInitrdBuilderSelector(forced, defaultBuilder string, dryRun bool) *InitrdBuilderSelector {
+	return &InitrdBuilderSelector{
+		Forced:     forced,
+		Default:    defaultBuilder,
+		Args:       make(map[string]string, 0),
+		ForcedArgs: make(map[string]string, 0),
+		DryRun:     dryRun,
+	}
+}
+
+// Set the default options of the builder.
+func (s *InitrdBuilderSelector) SetArgs(builder, args string) {
+	s.Args[builder] = args
+}
+
+// Set the options of the builder that override the options of
+// the kernel profiles.
+func (s *InitrdBuilderSelector) SetForcedArgs(builder, args string) {
+	s.ForcedArgs[builder] = args
+}
+
+func (s *InitrdBuilderSelector) GetArgs(builder string, kf *kernelspecs.KernelFiles) string {
+	if args, ok := s.ForcedArgs[builder]; ok {
+		return args
+	}
+
+	if builder == "dracut" && kf != nil && kf.Type != nil &&
+		kf.Type.GetDracutArgs() != "" {
+		return kf.Type.GetDracutArgs()
+	}
+
+	return s.Args[builder]
+}
+
+func (s *InitrdBuilderSelector) GetBuilderName(kf *kernelspecs.KernelFiles) string {
+	if s.Forced != "" {
+		return s.Forced
+	}
+
+	if kf != nil && kf.Type != nil && kf.Type.GetInitrdBuilder() != "" {
+		return kf.Type.GetInitrdBuilder()
+	}
+
+	if s.Default != "" {
+		return s.Default
+	}
+
+	return DefaultInitrdBuilder
+}
+
+func (s *InitrdBuilderSelector) GetBuilder(kf *kernelspecs.KernelFiles) (InitrdBuilder, error) {
+	name := s.GetBuilderName(kf)
+	b, err := NewInitrdBuilder(name, s.GetArgs(name, kf), s.DryRun)
+	if err != nil {
+		return nil, err
+	}
+	b.SetRootfs(s.Rootfs)
+
+	// The early microcode is managed by the microcode stage.
+	if d, ok := b.(*DracutBuilder); ok && s.Microcode.IsEnabled() {
+		d.NoEarlyMicrocode = true
+	}
+
+	return b, nil
+}
+
+func (s *InitrdBuilderSelector) Build(kf *kernelspecs.KernelFiles, bootDir string) error {
+	return s.BuildWithWriter(kf, bootDir, os.Stdout)
+}
+
+// Build the initrd image and run the microcode stage.
+func (s *InitrdBuilderSelector) BuildWithWriter(kf *kernelspecs.KernelFiles,
+	bootDir string, w io.Writer) error {
+
+	b, err := s.GetBuilder(kf)
+	if err != nil {
+		return err
+	}
+
+	err = b.BuildWithWriter(kf, bootDir, w)
+	if err != nil {
+		return err
+	}
+
+	return s.Microcode.Apply(kf, bootDir, w)
+}
+
+// Prepare the initrd image of the kernel files if not present.
+func prepareInitrdImage(kf *kernelspecs.KernelFiles) (*kernelspecs.InitrdImage, error) {
+	if kf == nil || kf.Kernel == nil || kf.Type == nil {
+		return nil, errors.New("Invalid kernel file")
+	}
+
+	initrd := kf.Initrd
+	if kf.Initrd == nil {
+		initrd = kernelspecs.NewInitrdImageFromKernel(kf.Type, kf.Kernel)
+	}
+
+	kf.Initrd = initrd
+
+	return initrd, nil
+}
+
+func getInitrdFile(kf *kernelspecs.KernelFiles, bootDir string) (string, error) {
+	initrd, err := prepareInitrdImage(kf)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(bootDir, initrd.GenerateFilename()), nil
+}
+
+// Run the builder command. With a rootfs different to / the command
+// is executed inside the rootfs with chroot and the paths of the
+// arguments are relative to the rootfs.
+func runBuildCommand(name, binary string, args []string, initrdFile string,
+	rootfs string, dryRun bool, w io.Writer) error {
+
+	cmdArgs := utils.ChrootArgs(rootfs, append([]string{binary}, args...))
+
+	if dryRun {
+		fmt.Fprintln(w, "[dry-run mode] command: "+strings.Join(cmdArgs, " "))
+		return nil
+	}
+
+	fmt.Fprint(w, fmt.Sprintf("Creating initrd image %s...",
+		utils.RootfsPath(rootfs, initrdFile)))
+
+	command := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	command.Stdout = w
+	command.Stderr = w
+
+	err := command.Start()
+	if err != nil {
+		return fmt.Errorf("Error on start %s command: %s", name, err.Error())
+	}
+
+	err = command.Wait()
+	if err != nil {
+		return fmt.Errorf("Error on waiting %s command: %s", name, err.Error())
+	}
+
+	if command.ProcessState.ExitCode() != 0 {
+		return fmt.Errorf("%s command exiting with %d",
+			name, command.ProcessState.ExitCode())
+	}
+
+	fmt.Fprintln(w, "DONE")
+
+	return nil
+}
@@ -0,0 +1,115 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package initrd_test
+
+import (
+	"path/filepath"
+
+	. "github.com/macaroni-os/macaronictl/pkg/initrd"
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Initrd Builder Test", func() {
+
+	newKernelFiles := func(builder string) *kernelspecs.KernelFiles {
+		t := &kernelspecs.KernelType{
+			Name:          "Macaroni Vanilla",
+			KernelPrefix:  "kernel",
+			InitrdPrefix:  "initramfs",
+			Suffix:        "macaroni",
+			Type:          "vanilla",
+			InitrdBuilder: builder,
+		}
+		kf := kernelspecs.NewKernelFiles(t)
+		kf.Kernel = kernelspecs.NewKernelImage()
+		kf.Kernel.SetPrefix("kernel")
+		kf.Kernel.SetType("vanilla")
+		kf.Kernel.SetArch("x86_64")
+		kf.Kernel.SetVersion("6.1.12")
+		kf.Kernel.SetSuffix("macaroni")
+		return kf
+	}
+
+	Context("Selector", func() {
+
+		It("Default builder", func() {
+			s := NewInitrdBuilderSelector("", "", true)
+			Expect(s.GetBuilderName(newKernelFiles(""))).To(Equal("dracut"))
+		})
+
+		It("Configuration builder", func() {
+			s := NewInitrdBuilderSelector("", "booster", true)
+			Expect(s.GetBuilderName(newKernelFiles(""))).To(Equal("booster"))
+		})
+
+		It("Profile builder", func() {
+			s := NewInitrdBuilderSelector("", "booster", true)
+			Expect(s.GetBuilderName(newKernelFiles("mkinitcpio"))).To(Equal("mkinitcpio"))
+		})
+
+		It("Forced builder", func() {
+			s := NewInitrdBuilderSelector("dracut", "booster", true)
+			Expect(s.GetBuilderName(newKernelFiles("mkinitcpio"))).To(Equal("dracut"))
+		})
+
+		It("Dracut options", func() {
+			s := NewInitrdBuilderSelector("", "", true)
+			s.SetArgs("dracut", "-q -f")
+			kf := newKernelFiles("")
+			Expect(s.GetArgs("dracut", kf)).To(Equal("-q -f"))
+
+			kf.Type.DracutArgs = "-H -f"
+			Expect(s.GetArgs("dracut", kf)).To(Equal("-H -f"))
+
+			s.SetForcedArgs("dracut", "-x")
+			Expect(s.GetArgs("dracut", kf)).To(Equal("-x"))
+		})
+
+		It("Unsupported builder", func() {
+			s := NewInitrdBuilderSelector("foo", "", true)
+			_, err := s.GetBuilder(newKernelFiles(""))
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Context("Commands", func() {
+
+		initrdFile := filepath.Join("/boot", "initramfs-vanilla-x86_64-6.1.12-macaroni")
+
+		It("dracut with extra modules", func() {
+			b := NewDracutBuilder("-H -f", true)
+			kf := newKernelFiles("")
+			kf.Type.ExtraModules = []string{"nvme", "xhci_pci"}
+			_, args, err := b.GetCommand(kf, "/boot")
+			Expect(err).Should(BeNil())
+			Expect(args).To(Equal([]string{
+				"-H", "-f", "--add-drivers", "nvme xhci_pci",
+				"--kver", "6.1.12-macaroni", initrdFile,
+			}))
+		})
+
+		It("mkinitcpio", func() {
+			b := NewMkinitcpioBuilder("", true)
+			_, args, err := b.GetCommand(newKernelFiles(""), "/boot")
+			Expect(err).Should(BeNil())
+			Expect(args).To(Equal([]string{
+				"-k", "6.1.12-macaroni", "-g", initrdFile,
+			}))
+		})
+
+		It("booster", func() {
+			b := NewBoosterBuilder("--strip", true)
+			_, args, err := b.GetCommand(newKernelFiles(""), "/boot")
+			Expect(err).Should(BeNil())
+			Expect(args).To(Equal([]string{
+				"build", "--force", "--strip",
+				"--kernel-version", "6.1.12-macaroni", initrdFile,
+			}))
+		})
+	})
+})
@@ -0,0 +1,340 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package initrd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+const (
+	CpioNewcMagic    = "070701"
+	CpioNewcCrcMagic = "070702"
+	CpioTrailer      = "TRAILER!!!"
+
+	cpioNewcHeaderSize = 110
+	// Max size of the names and of the target of the symlinks (PATH_MAX).
+	cpioMaxNameSize = 4096
+	cpioMaxLinkSize = 4096
+
+	// File types of the mode field
+	CpioModeTypeMask = 0170000
+	CpioModeDir      = 0040000
+	CpioModeRegular  = 0100000
+	CpioModeSymlink  = 0120000
+	CpioModeChar     = 0020000
+	CpioModeBlock    = 0060000
+	CpioModeFifo     = 0010000
+	CpioModeSocket   = 0140000
+)
+
+type CpioHeader struct {
+	Name     string `json:"name" yaml:"name"`
+	Inode    uint32 `json:"inode" yaml:"inode"`
+	Mode     uint32 `json:"mode" yaml:"mode"`
+	Uid      uint32 `json:"uid" yaml:"uid"`
+	Gid      uint32 `json:"gid" yaml:"gid"`
+	Nlink    uint32 `json:"nlink" yaml:"nlink"`
+	Mtime    int64  `json:"mtime" yaml:"mtime"`
+	Size     int64  `json:"size" yaml:"size"`
+	DevMajor uint32 `json:"dev_major" yaml:"dev_major"`
+	DevMinor uint32 `json:"dev_minor" yaml:"dev_minor"`
+	RMajor   uint32 `json:"rdev_major,omitempty" yaml:"rdev_major,omitempty"`
+	RMinor   uint32 `json:"rdev_minor,omitempty" yaml:"rdev_minor,omitempty"`
+	Linkname string `json:"linkname,omitempty" yaml:"linkname,omitempty"`
+}
+
+// Reader of the cpio archives in newc format used by the
+// initramfs images. The reader consumes only the bytes of the
+// archive so that the archives concatenated could be read in sequence.
+type CpioReader struct {
+	r       io.Reader
+	offset  int64
+	remain  int64
+	padding int64
+	eof     bool
+}
+
+func NewCpioReader(r io.Reader) *CpioReader {
+	return &CpioReader{r: r}
+}
+
+func (h *CpioHeader) IsDir() bool     { return h.Mode&CpioModeTypeMask == CpioModeDir }
+func (h *CpioHeader) IsRegular() bool { return h.Mode&CpioModeTypeMask == CpioModeRegular }
+func (h *CpioHeader) IsSymlink() bool { return h.Mode&CpioModeTypeMask == CpioModeSymlink }
+
+func (h *CpioHeader) FileMode() os.FileMode {
+	ans := os.FileMode(h.Mode & 0777)
+	switch h.Mode & CpioModeTypeMask {
+	case CpioModeDir:
+		ans |= os.ModeDir
+	case CpioModeSymlink:
+		ans |= os.ModeSymlink
+	case CpioModeChar:
+		ans |= os.ModeDevice | os.ModeCharDevice
+	case CpioModeBlock:
+		ans |= os.ModeDevice
+	case CpioModeFifo:
+		ans |= os.ModeNamedPipe
+	case CpioModeSocket:
+		ans |= os.ModeSocket
+	}
+	if h.Mode&04000 != 0 {
+		ans |= os.ModeSetuid
+	}
+	if h.Mode&02000 != 0 {
+		ans |= os.ModeSetgid
+	}
+	if h.Mode&01000 != 0 {
+		ans |= os.ModeSticky
+	}
+	return ans
+}
+
+func (c *CpioReader) read(buf []byte) error {
+	n, err := io.ReadFull(c.r, buf)
+	c.offset += int64(n)
+	return err
+}
+
+func (c *CpioReader) skip(n int64) error {
+	if n <= 0 {
+		return nil
+	}
+	written, err := io.CopyN(io.Discard, c.r, n)
+	c.offset += written
+	return err
+}
+
+func pad4(n int64) int64 {
+	return (4 - n%4) % 4
+}
+
+func parseHex(field []byte) (uint32, error) {
+	v, err := strconv.ParseUint(string(field), 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid cpio header field %q", string(field))
+	}
+	return uint32(v), nil
+}
+
+// Return the next entry of the archive or io.EOF when the
+// trailer is reached.
+func (c *CpioReader) Next() (*CpioHeader, error) {
+	if c.eof {
+		return nil, io.EOF
+	}
+
+	// Skip the data not read of the previous entry
+	err := c.skip(c.remain + c.padding)
+	if err != nil {
+		return nil, err
+	}
+	c.remain = 0
+	c.padding = 0
+
+	buf := make([]byte, cpioNewcHeaderSize)
+	err = c.read(buf)
+	if err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, errors.New("Truncated cpio header")
+		}
+		return nil, err
+	}
+
+	magic := string(buf[0:6])
+	if magic != CpioNewcMagic && magic != CpioNewcCrcMagic {
+		return nil, fmt.Errorf("Unsupported cpio magic %q", magic)
+	}
+
+	fields := make([]uint32, 13)
+	for i := range fields {
+		fields[i], err = parseHex(buf[6+i*8 : 14+i*8])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ans := &CpioHeader{
+		Inode:    fields[0],
+		Mode:     fields[1],
+		Uid:      fields[2],
+		Gid:      fields[3],
+		Nlink:    fields[4],
+		Mtime:    int64(fields[5]),
+		Size:     int64(fields[6]),
+		DevMajor: fields[7],
+		DevMinor: fields[8],
+		RMajor:   fields[9],
+		RMinor:   fields[10],
+	}
+	namesize := int64(fields[11])
+	if namesize == 0 {
+		return nil, errors.New("Invalid cpio entry with empty name")
+	}
+	if namesize > cpioMaxNameSize {
+		return nil, fmt.Errorf("Invalid cpio entry with name of %d bytes", namesize)
+	}
+
+	name := make([]byte, namesize)
+	err = c.read(name)
+	if err != nil {
+		return nil, err
+	}
+	// Drop the NUL terminator
+	ans.Name = string(name[:namesize-1])
+
+	err = c.skip(pad4(cpioNewcHeaderSize + namesize))
+	if err != nil {
+		return nil, err
+	}
+
+	if ans.Name == CpioTrailer {
+		c.eof = true
+		return nil, io.EOF
+	}
+
+	c.remain = ans.Size
+	c.padding = pad4(ans.Size)
+
+	if ans.IsSymlink() {
+		if ans.Size > cpioMaxLinkSize {
+			return nil, fmt.Errorf("Invalid symlink %s with target of %d bytes",
+				ans.Name, ans.Size)
+		}
+		target := make([]byte, ans.Size)
+		err = c.read(target)
+		if err != nil {
+			return nil, err
+		}
+		c.remain = 0
+		ans.Linkname = string(target)
+	}
+
+	return ans, nil
+}
+
+// Read the data of the current entry.
+func (c *CpioReader) Read(p []byte) (int, error) {
+	if c.remain <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > c.remain {
+		p = p[:c.remain]
+	}
+	n, err := c.r.Read(p)
+	c.offset += int64(n)
+	c.remain -= int64(n)
+	if err == io.EOF && c.remain > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// Consume the padding of the last entry. It's needed to
+// read the archive concatenated after the trailer.
+func (c *CpioReader) Close() error {
+	err := c.skip(c.remain + c.padding)
+	c.remain = 0
+	c.padding = 0
+	return err
+}
+
+// Return the number of bytes consumed.
+func (c *CpioReader) Offset() int64 { return c.offset }
+
+// Writer of cpio archives in newc format. It's used to create
+// the early microcode archives.
+type CpioWriter struct {
+	w      io.Writer
+	offset int64
+	inode  uint32
+	remain int64
+}
+
+func NewCpioWriter(w io.Writer) *CpioWriter {
+	return &CpioWriter{w: w}
+}
+
+func (c *CpioWriter) write(buf []byte) error {
+	n, err := c.w.Write(buf)
+	c.offset += int64(n)
+	return err
+}
+
+func (c *CpioWriter) pad() error {
+	return c.write(make([]byte, pad4(c.offset)))
+}
+
+// Write the header of the next entry. The data of the regular
+// files is written with Write.
+func (c *CpioWriter) WriteHeader(h *CpioHeader) error {
+	if c.remain > 0 {
+		return fmt.Errorf("Missing %d bytes of the previous entry", c.remain)
+	}
+
+	err := c.pad()
+	if err != nil {
+		return err
+	}
+
+	c.inode++
+	size := h.Size
+	if h.IsSymlink() {
+		size = int64(len(h.Linkname))
+	}
+	nlink := h.Nlink
+	if nlink == 0 {
+		nlink = 1
+	}
+
+	header := fmt.Sprintf("%s%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X",
+		CpioNewcMagic, c.inode, h.Mode, h.Uid, h.Gid, nlink, uint32(h.Mtime),
+		uint32(size), h.DevMajor, h.DevMinor, h.RMajor, h.RMinor,
+		len(h.Name)+1, 0)
+
+	err = c.write(append([]byte(header+h.Name), 0))
+	if err != nil {
+		return err
+	}
+
+	err = c.pad()
+	if err != nil {
+		return err
+	}
+
+	if h.IsSymlink() {
+		return c.write([]byte(h.Linkname))
+	}
+
+	c.remain = size
+
+	return nil
+}
+
+// Write the data of the current entry.
+func (c *CpioWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > c.remain {
+		return 0, errors.New("Write exceeds the size of the cpio entry")
+	}
+	n, err := c.w.Write(p)
+	c.offset += int64(n)
+	c.remain -= int64(n)
+	return n, err
+}
+
+// Write the trailer of the archive. The archive is padded
+// to 512 bytes like the kernel tools.
+func (c *CpioWriter) Close() error {
+	err := c.WriteHeader(&CpioHeader{Name: CpioTrailer})
+	if err != nil {
+		return err
+	}
+
+	return c.write(make([]byte, (512-c.offset%512)%512))
+}
@@ -1,15 +1,12 @@
 /*
-Copyright © 2021 Macaroni OS Linux
+Copyright © 2021-2026 Macaroni OS Linux
 See AUTHORS and LICENSE for the license details and contributors.
 */
 package initrd
 
 import (
-	"errors"
-	"fmt"
+	"io"
 	"os"
-	"os/exec"
-	"path/filepath"
 	"strings"
 
 	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
@@ -19,6 +16,10 @@ import (
 type DracutBuilder struct {
 	DryRun bool
 	Args   string
+	Rootfs string
+	// Disable the early microcode of dracut when it's managed
+	// by the microcode stage.
+	NoEarlyMicrocode bool
 }
 
 func NewDracutBuilder(args string, dryRun bool) *DracutBuilder {
@@ -34,66 +35,44 @@ func NewDracutBuilder(args string, dryRun bool) *DracutBuilder {
 	return ans
 }
 
-func (d *DracutBuilder) Build(kf *kernelspecs.KernelFiles, bootDir string) error {
-	if kf == nil || kf.Kernel == nil || kf.Type == nil {
-		return errors.New("Invalid kernel file")
-	}
+func (d *DracutBuilder) GetName() string { return "dracut" }
+
+func (d *DracutBuilder) SetRootfs(rootfs string) { d.Rootfs = rootfs }
 
-	kverstr := kf.Kernel.GetVersion()
-	if kf.Kernel.GetSuffix() != "" {
-		kverstr += "-" + kf.Kernel.GetSuffix()
+func (d *DracutBuilder) GetCommand(kf *kernelspecs.KernelFiles, bootDir string) (string, []string, error) {
+	initrdFile, err := getInitrdFile(kf, bootDir)
+	if err != nil {
+		return "", nil, err
 	}
 
 	// Convert args in array
-	args := strings.Split(d.Args, " ")
-
-	initrd := kf.Initrd
-	if kf.Initrd == nil {
-		initrd = kernelspecs.NewInitrdImage()
-		initrd.SetPrefix(kf.Type.GetInitrdPrefixSanitized())
-		initrd.SetVersion(kf.Kernel.GetVersion())
-		initrd.SetSuffix(kf.Type.GetSuffix())
-		initrd.SetKernelType(kf.Kernel.GetType())
-		initrd.SetArch(kf.Kernel.GetArch())
+	args := strings.Fields(d.Args)
+	if kf.Type != nil && len(kf.Type.GetExtraModules()) > 0 {
+		args = append(args, "--add-drivers",
+			strings.Join(kf.Type.GetExtraModules(), " "))
 	}
-
-	kf.Initrd = initrd
-
-	if d.DryRun {
-		fmt.Println("[dry-run mode] command: dracut " + d.Args)
-		return nil
+	if d.NoEarlyMicrocode {
+		args = append(args, "--no-early-microcode")
 	}
-
-	initrdFile := filepath.Join(bootDir, initrd.GenerateFilename())
-
 	args = append(args, []string{
-		"--kver", kverstr, initrdFile,
+		"--kver", kf.Kernel.GetRelease(), initrdFile,
 	}...)
 
-	fmt.Print(fmt.Sprintf("Creating initrd image %s...", initrdFile))
-
-	dracut := utils.TryResolveBinaryAbsPath("dracut")
-	dracutCommand := exec.Command(dracut, args...)
-	dracutCommand.Stdout = os.Stdout
-	dracutCommand.Stderr = os.Stderr
+	return utils.TryResolveBinaryAbsPath("dracut"), args, nil
+}
 
-	err := dracutCommand.Start()
-	if err != nil {
-		return errors.New("Error on start dracut command: " + err.Error())
-	}
+func (d *DracutBuilder) Build(kf *kernelspecs.KernelFiles, bootDir string) error {
+	return d.BuildWithWriter(kf, bootDir, os.Stdout)
+}
 
-	err = dracutCommand.Wait()
+// Build the initrd image and write the output of dracut to
+// the writer in input.
+func (d *DracutBuilder) BuildWithWriter(kf *kernelspecs.KernelFiles, bootDir string, w io.Writer) error {
+	dracut, args, err := d.GetCommand(kf, utils.RootfsRelPath(d.Rootfs, bootDir))
 	if err != nil {
-		return errors.New("Error on waiting dracut command: " + err.Error())
-	}
-
-	if dracutCommand.ProcessState.ExitCode() != 0 {
-		return errors.New(
-			fmt.Sprintf("dracut command exiting with %d",
-				dracutCommand.ProcessState.ExitCode()))
+		return err
 	}
 
-	fmt.Println("DONE")
-
-	return nil
+	return runBuildCommand(d.GetName(), dracut, args, args[len(args)-1],
+		d.Rootfs, d.DryRun, w)
 }
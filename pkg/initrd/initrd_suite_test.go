@@ -0,0 +1,17 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package initrd_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestInitrd(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Initrd Suite")
+}
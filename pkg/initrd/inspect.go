@@ -0,0 +1,318 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package initrd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/macaroni-os/macaronictl/pkg/utils/compress"
+)
+
+const (
+	CompressionNone  = compress.CompressionNone
+	CompressionGzip  = compress.CompressionGzip
+	CompressionXz    = compress.CompressionXz
+	CompressionZstd  = compress.CompressionZstd
+	CompressionLz4   = compress.CompressionLz4
+	CompressionBzip2 = compress.CompressionBzip2
+)
+
+type InitrdEntry struct {
+	*CpioHeader `json:",inline" yaml:",inline"`
+
+	// Index of the cpio archive inside the image.
+	Archive     int    `json:"archive" yaml:"archive"`
+	Compression string `json:"compression" yaml:"compression"`
+}
+
+type InitrdArchive struct {
+	Index       int    `json:"index" yaml:"index"`
+	Compression string `json:"compression" yaml:"compression"`
+	Entries     int    `json:"entries" yaml:"entries"`
+}
+
+// Callback called for every entry of the image. The reader
+// returns the data of the entry.
+type InitrdWalkFunc func(e *InitrdEntry, r io.Reader) error
+
+// Return the compression of the data from the magic bytes. The
+// uncompressed cpio archives have the compression none.
+func DetectCompression(magic []byte) (string, error) {
+	if bytes.HasPrefix(magic, []byte(CpioNewcMagic)) ||
+		bytes.HasPrefix(magic, []byte(CpioNewcCrcMagic)) {
+		return CompressionNone, nil
+	}
+	return compress.DetectCompression(magic)
+}
+
+// Skip the zero padding between the archives and return
+// the magic of the next archive.
+func peekNextArchive(br *bufio.Reader) ([]byte, error) {
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if b[0] != 0 {
+			break
+		}
+		br.Discard(1)
+	}
+
+	magic, err := br.Peek(6)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(magic) == 0 {
+		return nil, io.EOF
+	}
+	return magic, nil
+}
+
+func walkCpioArchive(r io.Reader, archive int, compression string, fn InitrdWalkFunc) error {
+	cr := NewCpioReader(r)
+	for {
+		h, err := cr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Error on read archive %d: %s", archive, err.Error())
+		}
+
+		err = fn(&InitrdEntry{
+			CpioHeader:  h,
+			Archive:     archive,
+			Compression: compression,
+		}, cr)
+		if err != nil {
+			return err
+		}
+	}
+	return cr.Close()
+}
+
+// Walk the cpio archives of the stream. The uncompressed archives
+// (early microcode) are followed by the compressed main archive.
+func walkInitrdStream(br *bufio.Reader, compression string, archive *int,
+	archives *[]*InitrdArchive, fn InitrdWalkFunc) error {
+
+	for {
+		magic, err := peekNextArchive(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		c, err := DetectCompression(magic)
+		if err != nil {
+			return err
+		}
+
+		if c == CompressionNone {
+			info := &InitrdArchive{Index: *archive, Compression: compression}
+			*archives = append(*archives, info)
+
+			err = walkCpioArchive(br, *archive, compression,
+				func(e *InitrdEntry, r io.Reader) error {
+					info.Entries++
+					return fn(e, r)
+				})
+			if err != nil {
+				return err
+			}
+			*archive++
+			continue
+		}
+
+		if compression != CompressionNone {
+			return fmt.Errorf("Unexpected %s data inside %s archive", c, compression)
+		}
+
+		dr, err := compress.NewDecompressor(c, br)
+		if err != nil {
+			return err
+		}
+
+		err = walkInitrdStream(bufio.NewReader(dr), c, archive, archives, fn)
+		cerr := dr.Close()
+		if err != nil {
+			return err
+		}
+		if cerr != nil {
+			return cerr
+		}
+
+		// The data after the compressed archive is ignored
+		// like the kernel does.
+		return nil
+	}
+}
+
+// Walk all entries of the initrd image and return the list of
+// the cpio archives found.
+func WalkInitrdImage(file string, fn InitrdWalkFunc) ([]*InitrdArchive, error) {
+	archives := []*InitrdArchive{}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return archives, err
+	}
+	defer f.Close()
+
+	archive := 0
+	err = walkInitrdStream(bufio.NewReader(f), CompressionNone, &archive, &archives, fn)
+	if err != nil {
+		return archives, err
+	}
+
+	if len(archives) == 0 {
+		return archives, errors.New("No cpio archives found")
+	}
+
+	return archives, nil
+}
+
+// Return the list of the entries of the initrd image.
+func ListInitrdImage(file string) ([]*InitrdEntry, []*InitrdArchive, error) {
+	ans := []*InitrdEntry{}
+	archives, err := WalkInitrdImage(file, func(e *InitrdEntry, r io.Reader) error {
+		ans = append(ans, e)
+		return nil
+	})
+	return ans, archives, err
+}
+
+// Check that the parent directories of the target are real directories
+// inside the target directory. A symlink in the path could redirect the
+// write outside the target directory.
+func checkExtractPath(targetDir, target string) error {
+	rel, err := filepath.Rel(targetDir, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return fmt.Errorf("The path %s is outside %s", target, targetDir)
+	}
+
+	dir := targetDir
+	for _, c := range strings.Split(filepath.Dir(rel), string(filepath.Separator)) {
+		if c == "." {
+			continue
+		}
+		dir = filepath.Join(dir, c)
+
+		info, err := os.Lstat(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("The path %s contains the symlink %s", target, dir)
+		}
+	}
+
+	return nil
+}
+
+// Extract the files of the initrd image under the target directory.
+// The entries of the early microcode archives are merged with the
+// entries of the main archive. The device nodes are skipped.
+// The symlinks are created after the files and the directories and
+// the paths with a symlink are refused, so that the entries are never
+// written outside the target directory.
+func ExtractInitrdImage(file, targetDir string, filter func(*InitrdEntry) bool) (int, error) {
+	n := 0
+
+	err := os.MkdirAll(targetDir, 0755)
+	if err != nil {
+		return n, err
+	}
+
+	symlinks := []*InitrdEntry{}
+
+	_, err = WalkInitrdImage(file, func(e *InitrdEntry, r io.Reader) error {
+		if filter != nil && !filter(e) {
+			return nil
+		}
+
+		name := filepath.Clean("/" + e.Name)
+		if name == "/" {
+			return nil
+		}
+		target := filepath.Join(targetDir, name)
+
+		if e.IsSymlink() {
+			symlinks = append(symlinks, e)
+			return nil
+		}
+
+		err := checkExtractPath(targetDir, target)
+		if err != nil {
+			return fmt.Errorf("Error on extract %s: %s", e.Name, err.Error())
+		}
+
+		err = os.MkdirAll(filepath.Dir(target), 0755)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case e.IsDir():
+			err = os.MkdirAll(target, e.FileMode().Perm())
+		case e.IsRegular():
+			var f *os.File
+			f, err = os.OpenFile(target,
+				os.O_CREATE|os.O_TRUNC|os.O_WRONLY|syscall.O_NOFOLLOW,
+				e.FileMode().Perm())
+			if err != nil {
+				return fmt.Errorf("Error on extract %s: %s", e.Name, err.Error())
+			}
+			_, err = io.Copy(f, r)
+			f.Close()
+		default:
+			// POST: device nodes, fifo and sockets aren't extracted.
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("Error on extract %s: %s", e.Name, err.Error())
+		}
+
+		n++
+		return nil
+	})
+	if err != nil {
+		return n, err
+	}
+
+	for _, e := range symlinks {
+		target := filepath.Join(targetDir, filepath.Clean("/"+e.Name))
+
+		err = checkExtractPath(targetDir, target)
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(target), 0755)
+		}
+		if err == nil {
+			os.Remove(target)
+			err = os.Symlink(e.Linkname, target)
+		}
+		if err != nil {
+			return n, fmt.Errorf("Error on extract %s: %s", e.Name, err.Error())
+		}
+
+		n++
+	}
+
+	return n, nil
+}
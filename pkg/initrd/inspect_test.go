@@ -0,0 +1,163 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package initrd_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/macaroni-os/macaronictl/pkg/initrd"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type cpioFile struct {
+	Name string
+	Mode uint32
+	Data string
+}
+
+func newcArchive(files []cpioFile) []byte {
+	buf := bytes.NewBuffer(nil)
+	pad := func() {
+		for buf.Len()%4 != 0 {
+			buf.WriteByte(0)
+		}
+	}
+
+	files = append(files, cpioFile{Name: CpioTrailer})
+	for idx, f := range files {
+		fmt.Fprintf(buf, "%s%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X",
+			CpioNewcMagic, idx+1, f.Mode, 0, 0, 1, 0, len(f.Data),
+			0, 0, 0, 0, len(f.Name)+1, 0)
+		buf.WriteString(f.Name)
+		buf.WriteByte(0)
+		pad()
+		buf.WriteString(f.Data)
+		pad()
+	}
+
+	// Pad the archive to 512 bytes like the kernel tools.
+	for buf.Len()%512 != 0 {
+		buf.WriteByte(0)
+	}
+
+	return buf.Bytes()
+}
+
+func writeInitrdImage(file string) error {
+	early := newcArchive([]cpioFile{
+		{Name: "kernel", Mode: 0040755},
+		{Name: "kernel/x86/microcode/GenuineIntel.bin", Mode: 0100644, Data: "ucode"},
+	})
+
+	main := bytes.NewBuffer(nil)
+	z := gzip.NewWriter(main)
+	z.Write(newcArchive([]cpioFile{
+		{Name: "init", Mode: 0120777, Data: "usr/lib/systemd/systemd"},
+		{Name: "etc", Mode: 0040755},
+		{Name: "etc/os-release", Mode: 0100644, Data: "ID=macaroni\n"},
+	}))
+	z.Close()
+
+	return os.WriteFile(file, append(early, main.Bytes()...), 0644)
+}
+
+var _ = Describe("Initrd Inspect Test", func() {
+
+	Context("Microcode and gzip archive", func() {
+
+		It("Detect compression", func() {
+			c, err := DetectCompression([]byte{0x28, 0xb5, 0x2f, 0xfd, 0, 0})
+			Expect(err).Should(BeNil())
+			Expect(c).To(Equal(CompressionZstd))
+		})
+
+		It("List", func() {
+			tmpdir, err := os.MkdirTemp("", "macaronictl-initrd")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+			file := filepath.Join(tmpdir, "initramfs")
+			Expect(writeInitrdImage(file)).Should(BeNil())
+
+			entries, archives, err := ListInitrdImage(file)
+			Expect(err).Should(BeNil())
+			Expect(len(archives)).To(Equal(2))
+			Expect(archives[0].Compression).To(Equal(CompressionNone))
+			Expect(archives[1].Compression).To(Equal(CompressionGzip))
+			Expect(len(entries)).To(Equal(5))
+			Expect(entries[1].Name).To(Equal("kernel/x86/microcode/GenuineIntel.bin"))
+			Expect(entries[2].IsSymlink()).To(BeTrue())
+			Expect(entries[2].Linkname).To(Equal("usr/lib/systemd/systemd"))
+			Expect(entries[4].Archive).To(Equal(1))
+			Expect(entries[4].Size).To(Equal(int64(12)))
+		})
+
+		It("Extract", func() {
+			tmpdir, err := os.MkdirTemp("", "macaronictl-initrd")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+			file := filepath.Join(tmpdir, "initramfs")
+			Expect(writeInitrdImage(file)).Should(BeNil())
+
+			target := filepath.Join(tmpdir, "extract")
+			n, err := ExtractInitrdImage(file, target, nil)
+			Expect(err).Should(BeNil())
+			Expect(n).To(Equal(5))
+
+			data, err := os.ReadFile(filepath.Join(target, "etc", "os-release"))
+			Expect(err).Should(BeNil())
+			Expect(string(data)).To(Equal("ID=macaroni\n"))
+
+			link, err := os.Readlink(filepath.Join(target, "init"))
+			Expect(err).Should(BeNil())
+			Expect(link).To(Equal("usr/lib/systemd/systemd"))
+		})
+
+		It("Extract without escape the target directory", func() {
+			tmpdir, err := os.MkdirTemp("", "macaronictl-initrd")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+
+			outside := filepath.Join(tmpdir, "outside")
+			Expect(os.MkdirAll(outside, 0755)).Should(BeNil())
+
+			file := filepath.Join(tmpdir, "initramfs")
+			Expect(os.WriteFile(file, newcArchive([]cpioFile{
+				{Name: "lib", Mode: 0120777, Data: outside},
+				{Name: "lib/foo", Mode: 0100644, Data: "foo\n"},
+			}), 0644)).Should(BeNil())
+
+			target := filepath.Join(tmpdir, "extract")
+			ExtractInitrdImage(file, target, nil)
+
+			_, err = os.Stat(filepath.Join(outside, "foo"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+
+			// An existing symlink of the target directory isn't followed.
+			Expect(os.RemoveAll(target)).Should(BeNil())
+			Expect(os.MkdirAll(target, 0755)).Should(BeNil())
+			Expect(os.Symlink(outside, filepath.Join(target, "lib"))).Should(BeNil())
+			_, err = ExtractInitrdImage(file, target, nil)
+			Expect(err).ShouldNot(BeNil())
+
+			_, err = os.Stat(filepath.Join(outside, "foo"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("Reject names longer than PATH_MAX", func() {
+			header := fmt.Sprintf("%s%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X",
+				CpioNewcMagic, 1, 0100644, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0x7fffffff, 0)
+
+			_, err := NewCpioReader(bytes.NewReader([]byte(header))).Next()
+			Expect(err).ShouldNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("name of"))
+		})
+	})
+})
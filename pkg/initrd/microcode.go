@@ -0,0 +1,364 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package initrd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+)
+
+const (
+	MicrocodeModeNone     = "none"
+	MicrocodeModePrepend  = "prepend"
+	MicrocodeModeSeparate = "separate"
+
+	CpuVendorIntel = "GenuineIntel"
+	CpuVendorAmd   = "AuthenticAMD"
+
+	DefaultCpuinfoFile  = "/proc/cpuinfo"
+	DefaultFirmwareDir  = "/lib/firmware"
+	microcodeArchiveDir = "kernel/x86/microcode"
+)
+
+var intelUcodeRegex = regexp.MustCompile(`^[0-9a-f]{2}-[0-9a-f]{2}-[0-9a-f]{2}$`)
+
+// Early microcode stage of the initrd pipeline. After the build of
+// the initrd image the microcode of the CPU vendor is prepended to
+// the initrd image or it's kept as a separate image in the boot
+// directory (intel-uc.img, amd-uc.img) loaded by the bootloader.
+// An existing image in the boot directory is used instead of build
+// a new archive from the firmware directory.
+type MicrocodeStage struct {
+	Mode        string
+	CpuinfoFile string
+	FirmwareDir string
+	DryRun      bool
+
+	mutex sync.Mutex
+}
+
+func NewMicrocodeStage(mode string, dryRun bool) *MicrocodeStage {
+	return &MicrocodeStage{
+		Mode:        mode,
+		CpuinfoFile: DefaultCpuinfoFile,
+		FirmwareDir: DefaultFirmwareDir,
+		DryRun:      dryRun,
+	}
+}
+
+func ValidMicrocodeMode(mode string) bool {
+	switch mode {
+	case "", MicrocodeModeNone, MicrocodeModePrepend, MicrocodeModeSeparate:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *MicrocodeStage) IsEnabled() bool {
+	return m != nil && m.Mode != "" && m.Mode != MicrocodeModeNone
+}
+
+// Return the CPU vendor from the vendor_id field of the cpuinfo file.
+func DetectCpuVendor(cpuinfoFile string) (string, error) {
+	f, err := os.Open(cpuinfoFile)
+	if err != nil {
+		return "", fmt.Errorf("Error on read %s: %s", cpuinfoFile, err.Error())
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if found && strings.TrimSpace(key) == "vendor_id" {
+			return strings.TrimSpace(value), nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", fmt.Errorf("No vendor_id found in %s", cpuinfoFile)
+}
+
+// Return the filename of the separate microcode image of the vendor.
+func MicrocodeImageName(vendor string) string {
+	return kernelspecs.MicrocodeImageNames[vendor]
+}
+
+// Return the microcode files of the vendor in the firmware directory.
+func microcodeFirmwareFiles(vendor, firmwareDir string) ([]string, error) {
+	var dir string
+	var match func(string) bool
+
+	switch vendor {
+	case CpuVendorIntel:
+		dir = filepath.Join(firmwareDir, "intel-ucode")
+		match = intelUcodeRegex.MatchString
+	case CpuVendorAmd:
+		dir = filepath.Join(firmwareDir, "amd-ucode")
+		match = func(name string) bool {
+			return strings.HasPrefix(name, "microcode_amd") &&
+				strings.HasSuffix(name, ".bin")
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported CPU vendor %s", vendor)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("Error on read microcode directory %s: %s",
+			dir, err.Error())
+	}
+
+	ans := []string{}
+	for _, e := range entries {
+		if e.Type().IsRegular() && match(e.Name()) {
+			ans = append(ans, filepath.Join(dir, e.Name()))
+		}
+	}
+
+	if len(ans) == 0 {
+		return nil, fmt.Errorf("No microcode files found in %s", dir)
+	}
+
+	sort.Strings(ans)
+
+	return ans, nil
+}
+
+// Build the early cpio archive with the microcode files of the vendor.
+func BuildMicrocodeArchive(vendor, firmwareDir string) ([]byte, error) {
+	files, err := microcodeFirmwareFiles(vendor, firmwareDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ucode bytes.Buffer
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		ucode.Write(data)
+	}
+
+	var buf bytes.Buffer
+	w := NewCpioWriter(&buf)
+
+	for _, dir := range []string{"kernel", "kernel/x86", microcodeArchiveDir} {
+		err = w.WriteHeader(&CpioHeader{Name: dir, Mode: CpioModeDir | 0755, Nlink: 2})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = w.WriteHeader(&CpioHeader{
+		Name: microcodeArchiveDir + "/" + vendor + ".bin",
+		Mode: CpioModeRegular | 0644,
+		Size: int64(ucode.Len()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = w.Write(ucode.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Return true if the first archive of the initrd image is an
+// uncompressed archive with the early microcode.
+func HasEarlyMicrocode(file string) (bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	magic, err := br.Peek(6)
+	if err != nil {
+		return false, err
+	}
+
+	c, err := DetectCompression(magic)
+	if err != nil || c != CompressionNone {
+		return false, err
+	}
+
+	cr := NewCpioReader(br)
+	for {
+		h, err := cr.Next()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if strings.HasPrefix(h.Name, microcodeArchiveDir+"/") {
+			return true, nil
+		}
+	}
+}
+
+// Prepend the early microcode archive to the initrd image. The
+// image is replaced with a temporary file to avoid a truncated image.
+func PrependMicrocode(initrdFile string, archive []byte) error {
+	data, err := os.ReadFile(initrdFile)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(initrdFile)
+	if err != nil {
+		return err
+	}
+
+	tmp := initrdFile + ".microcode"
+	err = os.WriteFile(tmp, append(archive, data...), info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("Error on write %s: %s", tmp, err.Error())
+	}
+
+	return os.Rename(tmp, initrdFile)
+}
+
+// Return the microcode archive of the vendor. The separate image
+// of the boot directory is used if available and if it isn't older
+// than the microcode files of the firmware directory.
+func (m *MicrocodeStage) getArchive(vendor, bootDir string) ([]byte, bool, error) {
+	image := filepath.Join(bootDir, MicrocodeImageName(vendor))
+	info, err := os.Stat(image)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, false, err
+	}
+
+	files, ferr := microcodeFirmwareFiles(vendor, m.FirmwareDir)
+
+	if err == nil {
+		updated := false
+		for _, f := range files {
+			finfo, err := os.Stat(f)
+			if err == nil && finfo.ModTime().After(info.ModTime()) {
+				updated = true
+				break
+			}
+		}
+
+		if !updated {
+			data, err := os.ReadFile(image)
+			return data, true, err
+		}
+	}
+
+	if ferr != nil {
+		return nil, false, ferr
+	}
+
+	data, err := BuildMicrocodeArchive(vendor, m.FirmwareDir)
+	return data, false, err
+}
+
+// Run the microcode stage for the initrd image of the kernel files and
+// record the microcode image selected in the kernel files.
+func (m *MicrocodeStage) Apply(kf *kernelspecs.KernelFiles, bootDir string, w io.Writer) error {
+	if !m.IsEnabled() {
+		return nil
+	}
+
+	if !ValidMicrocodeMode(m.Mode) {
+		return fmt.Errorf("Unsupported microcode mode %s", m.Mode)
+	}
+
+	initrdFile, err := getInitrdFile(kf, bootDir)
+	if err != nil {
+		return err
+	}
+
+	vendor, err := DetectCpuVendor(m.CpuinfoFile)
+	if err != nil {
+		return err
+	}
+
+	name := MicrocodeImageName(vendor)
+	if name == "" {
+		fmt.Fprintln(w, fmt.Sprintf(
+			"WARN: No early microcode available for CPU vendor %s.", vendor))
+		return nil
+	}
+
+	// The stage is executed by the parallel builds.
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	archive, existing, err := m.getArchive(vendor, bootDir)
+	if err != nil {
+		return fmt.Errorf("Error on prepare microcode: %s", err.Error())
+	}
+
+	if m.Mode == MicrocodeModeSeparate {
+		image := filepath.Join(bootDir, name)
+		if !existing {
+			if m.DryRun {
+				fmt.Fprintln(w, "[dry-run mode] creating microcode image "+image)
+			} else {
+				fmt.Fprintln(w, fmt.Sprintf("Creating microcode image %s.", image))
+				err = os.WriteFile(image, archive, 0644)
+				if err != nil {
+					return fmt.Errorf("Error on write %s: %s", image, err.Error())
+				}
+			}
+		}
+
+		kf.Microcode = &kernelspecs.MicrocodeImage{
+			Filename: name,
+			Vendor:   vendor,
+		}
+		return nil
+	}
+
+	kf.Microcode = &kernelspecs.MicrocodeImage{
+		Vendor:   vendor,
+		Embedded: true,
+	}
+
+	if m.DryRun {
+		fmt.Fprintln(w, "[dry-run mode] prepending microcode to "+initrdFile)
+		return nil
+	}
+
+	present, err := HasEarlyMicrocode(initrdFile)
+	if err != nil {
+		return fmt.Errorf("Error on read %s: %s", initrdFile, err.Error())
+	}
+	if present {
+		fmt.Fprintln(w, fmt.Sprintf("Early microcode already available in %s.", initrdFile))
+		return nil
+	}
+
+	fmt.Fprintln(w, fmt.Sprintf("Prepending microcode to %s.", initrdFile))
+
+	return PrependMicrocode(initrdFile, archive)
+}
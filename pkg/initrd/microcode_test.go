@@ -0,0 +1,174 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package initrd_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/macaroni-os/macaronictl/pkg/initrd"
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Initrd Microcode Test", func() {
+
+	// Prepare the cpuinfo, the firmware files and an initrd
+	// image without early microcode.
+	prepare := func(dir string) (*MicrocodeStage, *kernelspecs.KernelFiles) {
+		cpuinfo := filepath.Join(dir, "cpuinfo")
+		Expect(os.WriteFile(cpuinfo, []byte(
+			"processor\t: 0\nvendor_id\t: GenuineIntel\ncpu family\t: 6\n"),
+			0644)).Should(BeNil())
+
+		ucodeDir := filepath.Join(dir, "firmware", "intel-ucode")
+		Expect(os.MkdirAll(ucodeDir, 0755)).Should(BeNil())
+		Expect(os.WriteFile(filepath.Join(ucodeDir, "06-8e-09"),
+			[]byte("ucode1"), 0644)).Should(BeNil())
+		Expect(os.WriteFile(filepath.Join(ucodeDir, "06-9e-0a"),
+			[]byte("ucode2"), 0644)).Should(BeNil())
+		Expect(os.WriteFile(filepath.Join(ucodeDir, "README"),
+			[]byte("readme"), 0644)).Should(BeNil())
+
+		t := &kernelspecs.KernelType{
+			Name:         "Macaroni Vanilla",
+			KernelPrefix: "kernel",
+			InitrdPrefix: "initramfs",
+			Suffix:       "macaroni",
+			Type:         "vanilla",
+		}
+		kf := kernelspecs.NewKernelFiles(t)
+		kf.Kernel = kernelspecs.NewKernelImage()
+		kf.Kernel.SetPrefix("kernel")
+		kf.Kernel.SetType("vanilla")
+		kf.Kernel.SetArch("x86_64")
+		kf.Kernel.SetVersion("6.1.12")
+		kf.Kernel.SetSuffix("macaroni")
+		kf.Initrd = kernelspecs.NewInitrdImage()
+		kf.Initrd.SetPrefix("initramfs")
+		kf.Initrd.SetKernelType("vanilla")
+		kf.Initrd.SetArch("x86_64")
+		kf.Initrd.SetVersion("6.1.12")
+		kf.Initrd.SetSuffix("macaroni")
+
+		main := bytes.NewBuffer(nil)
+		z := gzip.NewWriter(main)
+		z.Write(newcArchive([]cpioFile{
+			{Name: "etc", Mode: 0040755},
+			{Name: "etc/os-release", Mode: 0100644, Data: "ID=macaroni\n"},
+		}))
+		z.Close()
+		Expect(os.WriteFile(filepath.Join(dir, kf.Initrd.GenerateFilename()),
+			main.Bytes(), 0644)).Should(BeNil())
+
+		stage := NewMicrocodeStage(MicrocodeModePrepend, false)
+		stage.CpuinfoFile = cpuinfo
+		stage.FirmwareDir = filepath.Join(dir, "firmware")
+
+		return stage, kf
+	}
+
+	Context("Microcode stage", func() {
+
+		It("Detect CPU vendor", func() {
+			tmpdir, err := os.MkdirTemp("", "macaronictl-microcode")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+
+			stage, _ := prepare(tmpdir)
+			vendor, err := DetectCpuVendor(stage.CpuinfoFile)
+			Expect(err).Should(BeNil())
+			Expect(vendor).To(Equal(CpuVendorIntel))
+			Expect(MicrocodeImageName(vendor)).To(Equal("intel-uc.img"))
+		})
+
+		It("Prepend microcode", func() {
+			tmpdir, err := os.MkdirTemp("", "macaronictl-microcode")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+
+			stage, kf := prepare(tmpdir)
+			Expect(stage.Apply(kf, tmpdir, io.Discard)).Should(BeNil())
+			Expect(kf.Microcode).ToNot(BeNil())
+			Expect(kf.Microcode.Embedded).To(BeTrue())
+			Expect(kf.GetMicrocodeFile()).To(Equal(""))
+
+			initrdFile := filepath.Join(tmpdir, kf.Initrd.GenerateFilename())
+			present, err := HasEarlyMicrocode(initrdFile)
+			Expect(err).Should(BeNil())
+			Expect(present).To(BeTrue())
+
+			entries, archives, err := ListInitrdImage(initrdFile)
+			Expect(err).Should(BeNil())
+			Expect(len(archives)).To(Equal(2))
+			Expect(archives[1].Compression).To(Equal(CompressionGzip))
+			Expect(entries[3].Name).To(Equal("kernel/x86/microcode/GenuineIntel.bin"))
+			Expect(entries[3].Size).To(Equal(int64(12)))
+			Expect(entries[5].Name).To(Equal("etc/os-release"))
+
+			// The microcode isn't prepended twice.
+			Expect(stage.Apply(kf, tmpdir, io.Discard)).Should(BeNil())
+			_, archives, err = ListInitrdImage(initrdFile)
+			Expect(err).Should(BeNil())
+			Expect(len(archives)).To(Equal(2))
+		})
+
+		It("Separate microcode", func() {
+			tmpdir, err := os.MkdirTemp("", "macaronictl-microcode")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+
+			stage, kf := prepare(tmpdir)
+			stage.Mode = MicrocodeModeSeparate
+			Expect(stage.Apply(kf, tmpdir, io.Discard)).Should(BeNil())
+			Expect(kf.GetMicrocodeFile()).To(Equal("intel-uc.img"))
+
+			entries, archives, err := ListInitrdImage(filepath.Join(tmpdir, "intel-uc.img"))
+			Expect(err).Should(BeNil())
+			Expect(len(archives)).To(Equal(1))
+			Expect(len(entries)).To(Equal(4))
+
+			present, err := HasEarlyMicrocode(
+				filepath.Join(tmpdir, kf.Initrd.GenerateFilename()))
+			Expect(err).Should(BeNil())
+			Expect(present).To(BeFalse())
+		})
+
+		It("Rebuild outdated microcode image", func() {
+			tmpdir, err := os.MkdirTemp("", "macaronictl-microcode")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+
+			stage, kf := prepare(tmpdir)
+			stage.Mode = MicrocodeModeSeparate
+			Expect(stage.Apply(kf, tmpdir, io.Discard)).Should(BeNil())
+
+			image := filepath.Join(tmpdir, "intel-uc.img")
+
+			// The image isn't rebuilt without firmware updates.
+			Expect(os.WriteFile(image, []byte("custom"), 0644)).Should(BeNil())
+			Expect(stage.Apply(kf, tmpdir, io.Discard)).Should(BeNil())
+			data, err := os.ReadFile(image)
+			Expect(err).Should(BeNil())
+			Expect(string(data)).To(Equal("custom"))
+
+			past := time.Now().Add(-time.Hour)
+			Expect(os.Chtimes(image, past, past)).Should(BeNil())
+			Expect(stage.Apply(kf, tmpdir, io.Discard)).Should(BeNil())
+
+			entries, _, err := ListInitrdImage(image)
+			Expect(err).Should(BeNil())
+			Expect(len(entries)).To(Equal(4))
+		})
+
+	})
+
+})
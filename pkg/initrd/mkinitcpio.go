@@ -0,0 +1,65 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package initrd
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+)
+
+type MkinitcpioBuilder struct {
+	DryRun bool
+	Args   string
+	Rootfs string
+}
+
+func NewMkinitcpioBuilder(args string, dryRun bool) *MkinitcpioBuilder {
+	ans := &MkinitcpioBuilder{
+		Args:   args,
+		DryRun: dryRun,
+	}
+
+	if os.Getenv("MACARONICTL_MKINITCPIO_ARGS") != "" {
+		ans.Args = os.Getenv("MACARONICTL_MKINITCPIO_ARGS")
+	}
+
+	return ans
+}
+
+func (m *MkinitcpioBuilder) GetName() string { return "mkinitcpio" }
+
+func (m *MkinitcpioBuilder) SetRootfs(rootfs string) { m.Rootfs = rootfs }
+
+func (m *MkinitcpioBuilder) GetCommand(kf *kernelspecs.KernelFiles, bootDir string) (string, []string, error) {
+	initrdFile, err := getInitrdFile(kf, bootDir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	args := strings.Fields(m.Args)
+	args = append(args, []string{
+		"-k", kf.Kernel.GetRelease(), "-g", initrdFile,
+	}...)
+
+	return utils.TryResolveBinaryAbsPath("mkinitcpio"), args, nil
+}
+
+func (m *MkinitcpioBuilder) Build(kf *kernelspecs.KernelFiles, bootDir string) error {
+	return m.BuildWithWriter(kf, bootDir, os.Stdout)
+}
+
+func (m *MkinitcpioBuilder) BuildWithWriter(kf *kernelspecs.KernelFiles, bootDir string, w io.Writer) error {
+	mkinitcpio, args, err := m.GetCommand(kf, utils.RootfsRelPath(m.Rootfs, bootDir))
+	if err != nil {
+		return err
+	}
+
+	return runBuildCommand(m.GetName(), mkinitcpio, args, args[len(args)-1],
+		m.Rootfs, m.DryRun, w)
+}
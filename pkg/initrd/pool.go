@@ -0,0 +1,69 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package initrd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+)
+
+type BuildResult struct {
+	KernelFiles *kernelspecs.KernelFiles
+	Output      string
+	Error       error
+}
+
+// Build the initrd images of the kernels in input with a pool
+// of jobs workers. The builder of every kernel is retrieved by
+// the selector. The output of every build is captured and printed
+// only when the build is completed to avoid interleaved logs.
+// The results are returned with the same order of the input files.
+func BuildAll(s *InitrdBuilderSelector, files []*kernelspecs.KernelFiles,
+	bootDir string, jobs int) []*BuildResult {
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+
+	if jobs <= 0 {
+		jobs = 1
+	}
+
+	ans := make([]*BuildResult, len(files))
+	sem := make(chan struct{}, jobs)
+
+	for idx := range files {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			var out bytes.Buffer
+			err := s.BuildWithWriter(files[i], bootDir, &out)
+
+			ans[i] = &BuildResult{
+				KernelFiles: files[i],
+				Output:      out.String(),
+				Error:       err,
+			}
+
+			mutex.Lock()
+			fmt.Fprint(os.Stdout, out.String())
+			if err != nil && out.Len() > 0 {
+				fmt.Fprintln(os.Stdout)
+			}
+			mutex.Unlock()
+		}(idx)
+	}
+
+	wg.Wait()
+
+	return ans
+}
@@ -1,5 +1,5 @@
 /*
-Copyright © 2021 Macaroni OS Linux
+Copyright © 2021-2026 Macaroni OS Linux
 See AUTHORS and LICENSE for the license details and contributors.
 */
 package kernel
@@ -32,6 +32,8 @@ func ReadBootDir(bootdir string, supportedTypes []kernelspecs.KernelType) (*kern
 	}
 
 	ans := kernelspecs.NewBootFiles(bootdir)
+	// Separate early microcode image loaded by the bootloader.
+	var microcode *kernelspecs.MicrocodeImage = nil
 
 	for _, t := range supportedTypes {
 		r := t.GetRegex()
@@ -53,7 +55,7 @@ func ReadBootDir(bootdir string, supportedTypes []kernelspecs.KernelType) (*kern
 
 		// Retrieve bzImage link
 		if file.Name() == "bzImage" && (file.Mode()&os.ModeSymlink != 0) {
-			linkedFile, err := os.Readlink(file.Name())
+			linkedFile, err := os.Readlink(filepath.Join(bootdir, file.Name()))
 			if err == nil {
 				ans.BzImageLink = linkedFile
 			}
@@ -61,68 +63,171 @@ func ReadBootDir(bootdir string, supportedTypes []kernelspecs.KernelType) (*kern
 
 		// Retrive Initrd link
 		if file.Name() == "Initrd" && (file.Mode()&os.ModeSymlink != 0) {
-			linkedFile, err := os.Readlink(file.Name())
+			linkedFile, err := os.Readlink(filepath.Join(bootdir, file.Name()))
 			if err == nil {
 				ans.InitrdLink = linkedFile
 			}
 		}
 
-		for _, t := range supportedTypes {
-			if t.GetRegex().MatchString(file.Name()) {
+		// Retrieve fallback links
+		if file.Name() == "bzImage.old" && (file.Mode()&os.ModeSymlink != 0) {
+			linkedFile, err := os.Readlink(filepath.Join(bootdir, file.Name()))
+			if err == nil {
+				ans.BzImageOldLink = linkedFile
+			}
+		}
+
+		if file.Name() == "Initrd.old" && (file.Mode()&os.ModeSymlink != 0) {
+			linkedFile, err := os.Readlink(filepath.Join(bootdir, file.Name()))
+			if err == nil {
+				ans.InitrdOldLink = linkedFile
+			}
+		}
+
+		if vendor := kernelspecs.MicrocodeImageVendor(file.Name()); vendor != "" {
+			if microcode == nil {
+				microcode = &kernelspecs.MicrocodeImage{
+					Filename: file.Name(),
+					Vendor:   vendor,
+				}
+			} else {
+				log.DebugC("Ignoring microcode image", file.Name())
+			}
+			continue
+		}
+
+		err = readBootFile(ans, bootdir, file.Name(), supportedTypes)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-				log.DebugC("File", file.Name(), "match type", t.GetName())
+	// The files of the templates with directories, for example
+	// {version}/vmlinuz, are under the subdirectories.
+	for _, t := range supportedTypes {
+		for _, pattern := range t.GetTemplatesGlobs() {
+			matches, err := filepath.Glob(filepath.Join(bootdir, pattern))
+			if err != nil {
+				return nil, fmt.Errorf("Error on search files of kernel type %s: %s",
+					t.GetName(), err.Error())
+			}
 
-				isInirtd, err := t.IsInitrdFile(file.Name())
+			for _, m := range matches {
+				name, err := filepath.Rel(bootdir, m)
 				if err != nil {
-					return nil, errors.New(
-						fmt.Sprintf("Error on check if the file %s is an initrd file: %s",
-							file.Name(), err.Error(),
-						))
+					return nil, err
+				}
+				if !isBootDirFileToRead(ans, name) {
+					continue
 				}
+				log.DebugC("Analyzing file", name, "...")
 
-				if isInirtd {
-					// Initrd image
-					iimage, err := kernelspecs.NewInitrdImageFromFile(&t, file.Name())
-					if err != nil {
-						return nil, errors.New(
-							fmt.Sprintf("Error on parse file %s: %s",
-								file.Name(), err.Error(),
-							))
-					}
+				err = readBootFile(ans, bootdir, name, supportedTypes)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
 
-					err = ans.AddInitrdImage(iimage, &t)
-					if err != nil {
-						return nil, err
-					}
+	pairCorrectedImages(ans)
 
-				} else {
-					// Kernel image
-					kimage, err := kernelspecs.NewKernelImageFromFile(&t, file.Name())
-					if err != nil {
-						return nil, errors.New(
-							fmt.Sprintf("Error on parse file %s: %s",
-								file.Name(), err.Error(),
-							))
-					}
+	if microcode != nil {
+		for _, kf := range ans.Files {
+			if kf.Microcode == nil {
+				ucode := *microcode
+				kf.Microcode = &ucode
+			}
+		}
+	}
+
+	return ans, nil
+}
+
+// Analyze the file of the boot directory with the supported types and
+// add it to the boot files. The file is the path relative to the boot
+// directory.
+func readBootFile(ans *kernelspecs.BootFiles, bootdir, file string,
+	supportedTypes []kernelspecs.KernelType) error {
+	log := logger.GetDefaultLogger()
+
+	for _, t := range supportedTypes {
+		if t.GetRegex().MatchString(file) {
+
+			log.DebugC("File", file, "match type", t.GetName())
 
-					err = ans.AddKernelImage(kimage, &t)
-					if err != nil {
-						return nil, err
+			isInirtd, err := t.IsInitrdFile(file)
+			if err != nil {
+				return errors.New(
+					fmt.Sprintf("Error on check if the file %s is an initrd file: %s",
+						file, err.Error(),
+					))
+			}
+
+			if isInirtd {
+				// Initrd image
+				iimage, err := kernelspecs.NewInitrdImageFromFile(&t, file)
+				if err != nil {
+					return errors.New(
+						fmt.Sprintf("Error on parse file %s: %s",
+							file, err.Error(),
+						))
+				}
+
+				err = ans.AddInitrdImage(iimage, &t)
+				if err != nil {
+					return err
+				}
+
+			} else {
+				// Kernel image
+				kimage, err := kernelspecs.NewKernelImageFromFile(&t, file)
+				if err != nil {
+					return errors.New(
+						fmt.Sprintf("Error on parse file %s: %s",
+							file, err.Error(),
+						))
+				}
+
+				release, err := ReadKernelRelease(filepath.Join(bootdir, file))
+				if err == nil {
+					kimage.SetHeaderRelease(release)
+					if kimage.HasReleaseMismatch() {
+						log.DebugC("File", file, "contains the kernel release", release)
 					}
+				} else {
+					log.DebugC("Error on read kernel release of", file, ":", err.Error())
 				}
 
-				//fmt.Println("Read file ", file.Name())
-				goto nextFile
+				err = ans.AddKernelImage(kimage, &t)
+				if err != nil {
+					return err
+				}
 			}
+
+			return nil
 		}
+	}
+
+	return nil
+}
 
-	nextFile:
+// Return false if the file is already analyzed or it's not a regular file.
+func isBootDirFileToRead(ans *kernelspecs.BootFiles, file string) bool {
+	for _, kf := range ans.Files {
+		if (kf.Kernel != nil && kf.Kernel.GetFilename() == file) ||
+			(kf.Initrd != nil && kf.Initrd.GetFilename() == file) {
+			return false
+		}
 	}
 
-	return ans, nil
+	info, err := os.Stat(filepath.Join(ans.Dir, file))
+	return err == nil && info.Mode().IsRegular()
 }
 
-func GrubMkconfig(grubCfgFile string, dryRun bool) error {
+// Generate the grub.cfg file. With a rootfs different to / the
+// grub-mkconfig command is executed inside the rootfs with chroot.
+func GrubMkconfig(grubCfgFile, rootfs string, dryRun bool) error {
 	if grubCfgFile == "" {
 		return errors.New("Invalid grub config file path")
 	}
@@ -148,18 +253,18 @@ func GrubMkconfig(grubCfgFile string, dryRun bool) error {
 	// Try to resolve absolute path of grub-mkconfig
 	grubBinary := utils.TryResolveBinaryAbsPath("grub-mkconfig")
 	//grub-mkconfig -o ${MACARONICTL_TARGET}/boot/grub/grub.cfg
-	args := []string{
-		"-o", grubCfgFile,
-	}
+	args := utils.ChrootArgs(rootfs, []string{
+		grubBinary, "-o", utils.RootfsRelPath(rootfs, grubCfgFile),
+	})
 
 	if dryRun {
-		fmt.Println("[dry-run mode] command: " + grubBinary + " " + strings.Join(args, " "))
+		fmt.Println("[dry-run mode] command: " + strings.Join(args, " "))
 		return nil
 	}
 
 	fmt.Println(fmt.Sprintf("Creating grub config file %s...", grubCfgFile))
 
-	grubCommand := exec.Command(grubBinary, args...)
+	grubCommand := exec.Command(args[0], args[1:]...)
 	grubCommand.Stdout = os.Stdout
 	grubCommand.Stderr = os.Stderr
 
@@ -183,3 +288,39 @@ func GrubMkconfig(grubCfgFile string, dryRun bool) error {
 
 	return nil
 }
+
+// Pair the kernel images with the version corrected by the header
+// release with the initrd images with the same filename.
+func pairCorrectedImages(bootFiles *kernelspecs.BootFiles) {
+	paired := make(map[int]bool, 0)
+
+	for _, kf := range bootFiles.Files {
+		if kf.Kernel == nil || kf.Initrd != nil || kf.Type == nil {
+			continue
+		}
+
+		initrdFile := kf.Type.GetInitrdFilename(kf.Kernel.GetFilename())
+
+		for idx, f := range bootFiles.Files {
+			if f.Kernel == nil && f.Initrd != nil && f.Initrd.GetFilename() == initrdFile {
+				kf.Initrd = f.Initrd
+				kf.Initrd.SetVersion(kf.Kernel.GetVersion())
+				kf.Initrd.SetSuffix(kf.Kernel.GetSuffix())
+				paired[idx] = true
+				break
+			}
+		}
+	}
+
+	if len(paired) == 0 {
+		return
+	}
+
+	files := []*kernelspecs.KernelFiles{}
+	for idx, f := range bootFiles.Files {
+		if _, present := paired[idx]; !present {
+			files = append(files, f)
+		}
+	}
+	bootFiles.Files = files
+}
@@ -0,0 +1,292 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kernel
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+)
+
+const (
+	CmdlineFile      = "/etc/kernel/cmdline"
+	CmdlineDropinDir = "/etc/kernel/cmdline.d"
+)
+
+// Kernel command line with the base options of /etc/kernel/cmdline and
+// the overrides of the drop-ins of the kernel profiles and versions:
+//
+//	/etc/kernel/cmdline.d/profile-<profile>.conf
+//	/etc/kernel/cmdline.d/version-<version>.conf
+//
+// The options of a drop-in replace all the options of the base with the
+// same key and an option with the "-" prefix removes the option from the base.
+type Cmdline struct {
+	Rootfs string
+}
+
+func NewCmdline(rootfs string) *Cmdline {
+	return &Cmdline{Rootfs: rootfs}
+}
+
+// Retrieve the default kernel command line from /etc/kernel/cmdline
+// of the rootfs or from the command line of the running kernel.
+// The command line of the running kernel is not used for a chroot.
+func DefaultCmdline(rootfs string) string {
+	content, err := os.ReadFile(utils.RootfsPath(rootfs, CmdlineFile))
+	if err == nil {
+		return strings.TrimSpace(string(content))
+	}
+
+	if utils.IsChrootRootfs(rootfs) {
+		return ""
+	}
+
+	// Fallback to the options of the running kernel.
+	content, err = os.ReadFile("/proc/cmdline")
+	if err != nil {
+		return ""
+	}
+
+	opts := []string{}
+	for _, o := range strings.Fields(string(content)) {
+		if strings.HasPrefix(o, "BOOT_IMAGE=") || strings.HasPrefix(o, "initrd=") {
+			continue
+		}
+		opts = append(opts, o)
+	}
+
+	return strings.Join(opts, " ")
+}
+
+// Return the key used in the drop-in file of the profile.
+// Example: Macaroni Zen Kernel -> macaroni-zen-kernel
+func CmdlineProfileKey(profile string) string {
+	return strings.ToLower(strings.Join(strings.Fields(profile), "-"))
+}
+
+// Return the key of the option: the option name without the value.
+func cmdlineOptionKey(opt string) string {
+	opt = strings.TrimPrefix(opt, "-")
+	if idx := strings.Index(opt, "="); idx >= 0 {
+		return opt[:idx]
+	}
+	return opt
+}
+
+// Merge the options of the layer with the options in input. The options
+// of the layer replace all the options with the same key and an option
+// with the "-" prefix removes all the options with the key. The options
+// with the same key of the layer are kept (console=tty0 console=ttyS0).
+func MergeCmdlineOptions(opts, layer []string) []string {
+	keys := make(map[string]bool, 0)
+	for _, o := range layer {
+		keys[cmdlineOptionKey(o)] = true
+	}
+
+	ans := []string{}
+	for _, o := range opts {
+		if !keys[cmdlineOptionKey(o)] {
+			ans = append(ans, o)
+		}
+	}
+
+	for _, o := range layer {
+		if !strings.HasPrefix(o, "-") {
+			ans = append(ans, o)
+		}
+	}
+
+	return ans
+}
+
+// Add the options to the layer. The options already present are not
+// duplicated and the removal of the same key with the "-" prefix is
+// dropped. The options with the same key but another value are kept.
+func AddCmdlineOptions(layer, opts []string) []string {
+	for _, o := range opts {
+		key := cmdlineOptionKey(o)
+
+		present := false
+		ans := []string{}
+		for _, l := range layer {
+			if l == "-"+key && !strings.HasPrefix(o, "-") {
+				continue
+			}
+			if l == o {
+				present = true
+			}
+			ans = append(ans, l)
+		}
+
+		if !present {
+			ans = append(ans, o)
+		}
+		layer = ans
+	}
+
+	return layer
+}
+
+// Remove the options with the same key of the options in input.
+// In a drop-in the options not defined in the layer are disabled
+// with the "-" prefix.
+func RemoveCmdlineOptions(layer, opts []string, dropin bool) []string {
+	for _, o := range opts {
+		key := cmdlineOptionKey(o)
+
+		ans := []string{}
+		for _, l := range layer {
+			if cmdlineOptionKey(l) != key {
+				ans = append(ans, l)
+			}
+		}
+
+		if dropin && len(ans) == len(layer) {
+			ans = append(ans, "-"+key)
+		}
+		layer = ans
+	}
+
+	return layer
+}
+
+// Return the file of the layer. Without profile and version
+// it's the base file.
+func (c *Cmdline) GetLayerFile(profile, version string) string {
+	var file string
+	switch {
+	case version != "":
+		file = filepath.Join(CmdlineDropinDir, "version-"+version+".conf")
+	case profile != "":
+		file = filepath.Join(CmdlineDropinDir,
+			"profile-"+CmdlineProfileKey(profile)+".conf")
+	default:
+		file = CmdlineFile
+	}
+
+	return utils.RootfsPath(c.Rootfs, file)
+}
+
+// Read the options of the layer. For the base layer without
+// /etc/kernel/cmdline it's used the default command line.
+func (c *Cmdline) ReadLayer(profile, version string) ([]string, error) {
+	if profile == "" && version == "" {
+		return strings.Fields(DefaultCmdline(c.Rootfs)), nil
+	}
+
+	file := c.GetLayerFile(profile, version)
+	content, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("Error on read %s: %s", file, err.Error())
+	}
+
+	return strings.Fields(string(content)), nil
+}
+
+// Write the options of the layer. The drop-in without options is removed.
+func (c *Cmdline) WriteLayer(profile, version string, opts []string) error {
+	file := c.GetLayerFile(profile, version)
+
+	if len(opts) == 0 && (profile != "" || version != "") {
+		err := os.Remove(file)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	err := os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return fmt.Errorf("Error on create directory %s: %s",
+			filepath.Dir(file), err.Error())
+	}
+
+	return os.WriteFile(file, []byte(strings.Join(opts, " ")+"\n"), 0644)
+}
+
+// Resolve the command line of the profile and the version with
+// the base options and the overrides of the drop-ins.
+func (c *Cmdline) Resolve(profile, version string) (string, error) {
+	opts, err := c.ReadLayer("", "")
+	if err != nil {
+		return "", err
+	}
+
+	if profile != "" {
+		layer, err := c.ReadLayer(profile, "")
+		if err != nil {
+			return "", err
+		}
+		opts = MergeCmdlineOptions(opts, layer)
+	}
+
+	if version != "" {
+		layer, err := c.ReadLayer("", version)
+		if err != nil {
+			return "", err
+		}
+		opts = MergeCmdlineOptions(opts, layer)
+	}
+
+	return strings.Join(opts, " "), nil
+}
+
+// Resolve the command line of the kernel files. On error it's
+// returned the base command line.
+func (c *Cmdline) ResolveKernelFiles(kf *kernelspecs.KernelFiles) string {
+	profile := ""
+	if kf.Type != nil {
+		profile = kf.Type.GetName()
+	}
+
+	version := ""
+	if kf.Kernel != nil {
+		version = kf.Kernel.GetVersion()
+	} else if kf.Initrd != nil {
+		version = kf.Initrd.GetVersion()
+	}
+
+	ans, err := c.Resolve(profile, version)
+	if err != nil {
+		return DefaultCmdline(c.Rootfs)
+	}
+	return ans
+}
+
+// Set the GRUB_CMDLINE_LINUX option of the grub defaults file.
+// The grub-mkconfig tool doesn't support options for single kernels
+// and so it's used the base command line.
+func SetGrubDefaultCmdline(file, cmdline string) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("Error on read %s: %s", file, err.Error())
+	}
+
+	value := strings.ReplaceAll(strings.ReplaceAll(cmdline, `\`, `\\`), `"`, `\"`)
+	option := fmt.Sprintf(`GRUB_CMDLINE_LINUX="%s"`, value)
+
+	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	replaced := false
+	for idx, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "GRUB_CMDLINE_LINUX=") {
+			lines[idx] = option
+			replaced = true
+		}
+	}
+
+	if !replaced {
+		lines = append(lines, option)
+	}
+
+	return os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
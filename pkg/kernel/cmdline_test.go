@@ -0,0 +1,90 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kernel_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/macaroni-os/macaronictl/pkg/kernel"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Kernel Cmdline", func() {
+
+	Context("Options", func() {
+
+		It("Merge and remove", func() {
+			opts := MergeCmdlineOptions(
+				[]string{"root=/dev/sda2", "quiet", "console=tty0"},
+				[]string{"console=ttyS0", "-quiet", "nomodeset"})
+			Expect(opts).To(Equal([]string{"root=/dev/sda2", "console=ttyS0", "nomodeset"}))
+
+			opts = MergeCmdlineOptions(
+				[]string{"root=/dev/sda2", "console=tty0", "console=ttyS0,115200"},
+				[]string{"quiet"})
+			Expect(opts).To(Equal([]string{"root=/dev/sda2", "console=tty0",
+				"console=ttyS0,115200", "quiet"}))
+
+			opts = MergeCmdlineOptions(
+				[]string{"root=/dev/sda2", "console=tty0", "console=ttyS0,115200"},
+				[]string{"-console"})
+			Expect(opts).To(Equal([]string{"root=/dev/sda2"}))
+
+			Expect(AddCmdlineOptions([]string{"console=tty0", "-quiet"},
+				[]string{"console=ttyS0,115200", "console=tty0", "quiet"})).To(Equal(
+				[]string{"console=tty0", "console=ttyS0,115200", "quiet"}))
+
+			Expect(RemoveCmdlineOptions([]string{"quiet", "console=tty0"},
+				[]string{"console"}, false)).To(Equal([]string{"quiet"}))
+			Expect(RemoveCmdlineOptions([]string{"console=tty0"},
+				[]string{"quiet"}, true)).To(Equal([]string{"console=tty0", "-quiet"}))
+		})
+	})
+
+	Context("Drop-ins", func() {
+
+		It("Resolve profile and version", func() {
+			rootfs, err := os.MkdirTemp("", "macaronictl-cmdline")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(rootfs)
+
+			c := NewCmdline(rootfs)
+			Expect(c.WriteLayer("", "", []string{"root=/dev/sda2", "quiet"})).Should(BeNil())
+			Expect(c.WriteLayer("Macaroni Zen Kernel", "", []string{"mitigations=off"})).Should(BeNil())
+			Expect(c.WriteLayer("", "6.1.12", []string{"-quiet"})).Should(BeNil())
+
+			Expect(filepath.Join(rootfs, "etc/kernel/cmdline.d/profile-macaroni-zen-kernel.conf")).
+				Should(BeAnExistingFile())
+
+			Expect(c.Resolve("", "")).To(Equal("root=/dev/sda2 quiet"))
+			Expect(c.Resolve("Macaroni Zen Kernel", "6.1.12")).To(
+				Equal("root=/dev/sda2 mitigations=off"))
+			Expect(c.Resolve("Macaroni", "6.1.9")).To(Equal("root=/dev/sda2 quiet"))
+
+			Expect(c.WriteLayer("", "6.1.12", []string{})).Should(BeNil())
+			Expect(filepath.Join(rootfs, "etc/kernel/cmdline.d/version-6.1.12.conf")).
+				ShouldNot(BeAnExistingFile())
+		})
+
+		It("Grub defaults", func() {
+			tmpdir, err := os.MkdirTemp("", "macaronictl-cmdline")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+
+			file := filepath.Join(tmpdir, "grub")
+			Expect(os.WriteFile(file,
+				[]byte("GRUB_TIMEOUT=5\nGRUB_CMDLINE_LINUX=\"\"\n"), 0644)).Should(BeNil())
+			Expect(SetGrubDefaultCmdline(file, "root=/dev/sda2 quiet")).Should(BeNil())
+
+			content, err := os.ReadFile(file)
+			Expect(err).Should(BeNil())
+			Expect(string(content)).To(Equal(
+				"GRUB_TIMEOUT=5\nGRUB_CMDLINE_LINUX=\"root=/dev/sda2 quiet\"\n"))
+		})
+	})
+})
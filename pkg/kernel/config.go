@@ -0,0 +1,282 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kernel
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+	"github.com/macaroni-os/macaronictl/pkg/utils/compress"
+)
+
+const (
+	ikconfigStart = "IKCFG_ST"
+	ikconfigEnd   = "IKCFG_ED"
+
+	// Value used for the symbols "is not set"
+	KernelConfigNotSet = "n"
+
+	KernelConfigAdded   = "added"
+	KernelConfigRemoved = "removed"
+	KernelConfigChanged = "changed"
+)
+
+// Map of the CONFIG_* symbols with their value.
+type KernelConfig map[string]string
+
+type KernelConfigChange struct {
+	Symbol string `json:"symbol" yaml:"symbol"`
+	Change string `json:"change" yaml:"change"`
+	Old    string `json:"old,omitempty" yaml:"old,omitempty"`
+	New    string `json:"new,omitempty" yaml:"new,omitempty"`
+}
+
+type KernelConfigDiff struct {
+	Changes []*KernelConfigChange `json:"changes" yaml:"changes"`
+}
+
+func ParseKernelConfig(r io.Reader) (KernelConfig, error) {
+	ans := make(KernelConfig, 0)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, "# CONFIG_") && strings.HasSuffix(line, " is not set") {
+			symbol := strings.TrimSuffix(strings.TrimPrefix(line, "# "), " is not set")
+			ans[symbol] = KernelConfigNotSet
+			continue
+		}
+
+		if !strings.HasPrefix(line, "CONFIG_") {
+			continue
+		}
+
+		words := strings.SplitN(line, "=", 2)
+		if len(words) != 2 {
+			continue
+		}
+		ans[words[0]] = words[1]
+	}
+
+	return ans, scanner.Err()
+}
+
+// Return the symbols sorted.
+func (c KernelConfig) Symbols() []string {
+	ans := []string{}
+	for k := range c {
+		ans = append(ans, k)
+	}
+	sort.Strings(ans)
+	return ans
+}
+
+func (c KernelConfig) String() string {
+	ans := ""
+	for _, s := range c.Symbols() {
+		if c[s] == KernelConfigNotSet {
+			ans += fmt.Sprintf("# %s is not set\n", s)
+		} else {
+			ans += fmt.Sprintf("%s=%s\n", s, c[s])
+		}
+	}
+	return ans
+}
+
+// Return the differences between the two configurations. The symbols
+// not set and the symbols not present are considered equal.
+func DiffKernelConfig(oldConfig, newConfig KernelConfig) *KernelConfigDiff {
+	ans := &KernelConfigDiff{
+		Changes: []*KernelConfigChange{},
+	}
+
+	symbols := make(map[string]bool, 0)
+	for s := range oldConfig {
+		symbols[s] = true
+	}
+	for s := range newConfig {
+		symbols[s] = true
+	}
+
+	list := []string{}
+	for s := range symbols {
+		list = append(list, s)
+	}
+	sort.Strings(list)
+
+	for _, s := range list {
+		oldValue, inOld := oldConfig[s]
+		newValue, inNew := newConfig[s]
+
+		if !inOld {
+			oldValue = KernelConfigNotSet
+		}
+		if !inNew {
+			newValue = KernelConfigNotSet
+		}
+
+		if oldValue == newValue {
+			continue
+		}
+
+		change := &KernelConfigChange{Symbol: s, Old: oldValue, New: newValue}
+		switch {
+		case oldValue == KernelConfigNotSet:
+			change.Change = KernelConfigAdded
+			change.Old = ""
+		case newValue == KernelConfigNotSet:
+			change.Change = KernelConfigRemoved
+			change.New = ""
+		default:
+			change.Change = KernelConfigChanged
+		}
+
+		ans.Changes = append(ans.Changes, change)
+	}
+
+	return ans
+}
+
+func extractIKConfigBlob(data []byte) ([]byte, error) {
+	start := bytes.Index(data, []byte(ikconfigStart))
+	if start < 0 {
+		return nil, nil
+	}
+	data = data[start+len(ikconfigStart):]
+
+	end := bytes.Index(data, []byte(ikconfigEnd))
+	if end >= 0 {
+		data = data[:end]
+	}
+
+	z, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("Error on read IKCONFIG blob: %s", err.Error())
+	}
+	defer z.Close()
+	z.Multistream(false)
+
+	return io.ReadAll(z)
+}
+
+// Return the configuration of the IKCONFIG blob if it's a valid
+// kernel configuration.
+func parseIKConfigBlob(data []byte) []byte {
+	ans, err := extractIKConfigBlob(data)
+	if err != nil || ans == nil {
+		return nil
+	}
+
+	config, err := ParseKernelConfig(bytes.NewReader(ans))
+	if err != nil || len(config) == 0 {
+		return nil
+	}
+
+	return ans
+}
+
+// Extract the configuration embedded in the kernel image with
+// CONFIG_IKCONFIG. The image is decompressed when needed and the
+// first configuration that is parsed is returned.
+func ExtractIKConfig(file string) ([]byte, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// POST: uncompressed images (arm64 Image)
+	if ans := parseIKConfigBlob(data); ans != nil {
+		return ans, nil
+	}
+
+	// Try to decompress the payload of the image from every
+	// compression magic found.
+	magics := []compress.Magic{
+		{Compression: compress.CompressionGzip, Magic: []byte{0x1f, 0x8b, 0x08}},
+		{Compression: compress.CompressionXz, Magic: []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+		{Compression: compress.CompressionZstd, Magic: []byte{0x28, 0xb5, 0x2f, 0xfd}},
+		// Legacy format used by the kernel.
+		{Compression: compress.CompressionLz4, Magic: []byte{0x02, 0x21, 0x4c, 0x18}},
+	}
+
+	for _, m := range magics {
+		offset := 0
+		for {
+			idx := bytes.Index(data[offset:], m.Magic)
+			if idx < 0 {
+				break
+			}
+			offset += idx
+
+			r, err := compress.NewDecompressor(m.Compression, bytes.NewReader(data[offset:]))
+			if err == nil {
+				payload, _ := io.ReadAll(r)
+				r.Close()
+
+				if ans := parseIKConfigBlob(payload); ans != nil {
+					return ans, nil
+				}
+			}
+
+			offset += len(m.Magic)
+		}
+	}
+
+	return nil, errors.New("No IKCONFIG data found in the kernel image")
+}
+
+// Read the configuration of the kernel from the config file
+// of the boot directory or from the kernel image. The second
+// value returned is the source of the configuration.
+func ReadKernelConfig(kf *kernelspecs.KernelFiles, bootDir string) (KernelConfig, string, error) {
+	if kf == nil || kf.Kernel == nil {
+		return nil, "", errors.New("Invalid kernel file")
+	}
+
+	candidates := []string{
+		filepath.Join(bootDir, "config-"+kf.Kernel.GetRelease()),
+	}
+	if kf.Type != nil {
+		candidates = append(candidates, filepath.Join(bootDir,
+			"config"+strings.TrimPrefix(kf.Kernel.GetFilename(),
+				kf.Type.GetKernelPrefixSanitized())))
+	}
+
+	for _, f := range candidates {
+		if !utils.Exists(f) {
+			continue
+		}
+
+		fd, err := os.Open(f)
+		if err != nil {
+			return nil, f, err
+		}
+		defer fd.Close()
+
+		ans, err := ParseKernelConfig(fd)
+		return ans, f, err
+	}
+
+	kernelFile := filepath.Join(bootDir, kf.Kernel.GetFilename())
+	data, err := ExtractIKConfig(kernelFile)
+	if err != nil {
+		return nil, kernelFile, fmt.Errorf("No config file found: %s", err.Error())
+	}
+
+	ans, err := ParseKernelConfig(bytes.NewReader(data))
+	return ans, kernelFile, err
+}
@@ -0,0 +1,85 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kernel_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/macaroni-os/macaronictl/pkg/kernel"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func gzipData(data []byte) []byte {
+	buf := bytes.NewBuffer(nil)
+	z := gzip.NewWriter(buf)
+	z.Write(data)
+	z.Close()
+	return buf.Bytes()
+}
+
+func ikconfigBlob(config string) []byte {
+	return append(append([]byte("IKCFG_ST"), gzipData([]byte(config))...),
+		[]byte("IKCFG_ED")...)
+}
+
+var _ = Describe("Kernel Config Test", func() {
+
+	Context("Diff", func() {
+
+		It("Added, removed and changed symbols", func() {
+			oldConfig, err := ParseKernelConfig(strings.NewReader(`
+# Linux/x86 6.1.12 Kernel Configuration
+CONFIG_A=y
+CONFIG_B=m
+# CONFIG_C is not set
+CONFIG_D="foo"
+# CONFIG_E is not set
+`))
+			Expect(err).Should(BeNil())
+			Expect(len(oldConfig)).To(Equal(5))
+
+			newConfig, err := ParseKernelConfig(strings.NewReader(`
+CONFIG_A=y
+CONFIG_B=y
+CONFIG_C=y
+`))
+			Expect(err).Should(BeNil())
+
+			diff := DiffKernelConfig(oldConfig, newConfig)
+			Expect(diff.Changes).To(Equal([]*KernelConfigChange{
+				{Symbol: "CONFIG_B", Change: KernelConfigChanged, Old: "m", New: "y"},
+				{Symbol: "CONFIG_C", Change: KernelConfigAdded, New: "y"},
+				{Symbol: "CONFIG_D", Change: KernelConfigRemoved, Old: `"foo"`},
+			}))
+		})
+	})
+
+	Context("IKCONFIG", func() {
+
+		It("Compressed kernel image", func() {
+			tmpdir, err := os.MkdirTemp("", "macaronictl-config")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+
+			// The first payload has an IKCONFIG blob without configuration.
+			data := []byte("MZ\x00\x00header")
+			data = append(data, gzipData(ikconfigBlob("foo\n"))...)
+			data = append(data, gzipData(ikconfigBlob("CONFIG_A=y\n"))...)
+
+			file := filepath.Join(tmpdir, "kernel")
+			Expect(os.WriteFile(file, data, 0644)).Should(BeNil())
+
+			config, err := ExtractIKConfig(file)
+			Expect(err).Should(BeNil())
+			Expect(string(config)).To(Equal("CONFIG_A=y\n"))
+		})
+	})
+})
@@ -0,0 +1,80 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kernel
+
+import (
+	"sort"
+
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+)
+
+// Extra module installed for other kernel branches without
+// an equivalent package in the kernel branch.
+type MissingModule struct {
+	Name string `json:"name" yaml:"name"`
+	// Packages of the module installed for the other branches.
+	Installed []string `json:"installed" yaml:"installed"`
+}
+
+type ModulesCoverage struct {
+	Branch  string           `json:"branch" yaml:"branch"`
+	Type    string           `json:"type" yaml:"type"`
+	Covered []string         `json:"covered" yaml:"covered"`
+	Missing []*MissingModule `json:"missing" yaml:"missing"`
+}
+
+// Check which extra modules installed for other kernel branches have
+// a package with the same name available for the kernel branch.
+// The modules installed in the kernel branch are always covered.
+func NewModulesCoverage(branch, kType string,
+	installedMods, availableMods *specs.StonesPack) *ModulesCoverage {
+
+	ans := &ModulesCoverage{
+		Branch:  branch,
+		Type:    kType,
+		Covered: []string{},
+		Missing: []*MissingModule{},
+	}
+
+	// Only the modules of the kernel type cover the installed modules.
+	availables := make(map[string]bool, 0)
+	for _, s := range availableMods.Stones {
+		if moduleKernelType(s) == kType {
+			availables[s.Name] = true
+		}
+	}
+
+	installed := make(map[string][]string, 0)
+	for _, s := range installedMods.Stones {
+		t := moduleKernelType(s)
+		if t == kType && KernelBranch(s, t) == branch {
+			availables[s.Name] = true
+		}
+		installed[s.Name] = append(installed[s.Name], s.HumanReadableString())
+	}
+
+	names := []string{}
+	for name := range installed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if availables[name] {
+			ans.Covered = append(ans.Covered, name)
+			continue
+		}
+
+		sort.Strings(installed[name])
+		ans.Missing = append(ans.Missing, &MissingModule{
+			Name:      name,
+			Installed: installed[name],
+		})
+	}
+
+	return ans
+}
+
+func (c *ModulesCoverage) IsComplete() bool { return len(c.Missing) == 0 }
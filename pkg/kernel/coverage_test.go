@@ -0,0 +1,78 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kernel_test
+
+import (
+	. "github.com/macaroni-os/macaronictl/pkg/kernel"
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Kernel Modules Coverage", func() {
+
+	Context("Switch branch", func() {
+
+		installed := &specs.StonesPack{
+			Stones: []*specs.Stone{
+				newTestStone("kernel-5.15", "zfs-kmod", "2.1.5", nil),
+				newTestStone("kernel-5.15", "nvidia-kernel-modules", "470.82", nil),
+				newTestStone("kernel-6.1", "virtualbox-modules", "7.0.6", nil),
+			},
+		}
+
+		It("Missing modules", func() {
+			availables := &specs.StonesPack{
+				Stones: []*specs.Stone{
+					newTestStone("kernel-6.1", "zfs-kmod", "2.1.9", nil),
+				},
+			}
+
+			c := NewModulesCoverage("6.1", "vanilla", installed, availables)
+			Expect(c.IsComplete()).To(BeFalse())
+			Expect(c.Covered).To(Equal([]string{"virtualbox-modules", "zfs-kmod"}))
+			Expect(len(c.Missing)).To(Equal(1))
+			Expect(c.Missing[0].Name).To(Equal("nvidia-kernel-modules"))
+			Expect(c.Missing[0].Installed).To(Equal(
+				[]string{"kernel-5.15/nvidia-kernel-modules-470.82"}))
+		})
+
+		It("Complete coverage", func() {
+			availables := &specs.StonesPack{
+				Stones: []*specs.Stone{
+					newTestStone("kernel-6.1", "zfs-kmod", "2.1.9", nil),
+					newTestStone("kernel-6.1", "nvidia-kernel-modules", "525.89", nil),
+				},
+			}
+
+			c := NewModulesCoverage("6.1", "vanilla", installed, availables)
+			Expect(c.IsComplete()).To(BeTrue())
+		})
+
+		It("Compare only the modules of the same type", func() {
+			zfsZen := newTestStone("kernel-zen-6.1", "zfs-kmod", "2.1.9", nil)
+			zfsZen.Labels["kernel.type"] = "zen"
+
+			// The vanilla modules of the same branch don't cover the zen kernel.
+			c := NewModulesCoverage("6.1", "zen", installed, &specs.StonesPack{
+				Stones: []*specs.Stone{
+					zfsZen,
+					newTestStone("kernel-6.1", "nvidia-kernel-modules", "525.89", nil),
+				},
+			})
+			Expect(c.Covered).To(Equal([]string{"zfs-kmod"}))
+			Expect(len(c.Missing)).To(Equal(2))
+			Expect(c.Missing[0].Name).To(Equal("nvidia-kernel-modules"))
+			Expect(c.Missing[1].Name).To(Equal("virtualbox-modules"))
+
+			// The zen modules don't cover the vanilla kernel.
+			c = NewModulesCoverage("6.1", "vanilla", installed, &specs.StonesPack{
+				Stones: []*specs.Stone{zfsZen},
+			})
+			Expect(c.Covered).To(Equal([]string{"virtualbox-modules"}))
+		})
+	})
+})
@@ -0,0 +1,237 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kernel
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+)
+
+const (
+	DoctorSeverityWarning = "warning"
+	DoctorSeverityError   = "error"
+
+	DoctorCheckBrokenLink     = "broken-link"
+	DoctorCheckMissingModules = "missing-modules"
+	DoctorCheckOrphanModules  = "orphan-modules"
+	DoctorCheckMissingInitrd  = "missing-initrd"
+	DoctorCheckEmptyInitrd    = "empty-initrd"
+	DoctorCheckOrphanInitrd   = "orphan-initrd"
+	DoctorCheckStaleGrub      = "stale-grub"
+)
+
+type DoctorOpts struct {
+	ModulesDir  string
+	GrubCfgFile string
+}
+
+type DoctorIssue struct {
+	Severity string `json:"severity" yaml:"severity"`
+	Check    string `json:"check" yaml:"check"`
+	File     string `json:"file,omitempty" yaml:"file,omitempty"`
+	Message  string `json:"message" yaml:"message"`
+	Fixable  bool   `json:"fixable" yaml:"fixable"`
+	Fixed    bool   `json:"fixed" yaml:"fixed"`
+
+	KernelFiles *kernelspecs.KernelFiles `json:"-" yaml:"-"`
+}
+
+type DoctorReport struct {
+	Issues []*DoctorIssue `json:"issues" yaml:"issues"`
+}
+
+func NewDoctorReport() *DoctorReport {
+	return &DoctorReport{
+		Issues: []*DoctorIssue{},
+	}
+}
+
+func (r *DoctorReport) Add(severity, check, file, msg string, fixable bool,
+	kf *kernelspecs.KernelFiles) {
+	r.Issues = append(r.Issues, &DoctorIssue{
+		Severity:    severity,
+		Check:       check,
+		File:        file,
+		Message:     msg,
+		Fixable:     fixable,
+		KernelFiles: kf,
+	})
+}
+
+// Return the exit code to use for monitoring: 0 if there aren't
+// problems, 1 with only warnings, 2 with errors. The fixed issues
+// are ignored.
+func (r *DoctorReport) ExitCode() int {
+	ans := 0
+	for _, i := range r.Issues {
+		if i.Fixed {
+			continue
+		}
+
+		switch i.Severity {
+		case DoctorSeverityError:
+			return 2
+		case DoctorSeverityWarning:
+			ans = 1
+		}
+	}
+	return ans
+}
+
+func (r *DoctorReport) String() string {
+	data, _ := json.Marshal(r)
+	return string(data)
+}
+
+// Analyze the boot files and the modules directory and return the
+// list of the inconsistencies found.
+func Doctor(bootFiles *kernelspecs.BootFiles, opts *DoctorOpts) (*DoctorReport, error) {
+	ans := NewDoctorReport()
+
+	modulesDir := opts.ModulesDir
+	if modulesDir == "" {
+		modulesDir = "/lib/modules"
+	}
+
+	// Check links
+	links := []struct {
+		Name     string
+		Target   string
+		Severity string
+	}{
+		{"bzImage", bootFiles.BzImageLink, DoctorSeverityError},
+		{"Initrd", bootFiles.InitrdLink, DoctorSeverityError},
+		{"bzImage.old", bootFiles.BzImageOldLink, DoctorSeverityWarning},
+		{"Initrd.old", bootFiles.InitrdOldLink, DoctorSeverityWarning},
+	}
+	for _, l := range links {
+		if l.Target == "" {
+			continue
+		}
+
+		target := l.Target
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(bootFiles.Dir, target)
+		}
+
+		if _, err := os.Stat(target); err != nil {
+			ans.Add(l.Severity, DoctorCheckBrokenLink, l.Name,
+				fmt.Sprintf("The link %s points to the missing file %s.",
+					l.Name, l.Target),
+				true, nil)
+		}
+	}
+
+	releases := make(map[string]bool, 0)
+	newestKernel := int64(0)
+
+	for idx, kf := range bootFiles.Files {
+		if kf.Kernel == nil {
+			if kf.Initrd != nil {
+				ans.Add(DoctorSeverityWarning, DoctorCheckOrphanInitrd,
+					kf.Initrd.GetFilename(),
+					"Initrd image without kernel image.",
+					false, bootFiles.Files[idx])
+			}
+			continue
+		}
+
+		release := kf.Kernel.GetRelease()
+		releases[release] = true
+
+		kstat, err := os.Stat(filepath.Join(bootFiles.Dir, kf.Kernel.GetFilename()))
+		if err == nil && kstat.ModTime().Unix() > newestKernel {
+			newestKernel = kstat.ModTime().Unix()
+		}
+
+		if !utils.Exists(filepath.Join(modulesDir, release)) {
+			ans.Add(DoctorSeverityError, DoctorCheckMissingModules,
+				kf.Kernel.GetFilename(),
+				fmt.Sprintf("No modules directory %s found.",
+					filepath.Join(modulesDir, release)),
+				false, bootFiles.Files[idx])
+		}
+
+		if kf.Initrd == nil {
+			ans.Add(DoctorSeverityWarning, DoctorCheckMissingInitrd,
+				kf.Kernel.GetFilename(),
+				"No initrd image found for the kernel.",
+				true, bootFiles.Files[idx])
+			continue
+		}
+
+		istat, err := os.Stat(filepath.Join(bootFiles.Dir, kf.Initrd.GetFilename()))
+		if err != nil || istat.Size() == 0 {
+			ans.Add(DoctorSeverityError, DoctorCheckEmptyInitrd,
+				kf.Initrd.GetFilename(),
+				"The initrd image is empty.",
+				true, bootFiles.Files[idx])
+		} else if istat.ModTime().Unix() > newestKernel {
+			newestKernel = istat.ModTime().Unix()
+		}
+	}
+
+	// Check leftover modules directories
+	if utils.Exists(modulesDir) {
+		dirs, err := os.ReadDir(modulesDir)
+		if err != nil {
+			return ans, err
+		}
+
+		for _, d := range dirs {
+			if !d.IsDir() {
+				continue
+			}
+			if _, present := releases[d.Name()]; present {
+				continue
+			}
+
+			ans.Add(DoctorSeverityWarning, DoctorCheckOrphanModules,
+				filepath.Join(modulesDir, d.Name()),
+				"Modules directory without kernel image.",
+				false, nil)
+		}
+	}
+
+	// Check grub.cfg
+	grubCfgFile := opts.GrubCfgFile
+	if grubCfgFile == "" {
+		grubCfgFile = filepath.Join(bootFiles.Dir, "grub", "grub.cfg")
+	}
+
+	gstat, err := os.Stat(grubCfgFile)
+	if err == nil {
+		content, err := os.ReadFile(grubCfgFile)
+		if err != nil {
+			return ans, err
+		}
+
+		if gstat.ModTime().Unix() < newestKernel {
+			ans.Add(DoctorSeverityWarning, DoctorCheckStaleGrub, grubCfgFile,
+				"The grub configuration is older than the kernel files.",
+				true, nil)
+		} else if !strings.Contains(string(content), "/bzImage") {
+			// POST: grub.cfg doesn't use the links. Every kernel must be present.
+			for _, kf := range bootFiles.Files {
+				if kf.Kernel != nil &&
+					!strings.Contains(string(content), kf.Kernel.GetFilename()) {
+					ans.Add(DoctorSeverityWarning, DoctorCheckStaleGrub, grubCfgFile,
+						fmt.Sprintf("The kernel %s is not present in the grub configuration.",
+							kf.Kernel.GetFilename()),
+						true, nil)
+					break
+				}
+			}
+		}
+	}
+
+	return ans, nil
+}
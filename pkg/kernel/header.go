@@ -0,0 +1,144 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kernel
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	// x86 boot protocol
+	x86HeaderMagicOffset   = 0x202
+	x86HeaderMagic         = "HdrS"
+	x86KernelVersionOffset = 0x20e
+	x86SetupOffset         = 0x200
+
+	// arm64 and riscv Image header
+	arm64ImageMagicOffset = 0x38
+	arm64ImageMagic       = "ARM\x64"
+	riscvImageMagicOffset = 0x34
+	riscvImageMagic       = "RSC\x05"
+
+	linuxBannerPrefix = "Linux version "
+)
+
+var ErrUnknownKernelFormat = errors.New("Unknown kernel image format")
+
+// Read the kernel release from the image header. For x86 bzImage
+// is used the kernel_version field of the boot protocol, for the
+// arm64 and riscv Image files is searched the Linux banner.
+func ReadKernelRelease(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	header := make([]byte, 0x240)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	header = header[:n]
+
+	if len(header) > x86KernelVersionOffset+2 &&
+		string(header[x86HeaderMagicOffset:x86HeaderMagicOffset+4]) == x86HeaderMagic {
+
+		ptr := binary.LittleEndian.Uint16(header[x86KernelVersionOffset:])
+		if ptr == 0 {
+			return "", errors.New("No kernel version available in the bzImage header")
+		}
+
+		buf := make([]byte, 256)
+		n, err := f.ReadAt(buf, int64(ptr)+x86SetupOffset)
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		return parseKernelRelease(buf[:n])
+	}
+
+	isImage := (len(header) >= arm64ImageMagicOffset+4 &&
+		string(header[arm64ImageMagicOffset:arm64ImageMagicOffset+4]) == arm64ImageMagic) ||
+		(len(header) >= riscvImageMagicOffset+4 &&
+			string(header[riscvImageMagicOffset:riscvImageMagicOffset+4]) == riscvImageMagic)
+
+	if isImage || bytes.HasPrefix(header, []byte{0x1f, 0x8b}) {
+		_, err = f.Seek(0, io.SeekStart)
+		if err != nil {
+			return "", err
+		}
+
+		var r io.Reader = f
+		if !isImage {
+			// POST: Image.gz
+			z, err := gzip.NewReader(f)
+			if err != nil {
+				return "", err
+			}
+			defer z.Close()
+			r = z
+		}
+
+		return searchLinuxBanner(r)
+	}
+
+	return "", ErrUnknownKernelFormat
+}
+
+// Return the first field of the version string.
+// Example: 6.1.12-macaroni (builder@macaroni) #1 SMP ...
+func parseKernelRelease(data []byte) (string, error) {
+	if idx := bytes.IndexByte(data, 0); idx >= 0 {
+		data = data[:idx]
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", errors.New("Empty kernel version string")
+	}
+
+	return fields[0], nil
+}
+
+func searchLinuxBanner(r io.Reader) (string, error) {
+	prefix := []byte(linuxBannerPrefix)
+	buf := make([]byte, 1024*1024)
+	// Bytes of the previous chunk kept to match the banner across chunks.
+	keep := 256
+	start := 0
+
+	for {
+		n, err := io.ReadFull(r, buf[start:])
+		data := buf[:start+n]
+
+		idx := bytes.Index(data, prefix)
+		if idx >= 0 && len(data)-idx > keep {
+			return parseKernelRelease(data[idx+len(prefix):])
+		} else if idx >= 0 && (err == io.EOF || err == io.ErrUnexpectedEOF) {
+			return parseKernelRelease(data[idx+len(prefix):])
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if len(data) > keep {
+			copy(buf, data[len(data)-keep:])
+			start = keep
+		}
+	}
+
+	return "", fmt.Errorf("No Linux banner found")
+}
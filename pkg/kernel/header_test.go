@@ -0,0 +1,122 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kernel_test
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+
+	. "github.com/macaroni-os/macaronictl/pkg/kernel"
+	"github.com/macaroni-os/macaronictl/pkg/profile"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func writeBzImage(file, version string) error {
+	data := make([]byte, 0x1000)
+	copy(data[0x202:], "HdrS")
+	binary.LittleEndian.PutUint16(data[0x20e:], 0x600)
+	copy(data[0x800:], version+" (builder@macaroni) #1 SMP")
+	return os.WriteFile(file, data, 0644)
+}
+
+var _ = Describe("Kernel Header Test", func() {
+
+	Context("Read release", func() {
+
+		It("x86 bzImage", func() {
+			tmpdir, err := os.MkdirTemp("", "macaronictl-header")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+
+			file := filepath.Join(tmpdir, "bzImage")
+			Expect(writeBzImage(file, "6.1.12-macaroni")).Should(BeNil())
+
+			release, err := ReadKernelRelease(file)
+			Expect(err).Should(BeNil())
+			Expect(release).To(Equal("6.1.12-macaroni"))
+		})
+
+		It("arm64 Image", func() {
+			tmpdir, err := os.MkdirTemp("", "macaronictl-header")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+
+			data := make([]byte, 0x3000)
+			copy(data[0x38:], "ARM\x64")
+			copy(data[0x2000:], "Linux version 6.6.1-rc2-foo-macaroni (builder@macaroni) #1")
+			file := filepath.Join(tmpdir, "Image")
+			Expect(os.WriteFile(file, data, 0644)).Should(BeNil())
+
+			release, err := ReadKernelRelease(file)
+			Expect(err).Should(BeNil())
+			Expect(release).To(Equal("6.6.1-rc2-foo-macaroni"))
+		})
+
+		It("Unknown format", func() {
+			tmpdir, err := os.MkdirTemp("", "macaronictl-header")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+
+			file := filepath.Join(tmpdir, "kernel")
+			Expect(os.WriteFile(file, []byte("foo"), 0644)).Should(BeNil())
+
+			_, err = ReadKernelRelease(file)
+			Expect(err).To(Equal(ErrUnknownKernelFormat))
+		})
+	})
+
+	Context("Boot dir", func() {
+
+		It("Parse and check release", func() {
+			tmpdir, err := os.MkdirTemp("", "macaronictl-header")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+
+			Expect(writeBzImage(
+				filepath.Join(tmpdir, "kernel-vanilla-x86_64-6.6.1-rc2-foo-macaroni"),
+				"6.6.1-rc2-foo-macaroni")).Should(BeNil())
+			Expect(writeBzImage(
+				filepath.Join(tmpdir, "kernel-vanilla-x86_64-6.1.12-macaroni"),
+				"6.1.10-macaroni")).Should(BeNil())
+
+			bootFiles, err := ReadBootDir(tmpdir, profile.GetDefaultKernelProfiles())
+			Expect(err).Should(BeNil())
+			Expect(len(bootFiles.Files)).To(Equal(2))
+
+			for _, kf := range bootFiles.Files {
+				switch kf.Kernel.GetFilename() {
+				case "kernel-vanilla-x86_64-6.6.1-rc2-foo-macaroni":
+					Expect(kf.Kernel.GetVersion()).To(Equal("6.6.1-rc2-foo"))
+					Expect(kf.Kernel.HasReleaseMismatch()).To(BeFalse())
+				default:
+					Expect(kf.Kernel.GetVersion()).To(Equal("6.1.12"))
+					Expect(kf.Kernel.GetHeaderRelease()).To(Equal("6.1.10-macaroni"))
+					Expect(kf.Kernel.HasReleaseMismatch()).To(BeTrue())
+				}
+			}
+		})
+
+		It("Separate microcode image", func() {
+			tmpdir, err := os.MkdirTemp("", "macaronictl-header")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+
+			Expect(writeBzImage(
+				filepath.Join(tmpdir, "kernel-vanilla-x86_64-6.1.12-macaroni"),
+				"6.1.12-macaroni")).Should(BeNil())
+			Expect(os.WriteFile(filepath.Join(tmpdir, "intel-uc.img"),
+				[]byte("ucode"), 0644)).Should(BeNil())
+
+			bootFiles, err := ReadBootDir(tmpdir, profile.GetDefaultKernelProfiles())
+			Expect(err).Should(BeNil())
+			Expect(len(bootFiles.Files)).To(Equal(1))
+			Expect(bootFiles.Files[0].GetMicrocodeFile()).To(Equal("intel-uc.img"))
+			Expect(bootFiles.Files[0].Microcode.Vendor).To(Equal("GenuineIntel"))
+		})
+	})
+})
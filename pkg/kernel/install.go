@@ -1,5 +1,5 @@
 /*
-Copyright © 2021-2023 Macaroni OS Linux
+Copyright © 2021-2026 Macaroni OS Linux
 See AUTHORS and LICENSE for the license details and contributors.
 */
 package kernel
@@ -15,7 +15,17 @@ import (
 	"github.com/macaroni-os/macaronictl/pkg/utils"
 )
 
-func InstallPackages(k *specs.Stone, modules []*specs.Stone) error {
+// Return the anise command to run inside the rootfs defined
+// in the configuration.
+func aniseCommand(config *specs.MacaroniCtlConfig, args []string) []string {
+	if config == nil {
+		return args
+	}
+	return utils.ChrootArgs(config.GetGeneral().GetRootfs(), args)
+}
+
+func InstallPackages(k *specs.Stone, modules []*specs.Stone,
+	config *specs.MacaroniCtlConfig) error {
 	log := logger.GetDefaultLogger()
 	aniseBin := utils.TryResolveBinaryAbsPath("anise")
 	args := []string{
@@ -25,6 +35,7 @@ func InstallPackages(k *specs.Stone, modules []*specs.Stone) error {
 		args = append(args, s.GetName())
 	}
 
+	args = aniseCommand(config, args)
 	cmd := exec.Command(args[0], args[1:]...)
 	log.Debug(fmt.Sprintf("Running install command: %s",
 		strings.Join(args, " ")))
@@ -43,7 +54,87 @@ func InstallPackages(k *specs.Stone, modules []*specs.Stone) error {
 	}
 
 	if cmd.ProcessState.ExitCode() != 0 {
-		return fmt.Errorf("anise install exiting with %s.",
+		return fmt.Errorf("anise install exiting with %d.",
+			cmd.ProcessState.ExitCode())
+	}
+
+	return nil
+}
+
+func RemovePackages(k *specs.Stone, modules []*specs.Stone,
+	config *specs.MacaroniCtlConfig) error {
+	log := logger.GetDefaultLogger()
+	aniseBin := utils.TryResolveBinaryAbsPath("anise")
+	args := []string{
+		aniseBin, "rm", k.GetName(),
+	}
+	for _, s := range modules {
+		args = append(args, s.GetName())
+	}
+
+	args = aniseCommand(config, args)
+	cmd := exec.Command(args[0], args[1:]...)
+	log.Debug(fmt.Sprintf("Running uninstall command: %s",
+		strings.Join(args, " ")))
+
+	cmd.Stdout = os.Stdout
+	cmd.Stdin = os.Stdin
+
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	err = cmd.Wait()
+	if err != nil {
+		return err
+	}
+
+	if cmd.ProcessState.ExitCode() != 0 {
+		return fmt.Errorf("anise uninstall exiting with %d.",
+			cmd.ProcessState.ExitCode())
+	}
+
+	return nil
+}
+
+// Replace the installed packages with the versions of the same
+// packages in input. It's used to upgrade the kernel and the extra
+// modules of the same branch.
+func ReplacePackages(old []*specs.Stone, news []*specs.Stone,
+	config *specs.MacaroniCtlConfig) error {
+	log := logger.GetDefaultLogger()
+	aniseBin := utils.TryResolveBinaryAbsPath("anise")
+	args := []string{
+		aniseBin, "replace",
+	}
+	for _, s := range old {
+		args = append(args, s.GetName())
+	}
+	for _, s := range news {
+		args = append(args, "--for", s.GetName()+"@"+s.GetVersion())
+	}
+
+	args = aniseCommand(config, args)
+	cmd := exec.Command(args[0], args[1:]...)
+	log.Debug(fmt.Sprintf("Running replace command: %s",
+		strings.Join(args, " ")))
+
+	cmd.Stdout = os.Stdout
+	cmd.Stdin = os.Stdin
+
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	err = cmd.Wait()
+	if err != nil {
+		return err
+	}
+
+	if cmd.ProcessState.ExitCode() != 0 {
+		return fmt.Errorf("anise replace exiting with %d.",
 			cmd.ProcessState.ExitCode())
 	}
 
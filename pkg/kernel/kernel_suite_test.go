@@ -0,0 +1,21 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kernel_test
+
+import (
+	"testing"
+
+	"github.com/macaroni-os/macaronictl/pkg/logger"
+	"github.com/macaroni-os/macaronictl/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestKernel(t *testing.T) {
+	RegisterFailHandler(Fail)
+	logger.NewMacaroniCtlLogger(specs.NewMacaroniCtlConfig(nil)).SetAsDefault()
+	RunSpecs(t, "Kernel Suite")
+}
@@ -1,17 +1,37 @@
 /*
-Copyright © 2021-2023 Macaroni OS Linux
+Copyright © 2021-2026 Macaroni OS Linux
 See AUTHORS and LICENSE for the license details and contributors.
 */
 package kernel
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/macaroni-os/macaronictl/pkg/anise"
 	specs "github.com/macaroni-os/macaronictl/pkg/specs"
 	"github.com/macaroni-os/macaronictl/pkg/utils"
 )
 
+// Return the branch of the kernel from the category of the package.
+// Example: kernel-6.1 -> 6.1
+func KernelBranch(s *specs.Stone, kType string) string {
+	ans := strings.TrimPrefix(s.Category, "kernel-")
+	if kType == "zen" {
+		ans = strings.TrimPrefix(ans, "zen-")
+	}
+	return ans
+}
+
+// Return the category of the packages of the kernel branch.
+// Example: 6.1 -> kernel-6.1, kernel-zen-6.1 for the zen kernels
+func KernelCategory(kernelBranch, kType string) string {
+	if kType == "zen" {
+		return "kernel-zen-" + kernelBranch
+	}
+	return "kernel-" + kernelBranch
+}
+
 func AvailableExtraModules(kernelBranch, kernelType string, installed bool,
 	config *specs.MacaroniCtlConfig) (*specs.StonesPack, error) {
 	ans := &specs.StonesPack{
@@ -25,17 +45,8 @@ func AvailableExtraModules(kernelBranch, kernelType string, installed bool,
 	}
 
 	if kernelBranch != "" {
-		var category string
-
-		switch kernelType {
-		case "zen":
-			category = "kernel-zen-" + kernelBranch
-		default:
-			category = "kernel-" + kernelBranch
-		}
-
 		args = append(args, []string{
-			"--category", category,
+			"--category", KernelCategory(kernelBranch, kernelType),
 		}...)
 	}
 
@@ -43,7 +54,7 @@ func AvailableExtraModules(kernelBranch, kernelType string, installed bool,
 		args = append(args, "--installed")
 	}
 
-	stones, err := anise.SearchStones(args)
+	stones, err := anise.SearchStones(aniseCommand(config, args))
 	if err != nil {
 		return ans, err
 	}
@@ -75,7 +86,7 @@ func AvailableKernels(config *specs.MacaroniCtlConfig) (*specs.StonesPack, error
 		"-o", "json",
 	}
 
-	return anise.SearchStones(args)
+	return anise.SearchStones(aniseCommand(config, args))
 }
 
 func InstalledKernels(config *specs.MacaroniCtlConfig) (*specs.StonesPack, error) {
@@ -85,7 +96,7 @@ func InstalledKernels(config *specs.MacaroniCtlConfig) (*specs.StonesPack, error
 		"-o", "json", "--installed",
 	}
 
-	return anise.SearchStones(args)
+	return anise.SearchStones(aniseCommand(config, args))
 }
 
 func ParseKernelAnnotations(s *specs.Stone) (*specs.KernelAnnotation, error) {
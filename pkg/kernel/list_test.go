@@ -0,0 +1,28 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kernel_test
+
+import (
+	. "github.com/macaroni-os/macaronictl/pkg/kernel"
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Kernel List", func() {
+
+	Context("Branches", func() {
+
+		It("Branch and category", func() {
+			Expect(KernelBranch(&specs.Stone{Category: "kernel-zen-6.1"}, "zen")).To(Equal("6.1"))
+			Expect(KernelBranch(&specs.Stone{Category: "kernel-6.1"}, "vanilla")).To(Equal("6.1"))
+			Expect(KernelCategory("6.1", "zen")).To(Equal("kernel-zen-6.1"))
+			Expect(KernelCategory("6.1", "vanilla")).To(Equal("kernel-6.1"))
+		})
+
+	})
+
+})
@@ -0,0 +1,147 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kernel
+
+import (
+	"fmt"
+	"sort"
+
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+)
+
+const (
+	ModuleStatusAvailable = "available"
+	ModuleStatusMissing   = "missing"
+)
+
+// Branch and type of an installed kernel.
+type ModulesMatrixColumn struct {
+	Branch string `json:"branch" yaml:"branch"`
+	Type   string `json:"type" yaml:"type"`
+}
+
+type ModulesMatrixRow struct {
+	Module string `json:"module" yaml:"module"`
+	// Installed version, available or missing for every column.
+	Cells []string `json:"cells" yaml:"cells"`
+	// The module is installed only for kernel branches not installed.
+	Orphan bool `json:"orphan" yaml:"orphan"`
+}
+
+type ModulesMatrix struct {
+	Columns []*ModulesMatrixColumn `json:"columns" yaml:"columns"`
+	Rows    []*ModulesMatrixRow    `json:"rows" yaml:"rows"`
+	// Module packages of kernel branches no more installed.
+	Orphans []*specs.Stone `json:"orphans" yaml:"orphans"`
+}
+
+func (c *ModulesMatrixColumn) String() string {
+	return fmt.Sprintf("%s (%s)", c.Branch, c.Type)
+}
+
+// Return the kernel type of the module. The packages without the
+// kernel.type label are managed as vanilla.
+func moduleKernelType(s *specs.Stone) string {
+	ans := s.GetLabelValue("kernel.type")
+	if ans == "" {
+		ans = "vanilla"
+	}
+	return ans
+}
+
+// Create the matrix of the installed extra modules with a column
+// for every branch and type of the installed kernels.
+func NewModulesMatrix(kernels, installedMods, availableMods *specs.StonesPack) (*ModulesMatrix, error) {
+	ans := &ModulesMatrix{
+		Columns: []*ModulesMatrixColumn{},
+		Rows:    []*ModulesMatrixRow{},
+		Orphans: []*specs.Stone{},
+	}
+
+	columns := make(map[string]int, 0)
+	for _, s := range kernels.Stones {
+		a, err := ParseKernelAnnotations(s)
+		if err != nil {
+			return nil, err
+		}
+
+		// The kernels without type are managed as vanilla like the modules.
+		t := a.Type
+		if t == "" {
+			t = "vanilla"
+		}
+
+		col := &ModulesMatrixColumn{
+			Branch: KernelBranch(s, t),
+			Type:   t,
+		}
+		if _, present := columns[col.String()]; present {
+			continue
+		}
+		ans.Columns = append(ans.Columns, col)
+		columns[col.String()] = 0
+	}
+
+	sort.Slice(ans.Columns, func(i, j int) bool {
+		if ans.Columns[i].Type != ans.Columns[j].Type {
+			return ans.Columns[i].Type < ans.Columns[j].Type
+		}
+		return CompareVersions(ans.Columns[i].Branch, ans.Columns[j].Branch) < 0
+	})
+	for idx, col := range ans.Columns {
+		columns[col.String()] = idx
+	}
+
+	available := make(map[string]bool, 0)
+	for _, s := range availableMods.Stones {
+		t := moduleKernelType(s)
+		col := &ModulesMatrixColumn{Branch: KernelBranch(s, t), Type: t}
+		available[s.Name+"@"+col.String()] = true
+	}
+
+	rows := make(map[string]*ModulesMatrixRow, 0)
+	names := []string{}
+	for idx, s := range installedMods.Stones {
+		row, present := rows[s.Name]
+		if !present {
+			row = &ModulesMatrixRow{
+				Module: s.Name,
+				Cells:  make([]string, len(ans.Columns)),
+				Orphan: true,
+			}
+			rows[s.Name] = row
+			names = append(names, s.Name)
+		}
+
+		t := moduleKernelType(s)
+		col := &ModulesMatrixColumn{Branch: KernelBranch(s, t), Type: t}
+		cidx, present := columns[col.String()]
+		if !present {
+			ans.Orphans = append(ans.Orphans, installedMods.Stones[idx])
+			continue
+		}
+
+		row.Orphan = false
+		row.Cells[cidx] = s.GetVersion()
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		row := rows[name]
+		for idx, col := range ans.Columns {
+			if row.Cells[idx] != "" {
+				continue
+			}
+			if available[name+"@"+col.String()] {
+				row.Cells[idx] = ModuleStatusAvailable
+			} else {
+				row.Cells[idx] = ModuleStatusMissing
+			}
+		}
+		ans.Rows = append(ans.Rows, row)
+	}
+
+	return ans, nil
+}
@@ -0,0 +1,77 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kernel_test
+
+import (
+	. "github.com/macaroni-os/macaronictl/pkg/kernel"
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Kernel Modules Matrix", func() {
+
+	Context("Installed modules", func() {
+
+		kernels := &specs.StonesPack{
+			Stones: []*specs.Stone{
+				newTestStone("kernel-6.6", "macaroni-full", "6.6.5", &specs.KernelAnnotation{Lts: true}),
+				newTestStone("kernel-6.1", "macaroni-full", "6.1.12", &specs.KernelAnnotation{Lts: true}),
+			},
+		}
+
+		installedMods := &specs.StonesPack{
+			Stones: []*specs.Stone{
+				newTestStone("kernel-6.1", "zfs-kmod", "2.1.9", nil),
+				newTestStone("kernel-6.6", "virtualbox-modules", "7.0.6", nil),
+				newTestStone("kernel-5.15", "nvidia-kernel-modules", "470.82", nil),
+			},
+		}
+
+		availableMods := &specs.StonesPack{
+			Stones: []*specs.Stone{
+				newTestStone("kernel-6.1", "zfs-kmod", "2.1.9", nil),
+				newTestStone("kernel-6.6", "zfs-kmod", "2.2.2", nil),
+				newTestStone("kernel-6.6", "virtualbox-modules", "7.0.6", nil),
+			},
+		}
+
+		It("Build matrix", func() {
+			m, err := NewModulesMatrix(kernels, installedMods, availableMods)
+			Expect(err).Should(BeNil())
+
+			Expect(len(m.Columns)).To(Equal(2))
+			Expect(m.Columns[0].String()).To(Equal("6.1 (vanilla)"))
+			Expect(m.Columns[1].String()).To(Equal("6.6 (vanilla)"))
+
+			Expect(len(m.Rows)).To(Equal(3))
+			Expect(m.Rows[0].Module).To(Equal("nvidia-kernel-modules"))
+			Expect(m.Rows[0].Orphan).To(BeTrue())
+			Expect(m.Rows[0].Cells).To(Equal([]string{ModuleStatusMissing, ModuleStatusMissing}))
+			Expect(m.Rows[1].Cells).To(Equal([]string{ModuleStatusMissing, "7.0.6"}))
+			Expect(m.Rows[2].Cells).To(Equal([]string{"2.1.9", ModuleStatusAvailable}))
+
+			Expect(len(m.Orphans)).To(Equal(1))
+			Expect(m.Orphans[0].HumanReadableString()).To(
+				Equal("kernel-5.15/nvidia-kernel-modules-470.82"))
+		})
+
+		It("Kernel without type", func() {
+			k := newTestStone("kernel-6.1", "macaroni-full", "6.1.12",
+				&specs.KernelAnnotation{Lts: true})
+			delete(k.Annotations["kernel"].(map[string]interface{}), "type")
+
+			m, err := NewModulesMatrix(&specs.StonesPack{Stones: []*specs.Stone{k}},
+				installedMods, availableMods)
+			Expect(err).Should(BeNil())
+
+			Expect(len(m.Columns)).To(Equal(1))
+			Expect(m.Columns[0].String()).To(Equal("6.1 (vanilla)"))
+			Expect(m.Rows[2].Cells).To(Equal([]string{"2.1.9"}))
+			Expect(len(m.Orphans)).To(Equal(2))
+		})
+	})
+})
@@ -0,0 +1,240 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kernel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+)
+
+const (
+	PruneReasonRunning = "running"
+	PruneReasonCurrent = "current"
+	PruneReasonOld     = "old"
+	PruneReasonNewest  = "newest"
+)
+
+type PruneOpts struct {
+	// Number of the newest kernels to keep for every kernel type.
+	Keep        int
+	KeepCurrent bool
+	KeepOld     bool
+	ModulesDir  string
+	// Release of the running kernel. It's never removed.
+	RunningRelease string
+	DryRun         bool
+}
+
+type PruneFile struct {
+	Path    string `json:"path" yaml:"path"`
+	Size    int64  `json:"size" yaml:"size"`
+	Removed bool   `json:"removed" yaml:"removed"`
+}
+
+type PruneKernel struct {
+	Kernel  string       `json:"kernel" yaml:"kernel"`
+	Release string       `json:"release" yaml:"release"`
+	Type    string       `json:"type,omitempty" yaml:"type,omitempty"`
+	Keep    bool         `json:"keep" yaml:"keep"`
+	Reason  string       `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Files   []*PruneFile `json:"files,omitempty" yaml:"files,omitempty"`
+
+	KernelFiles *kernelspecs.KernelFiles `json:"-" yaml:"-"`
+}
+
+type PruneReport struct {
+	Kernels []*PruneKernel `json:"kernels" yaml:"kernels"`
+	Freed   int64          `json:"freed" yaml:"freed"`
+	DryRun  bool           `json:"dry_run" yaml:"dry_run"`
+}
+
+func (r *PruneReport) String() string {
+	data, _ := json.Marshal(r)
+	return string(data)
+}
+
+// Return the kernels to remove.
+func (r *PruneReport) GetPruned() []*PruneKernel {
+	ans := []*PruneKernel{}
+	for idx := range r.Kernels {
+		if !r.Kernels[idx].Keep {
+			ans = append(ans, r.Kernels[idx])
+		}
+	}
+	return ans
+}
+
+func pruneKernelTypeKey(kf *kernelspecs.KernelFiles) string {
+	ans := kf.Kernel.GetType()
+	if kf.Type != nil {
+		ans = kf.Type.Name + "/" + ans
+	}
+	return ans
+}
+
+// Apply the retention policy to the kernels of the boot directory.
+// The running kernel, the kernels of the bzImage and bzImage.old links
+// (when enabled) and the newest kernels of every type are kept.
+func NewPruneReport(bootFiles *kernelspecs.BootFiles, opts *PruneOpts) *PruneReport {
+	ans := &PruneReport{
+		Kernels: []*PruneKernel{},
+		DryRun:  opts.DryRun,
+	}
+
+	groups := make(map[string][]*PruneKernel, 0)
+	keys := []string{}
+
+	for idx, kf := range bootFiles.Files {
+		if kf.Kernel == nil {
+			// The orphan initrd images are managed by the doctor command.
+			continue
+		}
+
+		pk := &PruneKernel{
+			Kernel:      kf.Kernel.GetFilename(),
+			Release:     kf.Kernel.GetRelease(),
+			Type:        kf.Kernel.GetType(),
+			KernelFiles: bootFiles.Files[idx],
+		}
+
+		switch {
+		case opts.RunningRelease != "" && pk.Release == opts.RunningRelease:
+			pk.Keep = true
+			pk.Reason = PruneReasonRunning
+		case opts.KeepCurrent && bootFiles.BzImageLink == pk.Kernel:
+			pk.Keep = true
+			pk.Reason = PruneReasonCurrent
+		case opts.KeepOld && bootFiles.BzImageOldLink == pk.Kernel:
+			pk.Keep = true
+			pk.Reason = PruneReasonOld
+		}
+
+		key := pruneKernelTypeKey(kf)
+		if _, present := groups[key]; !present {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], pk)
+	}
+
+	for _, key := range keys {
+		kernels := groups[key]
+		// Sort the kernels from the newest.
+		sort.SliceStable(kernels, func(i, j int) bool {
+			return CompareVersions(kernels[i].KernelFiles.Kernel.GetVersion(),
+				kernels[j].KernelFiles.Kernel.GetVersion()) > 0
+		})
+
+		for idx, pk := range kernels {
+			if !pk.Keep && idx < opts.Keep {
+				pk.Keep = true
+				pk.Reason = PruneReasonNewest
+			}
+		}
+
+		ans.Kernels = append(ans.Kernels, kernels...)
+	}
+
+	return ans
+}
+
+func pathSize(path string) int64 {
+	var ans int64 = 0
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err == nil && info.Mode().IsRegular() {
+			ans += info.Size()
+		}
+		return nil
+	})
+	return ans
+}
+
+// Return the existing files of the kernel: kernel image, initrd image,
+// System.map and config files and the directory of the modules.
+func KernelFilesPaths(kf *kernelspecs.KernelFiles, bootDir, modulesDir string) []string {
+	ans := []string{}
+
+	candidates := []string{
+		filepath.Join(bootDir, kf.Kernel.GetFilename()),
+	}
+	if kf.Initrd != nil {
+		candidates = append(candidates, filepath.Join(bootDir, kf.Initrd.GetFilename()))
+	}
+
+	release := kf.Kernel.GetRelease()
+	for _, name := range []string{"System.map", "config"} {
+		candidates = append(candidates, filepath.Join(bootDir, name+"-"+release))
+		if kf.Type != nil {
+			candidates = append(candidates, filepath.Join(bootDir,
+				name+strings.TrimPrefix(kf.Kernel.GetFilename(),
+					kf.Type.GetKernelPrefixSanitized())))
+		}
+	}
+
+	if modulesDir != "" {
+		candidates = append(candidates, filepath.Join(modulesDir, release))
+	}
+
+	for _, f := range candidates {
+		if utils.Exists(f) && !utils.KeyInList(f, &ans) {
+			ans = append(ans, f)
+		}
+	}
+
+	return ans
+}
+
+// Remove the files of the kernels not kept. In dry-run mode the
+// files are only collected.
+func (r *PruneReport) Apply(bootDir string, opts *PruneOpts) error {
+	for _, pk := range r.GetPruned() {
+		for _, f := range KernelFilesPaths(pk.KernelFiles, bootDir, opts.ModulesDir) {
+			pf := &PruneFile{
+				Path: f,
+				Size: pathSize(f),
+			}
+			pk.Files = append(pk.Files, pf)
+			r.Freed += pf.Size
+
+			if opts.DryRun {
+				continue
+			}
+
+			err := os.RemoveAll(f)
+			if err != nil {
+				return fmt.Errorf("Error on remove %s: %s", f, err.Error())
+			}
+			pf.Removed = true
+		}
+	}
+
+	return nil
+}
+
+// Return the size in a human readable format.
+func HumanReadableSize(size int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB"}
+	value := float64(size)
+	idx := 0
+	for value >= 1024 && idx < len(units)-1 {
+		value /= 1024
+		idx++
+	}
+	if idx == 0 {
+		return fmt.Sprintf("%d %s", size, units[idx])
+	}
+	return fmt.Sprintf("%.1f %s", value, units[idx])
+}
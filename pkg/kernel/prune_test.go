@@ -0,0 +1,84 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kernel_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/macaroni-os/macaronictl/pkg/kernel"
+	"github.com/macaroni-os/macaronictl/pkg/profile"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Kernel Prune", func() {
+
+	Context("Retention policy", func() {
+
+		It("Keep current, old and newest", func() {
+			tmpdir, err := os.MkdirTemp("", "macaronictl-prune")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+
+			bootDir := filepath.Join(tmpdir, "boot")
+			modulesDir := filepath.Join(tmpdir, "modules")
+			Expect(os.MkdirAll(bootDir, 0755)).Should(BeNil())
+
+			for _, v := range []string{"6.1.9", "6.1.12", "6.6.5", "6.10.1"} {
+				release := v + "-macaroni"
+				Expect(writeBzImage(
+					filepath.Join(bootDir, "kernel-vanilla-x86_64-"+release),
+					release)).Should(BeNil())
+				Expect(os.WriteFile(filepath.Join(bootDir, "System.map-"+release),
+					[]byte("map"), 0644)).Should(BeNil())
+				Expect(os.MkdirAll(filepath.Join(modulesDir, release, "kernel"), 0755)).Should(BeNil())
+				Expect(os.WriteFile(filepath.Join(modulesDir, release, "kernel", "foo.ko"),
+					[]byte("module"), 0644)).Should(BeNil())
+			}
+
+			Expect(os.Symlink("kernel-vanilla-x86_64-6.1.9-macaroni",
+				filepath.Join(bootDir, "bzImage"))).Should(BeNil())
+
+			bootFiles, err := ReadBootDir(bootDir, profile.GetDefaultKernelProfiles())
+			Expect(err).Should(BeNil())
+
+			opts := &PruneOpts{
+				Keep:        1,
+				KeepCurrent: true,
+				KeepOld:     true,
+				ModulesDir:  modulesDir,
+				DryRun:      true,
+			}
+
+			report := NewPruneReport(bootFiles, opts)
+			pruned := report.GetPruned()
+			Expect(len(pruned)).To(Equal(2))
+
+			names := []string{pruned[0].Kernel, pruned[1].Kernel}
+			Expect(names).To(ConsistOf(
+				"kernel-vanilla-x86_64-6.6.5-macaroni",
+				"kernel-vanilla-x86_64-6.1.12-macaroni",
+			))
+
+			Expect(report.Apply(bootDir, opts)).Should(BeNil())
+			Expect(report.Freed).To(Equal(int64(2 * (0x1000 + 3 + 6))))
+			Expect(len(pruned[0].Files)).To(Equal(3))
+			Expect(pruned[0].Files[0].Removed).To(BeFalse())
+			Expect(filepath.Join(modulesDir, "6.6.5-macaroni")).To(BeADirectory())
+
+			opts.DryRun = false
+			report = NewPruneReport(bootFiles, opts)
+			Expect(report.Apply(bootDir, opts)).Should(BeNil())
+
+			Expect(filepath.Join(bootDir, "kernel-vanilla-x86_64-6.6.5-macaroni")).ShouldNot(BeAnExistingFile())
+			Expect(filepath.Join(bootDir, "System.map-6.1.12-macaroni")).ShouldNot(BeAnExistingFile())
+			Expect(filepath.Join(modulesDir, "6.6.5-macaroni")).ShouldNot(BeAnExistingFile())
+			Expect(filepath.Join(bootDir, "kernel-vanilla-x86_64-6.1.9-macaroni")).Should(BeAnExistingFile())
+			Expect(filepath.Join(bootDir, "kernel-vanilla-x86_64-6.10.1-macaroni")).Should(BeAnExistingFile())
+		})
+	})
+})
@@ -1,5 +1,5 @@
 /*
-Copyright © 2021 Macaroni OS Linux
+Copyright © 2021-2026 Macaroni OS Linux
 See AUTHORS and LICENSE for the license details and contributors.
 */
 package kernelspecs
@@ -19,6 +19,27 @@ func NewKernelFiles(t *KernelType) *KernelFiles {
 	}
 }
 
+// Return the filename of the microcode image that the bootloader
+// loads before the initrd image. It's empty if the microcode is
+// prepended to the initrd image.
+func (k *KernelFiles) GetMicrocodeFile() string {
+	if k.Microcode == nil || k.Microcode.Embedded {
+		return ""
+	}
+	return k.Microcode.Filename
+}
+
+// Return the CPU vendor of the separate early microcode image
+// or an empty string if the file isn't a microcode image.
+func MicrocodeImageVendor(file string) string {
+	for vendor, name := range MicrocodeImageNames {
+		if name == file {
+			return vendor
+		}
+	}
+	return ""
+}
+
 func NewBootFiles(dir string) *BootFiles {
 	return &BootFiles{
 		Dir:   dir,
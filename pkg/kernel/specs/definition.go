@@ -1,5 +1,5 @@
 /*
-Copyright © 2021 Macaroni OS Linux
+Copyright © 2021-2026 Macaroni OS Linux
 See AUTHORS and LICENSE for the license details and contributors.
 */
 package kernelspecs
@@ -15,6 +15,14 @@ type KernelImage struct {
 	Suffix   string `json:"suffix,omitempty" yaml:"suffix,omitempty"`
 	Version  string `json:"version,omitempty" yaml:"version,omitempty"`
 	Arch     string `json:"arch,omitempty" yaml:"arch,omitempty"`
+
+	// Kernel release read from the image header.
+	HeaderRelease string `json:"header_release,omitempty" yaml:"header_release,omitempty"`
+	// The release of the header doesn't match with the filename.
+	ReleaseMismatch bool `json:"release_mismatch,omitempty" yaml:"release_mismatch,omitempty"`
+
+	// Template of the filename of the kernel profile.
+	Template string `json:"template,omitempty" yaml:"template,omitempty"`
 }
 
 type InitrdImage struct {
@@ -24,6 +32,26 @@ type InitrdImage struct {
 	KernelType string `json:"kernel_type,omitempty" yaml:"kernel_type,omitempty"`
 	Arch       string `json:"arch,omitempty" yaml:"arch,omitempty"`
 	Version    string `json:"version,omitempty" yaml:"version,omitempty"`
+
+	// Template of the filename of the kernel profile.
+	Template string `json:"template,omitempty" yaml:"template,omitempty"`
+}
+
+// Early microcode image of the kernel files.
+type MicrocodeImage struct {
+	// Filename of the image in the boot directory. It's empty when
+	// the microcode is prepended to the initrd image.
+	Filename string `json:"filename,omitempty" yaml:"filename,omitempty"`
+	// CPU vendor of the microcode: GenuineIntel or AuthenticAMD.
+	Vendor string `json:"vendor,omitempty" yaml:"vendor,omitempty"`
+	// The microcode archive is prepended to the initrd image.
+	Embedded bool `json:"embedded,omitempty" yaml:"embedded,omitempty"`
+}
+
+// Filenames of the separate early microcode images by CPU vendor.
+var MicrocodeImageNames = map[string]string{
+	"GenuineIntel": "intel-uc.img",
+	"AuthenticAMD": "amd-uc.img",
 }
 
 type KernelType struct {
@@ -33,6 +61,22 @@ type KernelType struct {
 	Suffix       string `json:"suffix,omitempty" yaml:"suffix,omitempty"`
 	Type         string `json:"type,omitempty" yaml:"type,omitempty"`
 	WithArch     bool   `json:"with_arch,omitempty" yaml:"with_arch,omitempty"`
+	// Templates of the filenames of the kernel and initrd images with the
+	// placeholders {prefix}, {type}, {arch}, {version} and {suffix}.
+	// Example: vmlinuz-{version}. Without template it's used the format
+	// <prefix>-<type>-<arch>-<version>-<suffix>.
+	KernelTemplate string `json:"kernel_template,omitempty" yaml:"kernel_template,omitempty"`
+	InitrdTemplate string `json:"initrd_template,omitempty" yaml:"initrd_template,omitempty"`
+	// Directory where the device-tree blobs are installed. Every kernel
+	// has a subdirectory with the kernel release as name.
+	DtbsDir string `json:"dtbs_dir,omitempty" yaml:"dtbs_dir,omitempty"`
+	// Backend used to build the initrd images: dracut, mkinitcpio, booster.
+	InitrdBuilder string `json:"initrd_builder,omitempty" yaml:"initrd_builder,omitempty"`
+	// Options of dracut used for the kernels of the profile. They override
+	// the dracut options of the configuration.
+	DracutArgs string `json:"dracut_args,omitempty" yaml:"dracut_args,omitempty"`
+	// Additional kernel modules to include in the initrd images (dracut only).
+	ExtraModules []string `json:"extra_modules,omitempty" yaml:"extra_modules,omitempty"`
 
 	Regex *regexp.Regexp `json:"-" yaml:"-"`
 }
@@ -41,6 +85,10 @@ type KernelFiles struct {
 	Kernel *KernelImage `json:"kernel,omitempty" yaml:"kernel,omitempty"`
 	Initrd *InitrdImage `json:"initrd,omitempty" yaml:"initrd,omitempty"`
 	Type   *KernelType  `json:"type,omitempty" yaml:"type,omitempty"`
+	// Effective kernel command line.
+	Cmdline string `json:"cmdline,omitempty" yaml:"cmdline,omitempty"`
+	// Early microcode image loaded before the initrd image.
+	Microcode *MicrocodeImage `json:"microcode,omitempty" yaml:"microcode,omitempty"`
 }
 
 type BootFiles struct {
@@ -49,4 +97,8 @@ type BootFiles struct {
 
 	BzImageLink string `json:"bzImage,omitempty" yaml:"bzImage,omitempty"`
 	InitrdLink  string `json:"initrd,omitempty" yaml:"initrd,omitempty"`
+
+	// Links of the previous selection used as fallback.
+	BzImageOldLink string `json:"bzImage_old,omitempty" yaml:"bzImage_old,omitempty"`
+	InitrdOldLink  string `json:"initrd_old,omitempty" yaml:"initrd_old,omitempty"`
 }
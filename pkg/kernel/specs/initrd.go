@@ -1,58 +1,58 @@
 /*
-Copyright © 2021 Macaroni OS Linux
+Copyright © 2021-2026 Macaroni OS Linux
 See AUTHORS and LICENSE for the license details and contributors.
 */
 package kernelspecs
 
 import (
 	"encoding/json"
-	"strings"
 )
 
 func NewInitrdImage() *InitrdImage {
 	return &InitrdImage{}
 }
 
+// Create the initrd image of the kernel image with the
+// values of the kernel profile.
+func NewInitrdImageFromKernel(t *KernelType, k *KernelImage) *InitrdImage {
+	ans := NewInitrdImage()
+	ans.Prefix = t.GetInitrdPrefixSanitized()
+	ans.Version = k.GetVersion()
+	ans.Suffix = t.GetSuffix()
+	ans.KernelType = k.GetType()
+	ans.Arch = k.GetArch()
+	ans.Template = t.InitrdTemplate
+	ans.Filename = ans.GenerateFilename()
+	return ans
+}
+
 func NewInitrdImageFromFile(t *KernelType, file string) (*InitrdImage, error) {
 	ans := NewInitrdImage()
 	ans.Filename = file
 
-	iprefix := t.InitrdPrefix
-	if t.InitrdPrefix == "" {
-		iprefix = "initramfs"
-	}
-
-	ans.Prefix = iprefix
-
-	// Skip prefix + '-'
-	file = file[len(iprefix)+1:]
-
-	if t.Type != "" {
-		ans.KernelType = t.Type
-		file = file[len(t.Type)+1:]
-	}
-
-	words := strings.Split(file, "-")
-	i := 0
-	if t.WithArch {
-		file = file[len(words[i])+1:]
-		ans.Arch = words[i]
-		i += 1
+	if t.InitrdTemplate != "" {
+		f, err := ParseFilenameTemplate(t.InitrdTemplate, file, t.GetInitrdFields())
+		if err != nil {
+			return nil, err
+		}
+		ans.Prefix = f.Prefix
+		ans.KernelType = f.Type
+		ans.Arch = f.Arch
+		ans.Version = f.Version
+		ans.Suffix = f.Suffix
+		ans.Template = t.InitrdTemplate
+		return ans, nil
 	}
 
-	if len(words) > 3 {
-		// POST: the version could contains a suffix
-		ans.Version = words[i] + "-" + words[i+1]
-		file = file[len(words[i])+1+len(words[i+1]):]
-		i += 1
-	} else {
-		ans.Version = words[i]
-		file = file[len(words[i]):]
-	}
-
-	if t.Suffix != "" && file != "" {
-		ans.Suffix = file[1:]
+	f, err := t.parseFilename(t.GetInitrdPrefixSanitized(), file)
+	if err != nil {
+		return nil, err
 	}
+	ans.Prefix = f.Prefix
+	ans.KernelType = f.Type
+	ans.Arch = f.Arch
+	ans.Version = f.Version
+	ans.Suffix = f.Suffix
 
 	return ans, nil
 }
@@ -63,6 +63,7 @@ func (i *InitrdImage) SetKernelType(t string) { i.KernelType = t }
 func (i *InitrdImage) SetArch(a string)       { i.Arch = a }
 func (i *InitrdImage) SetVersion(v string)    { i.Version = v }
 func (i *InitrdImage) SetFilename(f string)   { i.Filename = f }
+func (i *InitrdImage) SetTemplate(t string)   { i.Template = t }
 
 func (i *InitrdImage) GetPrefix() string     { return i.Prefix }
 func (i *InitrdImage) GetSuffix() string     { return i.Suffix }
@@ -96,7 +97,18 @@ func (i *InitrdImage) EqualTo(in *InitrdImage) bool {
 	return true
 }
 
+// Return the filename of the initrd image. The filename is generated
+// with the template of the kernel profile if available.
 func (i *InitrdImage) GenerateFilename() string {
+	if i.Template != "" {
+		return ExpandFilenameTemplate(i.Template, &FilenameFields{
+			Prefix:  i.Prefix,
+			Type:    i.KernelType,
+			Arch:    i.Arch,
+			Version: i.Version,
+			Suffix:  i.Suffix,
+		})
+	}
 
 	iprefix := i.Prefix
 	if i.Prefix == "" {
@@ -1,5 +1,5 @@
 /*
-Copyright © 2021 Macaroni OS Linux
+Copyright © 2021-2026 Macaroni OS Linux
 See AUTHORS and LICENSE for the license details and contributors.
 */
 package kernelspecs
@@ -17,42 +17,29 @@ func NewKernelImageFromFile(t *KernelType, file string) (*KernelImage, error) {
 	ans := NewKernelImage()
 	ans.Filename = file
 
-	kprefix := t.KernelPrefix
-	if t.KernelPrefix == "" {
-		kprefix = "kernel"
-	}
-
-	ans.Prefix = kprefix
-
-	// Skip prefix + '-'
-	file = file[len(kprefix)+1:]
-
-	if t.Type != "" {
-		ans.Type = t.Type
-		file = file[len(t.Type)+1:]
-	}
-
-	words := strings.Split(file, "-")
-	i := 0
-	if t.WithArch {
-		file = file[len(words[i])+1:]
-		ans.Arch = words[i]
-		i += 1
-	}
-
-	if len(words) > 3 {
-		// POST: the version could contains a suffix
-		ans.Version = words[i] + "-" + words[i+1]
-		file = file[len(words[i])+1+len(words[i+1]):]
-		i += 1
-	} else {
-		ans.Version = words[i]
-		file = file[len(words[i]):]
+	if t.KernelTemplate != "" {
+		f, err := ParseFilenameTemplate(t.KernelTemplate, file, t.GetKernelFields())
+		if err != nil {
+			return nil, err
+		}
+		ans.Prefix = f.Prefix
+		ans.Type = f.Type
+		ans.Arch = f.Arch
+		ans.Version = f.Version
+		ans.Suffix = f.Suffix
+		ans.Template = t.KernelTemplate
+		return ans, nil
 	}
 
-	if t.Suffix != "" {
-		ans.Suffix = file[1:]
+	f, err := t.parseFilename(t.GetKernelPrefixSanitized(), file)
+	if err != nil {
+		return nil, err
 	}
+	ans.Prefix = f.Prefix
+	ans.Type = f.Type
+	ans.Arch = f.Arch
+	ans.Version = f.Version
+	ans.Suffix = f.Suffix
 
 	return ans, nil
 }
@@ -63,6 +50,7 @@ func (k *KernelImage) SetVersion(v string)  { k.Version = v }
 func (k *KernelImage) SetArch(v string)     { k.Arch = v }
 func (k *KernelImage) SetType(t string)     { k.Type = t }
 func (k *KernelImage) SetFilename(f string) { k.Filename = f }
+func (k *KernelImage) SetTemplate(t string) { k.Template = t }
 
 func (k *KernelImage) GetPrefix() string   { return k.Prefix }
 func (k *KernelImage) GetSuffix() string   { return k.Suffix }
@@ -71,6 +59,44 @@ func (k *KernelImage) GetArch() string     { return k.Arch }
 func (k *KernelImage) GetType() string     { return k.Type }
 func (k *KernelImage) GetFilename() string { return k.Filename }
 
+// Return the kernel release string as reported by uname -r
+// and used for the /lib/modules directory.
+func (k *KernelImage) GetRelease() string {
+	ans := k.Version
+	if k.Suffix != "" {
+		ans += "-" + k.Suffix
+	}
+	return ans
+}
+
+// Compare the release read from the image header with the release
+// parsed from the filename. If the filename ends with the release of
+// the header the parsed version is corrected, otherwise the mismatch
+// is reported.
+func (k *KernelImage) SetHeaderRelease(release string) {
+	k.HeaderRelease = release
+	k.ReleaseMismatch = false
+
+	if release == "" || release == k.GetRelease() {
+		return
+	}
+
+	if strings.HasSuffix(k.Filename, "-"+release) {
+		if k.Suffix != "" && strings.HasSuffix(release, "-"+k.Suffix) {
+			k.Version = strings.TrimSuffix(release, "-"+k.Suffix)
+		} else {
+			k.Version = release
+			k.Suffix = ""
+		}
+		return
+	}
+
+	k.ReleaseMismatch = true
+}
+
+func (k *KernelImage) GetHeaderRelease() string { return k.HeaderRelease }
+func (k *KernelImage) HasReleaseMismatch() bool { return k.ReleaseMismatch }
+
 func (k *KernelImage) String() string {
 	data, _ := json.Marshal(k)
 	return string(data)
@@ -100,7 +126,18 @@ func (k *KernelImage) EqualTo(i *KernelImage) bool {
 	return true
 }
 
+// Return the filename of the kernel image. The filename is generated
+// with the template of the kernel profile if available.
 func (k *KernelImage) GenerateFilename() string {
+	if k.Template != "" {
+		return ExpandFilenameTemplate(k.Template, &FilenameFields{
+			Prefix:  k.Prefix,
+			Type:    k.Type,
+			Arch:    k.Arch,
+			Version: k.Version,
+			Suffix:  k.Suffix,
+		})
+	}
 
 	kprefix := k.Prefix
 	if k.Prefix == "" {
@@ -0,0 +1,152 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kernelspecs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Placeholders of the filename templates of the kernel profiles.
+// The prefix, the type and the suffix are the values of the profile,
+// the arch and the version are read from the filename.
+const (
+	TemplatePrefix  = "prefix"
+	TemplateType    = "type"
+	TemplateArch    = "arch"
+	TemplateVersion = "version"
+	TemplateSuffix  = "suffix"
+)
+
+var templatePlaceholderRegex = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// Fields of a filename generated by a template.
+type FilenameFields struct {
+	Prefix  string
+	Type    string
+	Arch    string
+	Version string
+	Suffix  string
+}
+
+func (f *FilenameFields) get(placeholder string) string {
+	switch placeholder {
+	case TemplatePrefix:
+		return f.Prefix
+	case TemplateType:
+		return f.Type
+	case TemplateArch:
+		return f.Arch
+	case TemplateVersion:
+		return f.Version
+	case TemplateSuffix:
+		return f.Suffix
+	default:
+		return ""
+	}
+}
+
+// Check that the template uses only the supported placeholders, one
+// time every placeholder, and that the version is present. The template
+// is a path relative to the boot directory and it could contain
+// directories, like the layout of kernel-install.
+// Example: vmlinuz-{version} or {version}/vmlinuz
+func ValidateFilenameTemplate(template string) error {
+	if strings.HasPrefix(template, "/") {
+		return fmt.Errorf("Invalid template %s: the path must be relative to the boot directory", template)
+	}
+	for _, d := range strings.Split(template, "/") {
+		if d == "" || d == "." || d == ".." {
+			return fmt.Errorf("Invalid template %s: invalid path component %s", template, d)
+		}
+	}
+
+	placeholders := make(map[string]bool, 0)
+	for _, m := range templatePlaceholderRegex.FindAllStringSubmatch(template, -1) {
+		switch m[1] {
+		case TemplatePrefix, TemplateType, TemplateArch, TemplateVersion, TemplateSuffix:
+		default:
+			return fmt.Errorf("Invalid template %s: unknown placeholder %s", template, m[0])
+		}
+
+		if placeholders[m[1]] {
+			return fmt.Errorf("Invalid template %s: duplicate placeholder %s", template, m[0])
+		}
+		placeholders[m[1]] = true
+	}
+
+	if !placeholders[TemplateVersion] {
+		return fmt.Errorf("Invalid template %s: missing placeholder {%s}",
+			template, TemplateVersion)
+	}
+
+	return nil
+}
+
+// Generate the filename of the template with the fields in input.
+func ExpandFilenameTemplate(template string, f *FilenameFields) string {
+	return templatePlaceholderRegex.ReplaceAllStringFunc(template, func(p string) string {
+		return f.get(p[1 : len(p)-1])
+	})
+}
+
+// Return the regex of the template. The placeholders of the profile
+// values are replaced with the values of the fields in input.
+func filenameTemplateRegex(template string, f *FilenameFields) string {
+	ans := "^"
+	last := 0
+
+	for _, loc := range templatePlaceholderRegex.FindAllStringSubmatchIndex(template, -1) {
+		ans += regexp.QuoteMeta(template[last:loc[0]])
+
+		switch p := template[loc[2]:loc[3]]; p {
+		case TemplateArch:
+			ans += `(?P<arch>[^-/]+)`
+		case TemplateVersion:
+			ans += `(?P<version>[^/]+)`
+		default:
+			ans += regexp.QuoteMeta(f.get(p))
+		}
+		last = loc[1]
+	}
+
+	return ans + regexp.QuoteMeta(template[last:]) + "$"
+}
+
+// Return the glob pattern of the files of the template. The placeholders
+// of the profile values are replaced with the values of the fields in input.
+func filenameTemplateGlob(template string, f *FilenameFields) string {
+	fields := *f
+	fields.Arch = "*"
+	fields.Version = "*"
+	return ExpandFilenameTemplate(template, &fields)
+}
+
+// Parse the filename with the template. The values of the profile
+// are copied from the fields in input.
+func ParseFilenameTemplate(template, file string, f *FilenameFields) (*FilenameFields, error) {
+	r, err := regexp.Compile(filenameTemplateRegex(template, f))
+	if err != nil {
+		return nil, err
+	}
+
+	match := r.FindStringSubmatch(file)
+	if match == nil {
+		return nil, fmt.Errorf("The file %s doesn't match the template %s", file, template)
+	}
+
+	ans := *f
+	for idx, name := range r.SubexpNames() {
+		switch name {
+		case TemplateArch:
+			ans.Arch = match[idx]
+		case TemplateVersion:
+			ans.Version = match[idx]
+		}
+	}
+
+	return &ans, nil
+}
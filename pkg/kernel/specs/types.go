@@ -1,5 +1,5 @@
 /*
-Copyright © 2021 Macaroni OS Linux
+Copyright © 2021-2026 Macaroni OS Linux
 See AUTHORS and LICENSE for the license details and contributors.
 */
 package kernelspecs
@@ -18,11 +18,39 @@ func (t *KernelType) SetInitrdPrefix(p string) { t.InitrdPrefix = p }
 func (t *KernelType) SetSuffix(s string)       { t.Suffix = s }
 func (t *KernelType) SetType(s string)         { t.Type = s }
 
-func (t *KernelType) GetKernelPrefix() string { return t.KernelPrefix }
-func (t *KernelType) GetInitrdPrefix() string { return t.InitrdPrefix }
-func (t *KernelType) GetSuffix() string       { return t.Suffix }
-func (t *KernelType) GetType() string         { return t.Type }
-func (t *KernelType) GetName() string         { return t.Name }
+func (t *KernelType) GetKernelPrefix() string   { return t.KernelPrefix }
+func (t *KernelType) GetInitrdPrefix() string   { return t.InitrdPrefix }
+func (t *KernelType) GetSuffix() string         { return t.Suffix }
+func (t *KernelType) GetType() string           { return t.Type }
+func (t *KernelType) GetName() string           { return t.Name }
+func (t *KernelType) GetDtbsDir() string        { return t.DtbsDir }
+func (t *KernelType) GetInitrdBuilder() string  { return t.InitrdBuilder }
+func (t *KernelType) GetDracutArgs() string     { return t.DracutArgs }
+func (t *KernelType) GetExtraModules() []string { return t.ExtraModules }
+func (t *KernelType) GetKernelTemplate() string { return t.KernelTemplate }
+func (t *KernelType) GetInitrdTemplate() string { return t.InitrdTemplate }
+
+func (t *KernelType) HasTemplates() bool {
+	return t.KernelTemplate != "" || t.InitrdTemplate != ""
+}
+
+// Return the fields of the kernel images defined by the profile.
+func (t *KernelType) GetKernelFields() *FilenameFields {
+	return &FilenameFields{
+		Prefix: t.GetKernelPrefixSanitized(),
+		Type:   t.Type,
+		Suffix: t.Suffix,
+	}
+}
+
+// Return the fields of the initrd images defined by the profile.
+func (t *KernelType) GetInitrdFields() *FilenameFields {
+	return &FilenameFields{
+		Prefix: t.GetInitrdPrefixSanitized(),
+		Type:   t.Type,
+		Suffix: t.Suffix,
+	}
+}
 
 func (t *KernelType) GetInitrdPrefixSanitized() string {
 	initrdprefix := t.InitrdPrefix
@@ -48,6 +76,14 @@ func (t *KernelType) IsInitrdFile(f string) (bool, error) {
 		return ans, errors.New("Invalid file path")
 	}
 
+	if t.HasTemplates() {
+		r, err := regexp.Compile(t.getInitrdRegex())
+		if err != nil {
+			return ans, err
+		}
+		return r.MatchString(f), nil
+	}
+
 	initrdprefix := t.GetInitrdPrefixSanitized()
 
 	if strings.HasPrefix(f, initrdprefix) {
@@ -63,6 +99,14 @@ func (t *KernelType) IsKernelFile(f string) (bool, error) {
 		return ans, errors.New("Invalid kernel file path")
 	}
 
+	if t.HasTemplates() {
+		r, err := regexp.Compile(t.getKernelRegex())
+		if err != nil {
+			return ans, err
+		}
+		return r.MatchString(f), nil
+	}
+
 	kprefix := t.GetKernelPrefixSanitized()
 
 	if strings.HasPrefix(f, kprefix) {
@@ -72,7 +116,70 @@ func (t *KernelType) IsKernelFile(f string) (bool, error) {
 	return ans, nil
 }
 
+// Parse the filename <prefix>-<type>-<arch>-<version>-<suffix> of the
+// profiles without templates. The type, the arch and the suffix are
+// optional and depend on the profile.
+func (t *KernelType) parseFilename(prefix, file string) (*FilenameFields, error) {
+	ans := &FilenameFields{Prefix: prefix}
+
+	name, ok := strings.CutPrefix(file, prefix+"-")
+	if !ok {
+		return nil, fmt.Errorf("The file %s doesn't start with %s-", file, prefix)
+	}
+
+	if t.Type != "" {
+		ans.Type = t.Type
+		name, ok = strings.CutPrefix(name, t.Type+"-")
+		if !ok {
+			return nil, fmt.Errorf("The file %s doesn't contain the type %s", file, t.Type)
+		}
+	}
+
+	words := strings.Split(name, "-")
+	i := 0
+	if t.WithArch {
+		if len(words) < 2 {
+			return nil, fmt.Errorf("The file %s doesn't contain the arch and the version", file)
+		}
+		name = name[len(words[i])+1:]
+		ans.Arch = words[i]
+		i += 1
+	}
+
+	if t.Suffix != "" && strings.HasSuffix(name, "-"+t.Suffix) &&
+		len(name) > len(t.Suffix)+1 {
+		// POST: the suffix of the profile is always at the end of
+		// the filename. The version is all the rest.
+		ans.Version = name[:len(name)-len(t.Suffix)-1]
+		ans.Suffix = t.Suffix
+		return ans, nil
+	}
+
+	if len(words) > 3 {
+		// POST: the version could contains a suffix
+		ans.Version = words[i] + "-" + words[i+1]
+		name = name[len(words[i])+1+len(words[i+1]):]
+	} else {
+		ans.Version = words[i]
+		name = name[len(words[i]):]
+	}
+
+	if ans.Version == "" {
+		return nil, fmt.Errorf("The file %s doesn't contain the version", file)
+	}
+
+	if t.Suffix != "" && name != "" {
+		ans.Suffix = name[1:]
+	}
+
+	return ans, nil
+}
+
 func (t *KernelType) getKernelRegex() string {
+	if t.KernelTemplate != "" {
+		return filenameTemplateRegex(t.KernelTemplate, t.GetKernelFields())
+	}
+
 	kprefix := t.GetKernelPrefixSanitized()
 
 	ans := fmt.Sprintf("^%s-", kprefix)
@@ -91,6 +198,10 @@ func (t *KernelType) getKernelRegex() string {
 }
 
 func (t *KernelType) getInitrdRegex() string {
+	if t.InitrdTemplate != "" {
+		return filenameTemplateRegex(t.InitrdTemplate, t.GetInitrdFields())
+	}
+
 	initrdprefix := t.GetInitrdPrefixSanitized()
 
 	ans := fmt.Sprintf("^%s-", initrdprefix)
@@ -108,6 +219,21 @@ func (t *KernelType) getInitrdRegex() string {
 	return ans
 }
 
+// Return the glob patterns of the templates with directories. The files
+// of these templates are not in the top level of the boot directory.
+func (t *KernelType) GetTemplatesGlobs() []string {
+	ans := []string{}
+
+	if strings.Contains(t.KernelTemplate, "/") {
+		ans = append(ans, filenameTemplateGlob(t.KernelTemplate, t.GetKernelFields()))
+	}
+	if strings.Contains(t.InitrdTemplate, "/") {
+		ans = append(ans, filenameTemplateGlob(t.InitrdTemplate, t.GetInitrdFields()))
+	}
+
+	return ans
+}
+
 func (t *KernelType) GetRegex() *regexp.Regexp {
 	if t.Regex == nil {
 		regstrk := t.getKernelRegex()
@@ -121,6 +247,41 @@ func (t *KernelType) GetRegex() *regexp.Regexp {
 	return t.Regex
 }
 
+// Check that the kernel and the initrd regexes of the type are valid.
+// GetRegex panics with an invalid regex.
+func (t *KernelType) ValidateRegex() error {
+	for _, tmpl := range []string{t.KernelTemplate, t.InitrdTemplate} {
+		if tmpl == "" {
+			continue
+		}
+		if err := ValidateFilenameTemplate(tmpl); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range []string{t.getKernelRegex(), t.getInitrdRegex()} {
+		if _, err := regexp.Compile(r); err != nil {
+			return fmt.Errorf("Invalid regex %s: %s", r, err.Error())
+		}
+	}
+	return nil
+}
+
+// Return the filename of the initrd image of the kernel image file.
+func (t *KernelType) GetInitrdFilename(kernelFile string) string {
+	if !t.HasTemplates() {
+		return t.GetInitrdPrefixSanitized() + strings.TrimPrefix(
+			kernelFile, t.GetKernelPrefixSanitized())
+	}
+
+	k, err := NewKernelImageFromFile(t, kernelFile)
+	if err != nil {
+		return ""
+	}
+
+	return NewInitrdImageFromKernel(t, k).GenerateFilename()
+}
+
 func KernelTypeFromYaml(data []byte) (*KernelType, error) {
 	ans := &KernelType{}
 	if err := yaml.Unmarshal(data, ans); err != nil {
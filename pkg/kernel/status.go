@@ -0,0 +1,190 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kernel
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+)
+
+const (
+	KernelStatusOk      = "ok"
+	KernelStatusNearEol = "near-eol"
+	KernelStatusEol     = "eol"
+	KernelStatusUnknown = "unknown"
+
+	DefaultNearEolDays = 90
+)
+
+type KernelStatus struct {
+	Package  string `json:"package" yaml:"package"`
+	Version  string `json:"version" yaml:"version"`
+	Kernel   string `json:"kernel" yaml:"kernel"`
+	Type     string `json:"type,omitempty" yaml:"type,omitempty"`
+	Lts      bool   `json:"lts" yaml:"lts"`
+	EoL      string `json:"eol,omitempty" yaml:"eol,omitempty"`
+	EoLDate  string `json:"eol_date,omitempty" yaml:"eol_date,omitempty"`
+	DaysLeft int    `json:"days_left,omitempty" yaml:"days_left,omitempty"`
+	Status   string `json:"status" yaml:"status"`
+	Message  string `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+type KernelsStatus struct {
+	Kernels []*KernelStatus `json:"kernels" yaml:"kernels"`
+}
+
+var eolLayouts = []string{
+	"Jan, 2006",
+	"Jan 2006",
+	"January, 2006",
+	"January 2006",
+	"2006-01",
+	"01/2006",
+}
+
+var eolDayLayouts = []string{
+	"2006-01-02",
+	"Jan 2, 2006",
+	"2 Jan 2006",
+}
+
+// Parse the EoL string of the kernel annotation. The strings with
+// only month and year (Dec, 2026) are converted to the last day of the month.
+func ParseEoL(eol string) (time.Time, error) {
+	eol = strings.TrimSpace(eol)
+
+	for _, layout := range eolDayLayouts {
+		t, err := time.Parse(layout, eol)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	for _, layout := range eolLayouts {
+		t, err := time.Parse(layout, eol)
+		if err == nil {
+			return t.AddDate(0, 1, -1), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("Invalid EoL string %q", eol)
+}
+
+func NewKernelStatus(s *specs.Stone, a *specs.KernelAnnotation) *KernelStatus {
+	ans := &KernelStatus{
+		Package: s.GetName(),
+		Version: s.GetVersion(),
+		Kernel:  s.GetLabelValue("package.version"),
+		Type:    s.GetLabelValue("kernel.type"),
+		Status:  KernelStatusUnknown,
+	}
+
+	if a != nil {
+		ans.Lts = a.Lts
+		ans.EoL = a.EoL
+		if ans.Type == "" {
+			ans.Type = a.Type
+		}
+	}
+
+	return ans
+}
+
+// Evaluate the status of the kernel against the date in input.
+func (k *KernelStatus) Evaluate(now time.Time, nearDays int) {
+	if k.EoL == "" {
+		k.Status = KernelStatusUnknown
+		k.Message = "No EoL available."
+		return
+	}
+
+	eol, err := ParseEoL(k.EoL)
+	if err != nil {
+		k.Status = KernelStatusUnknown
+		k.Message = err.Error()
+		return
+	}
+
+	k.EoLDate = eol.Format("2006-01-02")
+	// The kernel is supported until the end of the EoL day.
+	end := eol.AddDate(0, 0, 1)
+	k.DaysLeft = int(end.Sub(now).Hours() / 24)
+
+	switch {
+	case !now.Before(end):
+		k.Status = KernelStatusEol
+		k.DaysLeft = 0
+		k.Message = "The kernel is EOL."
+	case k.DaysLeft <= nearDays:
+		k.Status = KernelStatusNearEol
+		k.Message = fmt.Sprintf("The kernel will be EOL in %d days.", k.DaysLeft)
+	default:
+		k.Status = KernelStatusOk
+		k.Message = ""
+	}
+}
+
+func (k *KernelsStatus) String() string {
+	data, _ := json.Marshal(k)
+	return string(data)
+}
+
+// Return the exit code to use for monitoring: 0 if all kernels
+// are ok, 1 if there are kernels near EOL, 2 if there are kernels EOL.
+// The kernels without EOL information are ignored.
+func (k *KernelsStatus) ExitCode() int {
+	ans := 0
+	for _, s := range k.Kernels {
+		switch s.Status {
+		case KernelStatusEol:
+			return 2
+		case KernelStatusNearEol:
+			ans = 1
+		}
+	}
+	return ans
+}
+
+// Join the installed kernels with the annotations of the kernels
+// available in the repositories. The annotations of the repositories
+// are preferred because the EoL could be updated after the installation.
+func InstalledKernelsStatus(installed, availables *specs.StonesPack,
+	now time.Time, nearDays int) *KernelsStatus {
+
+	ans := &KernelsStatus{
+		Kernels: []*KernelStatus{},
+	}
+
+	amap := make(map[string]*specs.Stone, 0)
+	if availables != nil {
+		for idx, s := range availables.Stones {
+			if _, present := amap[s.GetName()]; !present {
+				amap[s.GetName()] = availables.Stones[idx]
+			}
+		}
+	}
+
+	for _, s := range installed.Stones {
+		var a *specs.KernelAnnotation = nil
+		if as, ok := amap[s.GetName()]; ok {
+			a, _ = ParseKernelAnnotations(as)
+		}
+		if a == nil || a.EoL == "" {
+			if ia, err := ParseKernelAnnotations(s); err == nil {
+				a = ia
+			}
+		}
+
+		status := NewKernelStatus(s, a)
+		status.Evaluate(now, nearDays)
+		ans.Kernels = append(ans.Kernels, status)
+	}
+
+	return ans
+}
@@ -0,0 +1,57 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kernel_test
+
+import (
+	"time"
+
+	. "github.com/macaroni-os/macaronictl/pkg/kernel"
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Kernel Status Test", func() {
+
+	Context("EoL", func() {
+
+		It("Parse month and year", func() {
+			t, err := ParseEoL("Dec, 2026")
+			Expect(err).Should(BeNil())
+			Expect(t.Format("2006-01-02")).To(Equal("2026-12-31"))
+		})
+
+		It("Parse invalid string", func() {
+			_, err := ParseEoL("soon")
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("Installed kernels", func() {
+			now := time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC)
+			installed := &specs.StonesPack{
+				Stones: []*specs.Stone{
+					newTestStone("kernel-6.1", "macaroni-full", "6.1.12",
+						&specs.KernelAnnotation{Lts: true, EoL: "Dec, 2026"}),
+					newTestStone("kernel-6.1", "macaroni-minimal", "5.10.1",
+						&specs.KernelAnnotation{Lts: true, EoL: "Sep, 2026"}),
+					newTestStone("kernel-6.1", "zen-full", "6.6.1",
+						&specs.KernelAnnotation{Lts: true, EoL: "Dec, 2027"}),
+					newTestStone("kernel-6.1", "test-full", "6.7.1",
+						&specs.KernelAnnotation{Lts: true}),
+				},
+			}
+
+			status := InstalledKernelsStatus(installed, nil, now, DefaultNearEolDays)
+			Expect(len(status.Kernels)).To(Equal(4))
+			Expect(status.Kernels[0].Status).To(Equal(KernelStatusNearEol))
+			Expect(status.Kernels[0].DaysLeft).To(Equal(75))
+			Expect(status.Kernels[1].Status).To(Equal(KernelStatusEol))
+			Expect(status.Kernels[2].Status).To(Equal(KernelStatusOk))
+			Expect(status.Kernels[3].Status).To(Equal(KernelStatusUnknown))
+			Expect(status.ExitCode()).To(Equal(2))
+		})
+	})
+})
@@ -0,0 +1,49 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kernel_test
+
+import (
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+)
+
+// Create a package of the tests. The kernel packages have the kernel
+// annotation, with macaroni and vanilla as default suffix and type.
+// Without annotation the package is an extra module of the vanilla kernels.
+func newTestStone(category, name, version string, a *specs.KernelAnnotation) *specs.Stone {
+	ans := &specs.Stone{
+		Name:     name,
+		Category: category,
+		Version:  version,
+	}
+
+	if a == nil {
+		ans.Labels = map[string]string{
+			"kernel.type": "vanilla",
+		}
+		return ans
+	}
+
+	suffix, kType := a.Suffix, a.Type
+	if suffix == "" {
+		suffix = "macaroni"
+	}
+	if kType == "" {
+		kType = "vanilla"
+	}
+
+	ans.Labels = map[string]string{
+		"package.version": version,
+	}
+	ans.Annotations = map[string]interface{}{
+		"kernel": map[string]interface{}{
+			"eol":    a.EoL,
+			"lts":    a.Lts,
+			"suffix": suffix,
+			"type":   kType,
+		},
+	}
+
+	return ans
+}
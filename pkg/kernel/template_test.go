@@ -0,0 +1,166 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kernel_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/macaroni-os/macaronictl/pkg/kernel"
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Filename Templates Test", func() {
+
+	debian := kernelspecs.KernelType{
+		Name:           "Debian",
+		KernelPrefix:   "vmlinuz",
+		InitrdPrefix:   "initrd.img",
+		Type:           "debian",
+		KernelTemplate: "{prefix}-{version}",
+		InitrdTemplate: "{prefix}-{version}",
+	}
+
+	Context("Templates", func() {
+
+		It("Validate templates", func() {
+			Expect(kernelspecs.ValidateFilenameTemplate("vmlinuz-{version}")).Should(BeNil())
+			Expect(kernelspecs.ValidateFilenameTemplate("vmlinuz-{arch}")).ShouldNot(BeNil())
+			Expect(kernelspecs.ValidateFilenameTemplate("vmlinuz-{release}")).ShouldNot(BeNil())
+			Expect(kernelspecs.ValidateFilenameTemplate(
+				"{version}-{version}")).ShouldNot(BeNil())
+			Expect(kernelspecs.ValidateFilenameTemplate("{version}/vmlinuz")).Should(BeNil())
+			Expect(kernelspecs.ValidateFilenameTemplate(
+				"/lib/modules/{version}/vmlinuz")).ShouldNot(BeNil())
+			Expect(kernelspecs.ValidateFilenameTemplate("../{version}/vmlinuz")).ShouldNot(BeNil())
+		})
+
+		It("Parse and generate filenames", func() {
+			t := kernelspecs.KernelType{
+				Name:           "Macaroni",
+				Suffix:         "macaroni",
+				Type:           "vanilla",
+				KernelTemplate: "{prefix}-{type}-{arch}-{version}-{suffix}",
+				InitrdTemplate: "{prefix}-{type}-{arch}-{version}-{suffix}.img",
+			}
+
+			k, err := kernelspecs.NewKernelImageFromFile(&t,
+				"kernel-vanilla-x86_64-6.1.12-rc1-macaroni")
+			Expect(err).Should(BeNil())
+			Expect(k.GetArch()).To(Equal("x86_64"))
+			Expect(k.GetVersion()).To(Equal("6.1.12-rc1"))
+			Expect(k.GetSuffix()).To(Equal("macaroni"))
+			Expect(k.GenerateFilename()).To(Equal("kernel-vanilla-x86_64-6.1.12-rc1-macaroni"))
+
+			i := kernelspecs.NewInitrdImageFromKernel(&t, k)
+			Expect(i.GenerateFilename()).To(Equal(
+				"initramfs-vanilla-x86_64-6.1.12-rc1-macaroni.img"))
+
+			i2, err := kernelspecs.NewInitrdImageFromFile(&t, i.GenerateFilename())
+			Expect(err).Should(BeNil())
+			Expect(i2.EqualTo(i)).To(BeTrue())
+
+			isInitrd, err := t.IsInitrdFile(i.GenerateFilename())
+			Expect(err).Should(BeNil())
+			Expect(isInitrd).To(BeTrue())
+		})
+
+		It("Reject invalid filenames without templates", func() {
+			t := kernelspecs.KernelType{
+				Name:     "Macaroni",
+				Suffix:   "macaroni",
+				Type:     "vanilla",
+				WithArch: true,
+			}
+
+			for _, file := range []string{
+				"kernel", "kernel-", "kernel-vanilla", "kernel-vanilla-",
+				"kernel-vanilla-x86_64", "kernel-vanilla-x86_64-", "kernel-zen-x86_64-6.1",
+			} {
+				_, err := kernelspecs.NewKernelImageFromFile(&t, file)
+				Expect(err).ShouldNot(BeNil(), file)
+			}
+			_, err := kernelspecs.NewInitrdImageFromFile(&t, "initramfs-vanilla-x86_64")
+			Expect(err).ShouldNot(BeNil())
+
+			k, err := kernelspecs.NewKernelImageFromFile(&t, "kernel-vanilla-x86_64-6.1.12-macaroni")
+			Expect(err).Should(BeNil())
+			Expect(k.GetVersion()).To(Equal("6.1.12"))
+			Expect(k.GetSuffix()).To(Equal("macaroni"))
+		})
+
+		It("Read boot dir", func() {
+			tmpdir, err := os.MkdirTemp("", "macaronictl-template")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+
+			Expect(writeBzImage(filepath.Join(tmpdir, "vmlinuz-6.1.0-13-amd64"),
+				"6.1.0-13-amd64")).Should(BeNil())
+			Expect(os.WriteFile(filepath.Join(tmpdir, "initrd.img-6.1.0-13-amd64"),
+				[]byte("initrd"), 0644)).Should(BeNil())
+			Expect(os.WriteFile(filepath.Join(tmpdir, "config-6.1.0-13-amd64"),
+				[]byte("CONFIG_X86=y\n"), 0644)).Should(BeNil())
+
+			bootFiles, err := ReadBootDir(tmpdir, []kernelspecs.KernelType{debian})
+			Expect(err).Should(BeNil())
+			Expect(len(bootFiles.Files)).To(Equal(1))
+
+			kf := bootFiles.Files[0]
+			Expect(kf.Kernel).ToNot(BeNil())
+			Expect(kf.Initrd).ToNot(BeNil())
+			Expect(kf.Kernel.GetVersion()).To(Equal("6.1.0-13-amd64"))
+			Expect(kf.Kernel.GetRelease()).To(Equal("6.1.0-13-amd64"))
+			Expect(kf.Kernel.HasReleaseMismatch()).To(BeFalse())
+			Expect(kf.Initrd.GenerateFilename()).To(Equal("initrd.img-6.1.0-13-amd64"))
+		})
+
+		It("Read boot dir with directories", func() {
+			tmpdir, err := os.MkdirTemp("", "macaronictl-template")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+
+			t := kernelspecs.KernelType{
+				Name:           "Kernel Install",
+				Type:           "bls",
+				KernelTemplate: "{version}/vmlinuz",
+				InitrdTemplate: "{version}/initrd",
+			}
+			Expect(t.ValidateRegex()).Should(BeNil())
+
+			for _, release := range []string{"6.1.12", "6.6.1"} {
+				Expect(os.MkdirAll(filepath.Join(tmpdir, release), 0755)).Should(BeNil())
+				Expect(writeBzImage(filepath.Join(tmpdir, release, "vmlinuz"),
+					release)).Should(BeNil())
+			}
+			Expect(os.WriteFile(filepath.Join(tmpdir, "6.6.1", "initrd"),
+				[]byte("initrd"), 0644)).Should(BeNil())
+			Expect(os.WriteFile(filepath.Join(tmpdir, "6.6.1", "config"),
+				[]byte("CONFIG_X86=y\n"), 0644)).Should(BeNil())
+
+			bootFiles, err := ReadBootDir(tmpdir, []kernelspecs.KernelType{t})
+			Expect(err).Should(BeNil())
+			Expect(len(bootFiles.Files)).To(Equal(2))
+
+			files := map[string]*kernelspecs.KernelFiles{}
+			for _, kf := range bootFiles.Files {
+				Expect(kf.Kernel).ToNot(BeNil())
+				files[kf.Kernel.GetVersion()] = kf
+			}
+
+			Expect(files["6.1.12"].Kernel.GetFilename()).To(Equal("6.1.12/vmlinuz"))
+			Expect(files["6.1.12"].Initrd).To(BeNil())
+			Expect(files["6.6.1"].Kernel.GetFilename()).To(Equal("6.6.1/vmlinuz"))
+			Expect(files["6.6.1"].Kernel.HasReleaseMismatch()).To(BeFalse())
+			Expect(files["6.6.1"].Initrd).ToNot(BeNil())
+			Expect(files["6.6.1"].Initrd.GetFilename()).To(Equal("6.6.1/initrd"))
+			Expect(t.GetInitrdFilename("6.1.12/vmlinuz")).To(Equal("6.1.12/initrd"))
+		})
+
+	})
+
+})
@@ -0,0 +1,180 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kernel
+
+import (
+	"strconv"
+	"strings"
+
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+
+	semver "github.com/Masterminds/semver/v3"
+)
+
+type KernelUpgrade struct {
+	Installed *specs.Stone   `json:"installed" yaml:"installed"`
+	Candidate *specs.Stone   `json:"candidate" yaml:"candidate"`
+	Modules   []*specs.Stone `json:"modules,omitempty" yaml:"modules,omitempty"`
+	// The extra modules installed for the branch of the installed kernel.
+	InstalledModules []*specs.Stone `json:"installed_modules,omitempty" yaml:"installed_modules,omitempty"`
+	Type             string         `json:"type,omitempty" yaml:"type,omitempty"`
+	Suffix           string         `json:"suffix,omitempty" yaml:"suffix,omitempty"`
+}
+
+// Return true if the candidate is in a different branch of the
+// installed kernel.
+func (u *KernelUpgrade) IsBranchChange() bool {
+	return u.Installed.Category != u.Candidate.Category
+}
+
+// Return the installed modules replaced by the selected modules.
+func (u *KernelUpgrade) GetReplacedModules() []*specs.Stone {
+	ans := []*specs.Stone{}
+	for _, m := range u.Modules {
+		for idx, im := range u.InstalledModules {
+			if im.Name == m.Name {
+				ans = append(ans, u.InstalledModules[idx])
+				break
+			}
+		}
+	}
+	return ans
+}
+
+// Compare two versions with semver. When the versions are equal
+// the build metadata used for the package revision (6.1.12+2) is
+// compared as number. The versions not semver compliant are
+// compared as strings.
+func CompareVersions(a, b string) int {
+	va, errA := semver.NewVersion(a)
+	vb, errB := semver.NewVersion(b)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+
+	ans := va.Compare(vb)
+	if ans != 0 || va.Metadata() == vb.Metadata() {
+		return ans
+	}
+
+	ma, errA := strconv.Atoi(va.Metadata())
+	mb, errB := strconv.Atoi(vb.Metadata())
+	if errA != nil || errB != nil {
+		return strings.Compare(va.Metadata(), vb.Metadata())
+	}
+
+	switch {
+	case ma < mb:
+		return -1
+	case ma > mb:
+		return 1
+	}
+	return 0
+}
+
+// Compare two kernel packages by the kernel version and then
+// by the package version.
+func CompareKernelStones(a, b *specs.Stone) int {
+	ans := CompareVersions(kernelStoneVersion(a), kernelStoneVersion(b))
+	if ans != 0 {
+		return ans
+	}
+	return CompareVersions(a.GetVersion(), b.GetVersion())
+}
+
+func kernelStoneVersion(s *specs.Stone) string {
+	ans := s.GetLabelValue("package.version")
+	if ans == "" {
+		ans = s.GetVersion()
+	}
+	return ans
+}
+
+// Search the upgrade candidate of the installed kernel between the
+// available kernels with the same name, suffix and type. Without lts
+// the candidate is the highest version of the same category, with lts
+// the highest version of the LTS branches. It returns nil if there
+// aren't newer versions.
+func FindUpgradeCandidate(installed *specs.Stone, availables *specs.StonesPack,
+	lts bool) (*specs.Stone, error) {
+
+	ia, err := ParseKernelAnnotations(installed)
+	if err != nil {
+		return nil, err
+	}
+
+	var ans *specs.Stone = nil
+	for idx, s := range availables.Stones {
+		if s.Name != installed.Name {
+			continue
+		}
+
+		if !lts && s.Category != installed.Category {
+			continue
+		}
+
+		a, err := ParseKernelAnnotations(s)
+		if err != nil {
+			return nil, err
+		}
+
+		if a.Suffix != ia.Suffix || a.Type != ia.Type {
+			continue
+		}
+
+		if lts && !a.Lts {
+			continue
+		}
+
+		if CompareKernelStones(s, installed) <= 0 {
+			continue
+		}
+
+		if ans == nil || CompareKernelStones(s, ans) > 0 {
+			ans = availables.Stones[idx]
+		}
+	}
+
+	return ans, nil
+}
+
+// Select the extra modules to install with the candidate kernel.
+// For every installed module is selected the highest version with
+// the same name available for the candidate branch. On upgrade of
+// the same branch are selected only the modules with a newer version.
+func SelectUpgradeModules(installedMods, availableMods *specs.StonesPack,
+	sameBranch bool) []*specs.Stone {
+
+	imap := make(map[string]*specs.Stone, 0)
+	for idx, s := range installedMods.Stones {
+		imap[s.Name] = installedMods.Stones[idx]
+	}
+
+	amap := make(map[string]*specs.Stone, 0)
+	names := []string{}
+	for idx, s := range availableMods.Stones {
+		if _, present := imap[s.Name]; !present {
+			continue
+		}
+
+		if m, present := amap[s.Name]; !present {
+			names = append(names, s.Name)
+			amap[s.Name] = availableMods.Stones[idx]
+		} else if CompareVersions(s.GetVersion(), m.GetVersion()) > 0 {
+			amap[s.Name] = availableMods.Stones[idx]
+		}
+	}
+
+	ans := []*specs.Stone{}
+	for _, name := range names {
+		m := amap[name]
+		if sameBranch && CompareVersions(m.GetVersion(), imap[name].GetVersion()) <= 0 {
+			continue
+		}
+		ans = append(ans, m)
+	}
+
+	return ans
+}
@@ -0,0 +1,97 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kernel_test
+
+import (
+	. "github.com/macaroni-os/macaronictl/pkg/kernel"
+	specs "github.com/macaroni-os/macaronictl/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Kernel Upgrade", func() {
+
+	Context("Versions", func() {
+
+		It("Compare semver", func() {
+			Expect(CompareVersions("6.1.9", "6.1.12")).To(Equal(-1))
+			Expect(CompareVersions("6.10", "6.9.5")).To(Equal(1))
+			Expect(CompareVersions("6.1.12", "6.1.12")).To(Equal(0))
+		})
+
+		It("Compare package revision", func() {
+			Expect(CompareVersions("6.1.12+2", "6.1.12+10")).To(Equal(-1))
+			Expect(CompareVersions("6.1.12+1", "6.1.12")).To(Equal(1))
+		})
+
+	})
+
+	Context("Candidates", func() {
+
+		installed := newTestStone("kernel-6.1", "macaroni-full", "6.1.9",
+			&specs.KernelAnnotation{Lts: true})
+		availables := &specs.StonesPack{
+			Stones: []*specs.Stone{
+				newTestStone("kernel-6.1", "macaroni-full", "6.1.12", &specs.KernelAnnotation{Lts: true}),
+				newTestStone("kernel-6.1", "macaroni-full", "6.1.10", &specs.KernelAnnotation{Lts: true}),
+				newTestStone("kernel-6.6", "macaroni-full", "6.6.5", &specs.KernelAnnotation{Lts: true}),
+				newTestStone("kernel-6.10", "macaroni-full", "6.10.2", &specs.KernelAnnotation{Lts: false}),
+			},
+		}
+
+		It("Same branch", func() {
+			c, err := FindUpgradeCandidate(installed, availables, false)
+			Expect(err).Should(BeNil())
+			Expect(c).ShouldNot(BeNil())
+			Expect(c.HumanReadableString()).To(Equal("kernel-6.1/macaroni-full-6.1.12"))
+		})
+
+		It("LTS branch", func() {
+			c, err := FindUpgradeCandidate(installed, availables, true)
+			Expect(err).Should(BeNil())
+			Expect(c).ShouldNot(BeNil())
+			Expect(c.HumanReadableString()).To(Equal("kernel-6.6/macaroni-full-6.6.5"))
+		})
+
+		It("No upgrades", func() {
+			installed := newTestStone("kernel-6.10", "macaroni-full", "6.10.2",
+				&specs.KernelAnnotation{Lts: false})
+			c, err := FindUpgradeCandidate(installed, availables, true)
+			Expect(err).Should(BeNil())
+			Expect(c).Should(BeNil())
+		})
+
+	})
+
+	Context("Modules", func() {
+
+		It("Select modules", func() {
+			installedMods := &specs.StonesPack{
+				Stones: []*specs.Stone{
+					{Name: "zfs-kmod", Category: "kernel-6.1", Version: "2.2.2"},
+					{Name: "nvidia-kmod", Category: "kernel-6.1", Version: "550.1"},
+				},
+			}
+			availableMods := &specs.StonesPack{
+				Stones: []*specs.Stone{
+					{Name: "zfs-kmod", Category: "kernel-6.1", Version: "2.2.10"},
+					{Name: "zfs-kmod", Category: "kernel-6.1", Version: "2.2.3"},
+					{Name: "nvidia-kmod", Category: "kernel-6.1", Version: "550.1"},
+					{Name: "vbox-kmod", Category: "kernel-6.1", Version: "7.0.1"},
+				},
+			}
+
+			mods := SelectUpgradeModules(installedMods, availableMods, true)
+			Expect(len(mods)).To(Equal(1))
+			Expect(mods[0].Version).To(Equal("2.2.10"))
+
+			mods = SelectUpgradeModules(installedMods, availableMods, false)
+			Expect(len(mods)).To(Equal(2))
+		})
+
+	})
+
+})
@@ -1,17 +1,29 @@
 /*
-Copyright © 2021 Macaroni OS Linux
+Copyright © 2021-2026 Macaroni OS Linux
 See AUTHORS and LICENSE for the license details and contributors.
 */
 package profile
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
 	"path"
 	"regexp"
+	"strings"
 
+	"github.com/macaroni-os/macaronictl/pkg/initrd"
 	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+	"github.com/macaroni-os/macaronictl/pkg/logger"
+
+	"gopkg.in/yaml.v3"
 )
 
+var regexProfileFile = regexp.MustCompile(`.yml$|.yaml$`)
+
 func GetDefaultKernelProfiles() []kernelspecs.KernelType {
 	return []kernelspecs.KernelType{
 		{
@@ -35,12 +47,97 @@ func GetDefaultKernelProfiles() []kernelspecs.KernelType {
 	}
 }
 
+func warning(msg ...interface{}) {
+	log := logger.GetDefaultLogger()
+	if log != nil {
+		log.Warning(msg...)
+	} else {
+		fmt.Println(append([]interface{}{"WARN:"}, msg...)...)
+	}
+}
+
+// Return true if the file is a kernel profile file.
+func IsKernelProfileFile(file string) bool {
+	return regexProfileFile.MatchString(file)
+}
+
+// Return the errors of the kernel profile.
+func ValidateKernelType(t *kernelspecs.KernelType) []error {
+	ans := []error{}
+
+	if t.GetName() == "" {
+		ans = append(ans, errors.New("Missing mandatory field name"))
+	}
+
+	if err := t.ValidateRegex(); err != nil {
+		ans = append(ans, err)
+	}
+
+	if t.GetInitrdBuilder() != "" {
+		if _, err := initrd.NewInitrdBuilder(t.GetInitrdBuilder(), "", true); err != nil {
+			ans = append(ans, err)
+		}
+	}
+
+	return ans
+}
+
+// Return the errors of the kernel profile file. Unlike the loading of
+// the profiles the unknown fields are reported as errors.
+func ValidateKernelProfileFile(file string) []error {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return []error{err}
+	}
+
+	ans := []error{}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	err = decoder.Decode(&kernelspecs.KernelType{})
+	if err != nil && err != io.EOF {
+		ans = append(ans, err)
+	}
+
+	ktype, err := kernelspecs.KernelTypeFromYaml(content)
+	if err != nil {
+		if len(ans) == 0 {
+			ans = append(ans, err)
+		}
+		return ans
+	}
+
+	return append(ans, ValidateKernelType(ktype)...)
+}
+
+// Read the kernel profile file and check if it's valid.
+func LoadKernelProfileFile(file string) (*kernelspecs.KernelType, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	ans, err := kernelspecs.KernelTypeFromYaml(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if errs := ValidateKernelType(ans); len(errs) > 0 {
+		msgs := []string{}
+		for _, e := range errs {
+			msgs = append(msgs, e.Error())
+		}
+		return nil, errors.New(strings.Join(msgs, ", "))
+	}
+
+	return ans, nil
+}
+
+// Load the kernel profiles of the directory. The files not
+// valid are skipped with a warning.
 func LoadKernelProfiles(dir string) ([]kernelspecs.KernelType, error) {
 	ans := []kernelspecs.KernelType{}
 
-	var regexRepo = regexp.MustCompile(`.yml$|.yaml$`)
-	var err error
-
 	files, err := ioutil.ReadDir(dir)
 	if err != nil {
 		return ans, err
@@ -51,25 +148,59 @@ func LoadKernelProfiles(dir string) ([]kernelspecs.KernelType, error) {
 			continue
 		}
 
-		if !regexRepo.MatchString(file.Name()) {
+		if !IsKernelProfileFile(file.Name()) {
 			continue
 		}
 
-		content, err := ioutil.ReadFile(path.Join(dir, file.Name()))
+		ktype, err := LoadKernelProfileFile(path.Join(dir, file.Name()))
 		if err != nil {
-			// TODO: integrate warning logger
+			warning(fmt.Sprintf("Skipping kernel profile %s: %s",
+				path.Join(dir, file.Name()), err.Error()))
 			continue
 		}
 
-		ktype, err := kernelspecs.KernelTypeFromYaml(content)
+		ans = append(ans, *ktype)
+	}
+
+	return ans, nil
+}
+
+// Load the kernel profiles of the directories layered over the default
+// profiles. The profiles of a directory replace the profiles with the
+// same name of the previous directories and of the defaults, and they
+// are matched before them on the analysis of the boot files.
+// The directories not present are ignored.
+func LoadLayeredKernelProfiles(dirs []string) []kernelspecs.KernelType {
+	layers := [][]kernelspecs.KernelType{GetDefaultKernelProfiles()}
+
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+
+		types, err := LoadKernelProfiles(dir)
 		if err != nil {
+			if !os.IsNotExist(err) {
+				warning(fmt.Sprintf("Error on read kernel profiles directory %s: %s",
+					dir, err.Error()))
+			}
 			continue
 		}
+		layers = append(layers, types)
+	}
 
-		if ktype.GetType() != "" {
-			ans = append(ans, *ktype)
+	ans := []kernelspecs.KernelType{}
+	names := make(map[string]bool, 0)
+
+	for idx := len(layers) - 1; idx >= 0; idx-- {
+		for _, t := range layers[idx] {
+			if names[t.GetName()] {
+				continue
+			}
+			names[t.GetName()] = true
+			ans = append(ans, t)
 		}
 	}
 
-	return ans, nil
+	return ans
 }
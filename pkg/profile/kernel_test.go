@@ -0,0 +1,78 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package profile_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/macaroni-os/macaronictl/pkg/profile"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Kernel Profiles Test", func() {
+
+	Context("Layered profiles", func() {
+
+		It("Load profiles over the defaults", func() {
+			tmpdir, err := os.MkdirTemp("", "macaronictl-profiles")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+
+			base := filepath.Join(tmpdir, "base")
+			extra := filepath.Join(tmpdir, "extra")
+			Expect(os.MkdirAll(base, 0755)).Should(BeNil())
+			Expect(os.MkdirAll(extra, 0755)).Should(BeNil())
+
+			Expect(os.WriteFile(filepath.Join(base, "lts.yml"), []byte(
+				"name: Macaroni LTS\ntype: lts\nsuffix: macaroni\nwith_arch: true\n"),
+				0644)).Should(BeNil())
+			Expect(os.WriteFile(filepath.Join(base, "notype.yml"), []byte(
+				"name: Without Type\nsuffix: macaroni\n"), 0644)).Should(BeNil())
+			Expect(os.WriteFile(filepath.Join(extra, "macaroni.yaml"), []byte(
+				"name: Macaroni\ntype: vanilla\nsuffix: custom\n"), 0644)).Should(BeNil())
+
+			types := LoadLayeredKernelProfiles([]string{
+				base, extra, filepath.Join(tmpdir, "missing"),
+			})
+
+			names := []string{}
+			for _, t := range types {
+				names = append(names, t.GetName())
+			}
+			Expect(names).To(Equal([]string{
+				"Macaroni", "Macaroni LTS", "Without Type", "Sabayon", "Macaroni Zen Kernel",
+			}))
+			Expect(types[0].GetSuffix()).To(Equal("custom"))
+			// The type is optional.
+			Expect(types[2].GetType()).To(Equal(""))
+		})
+
+	})
+
+	Context("Validation", func() {
+
+		It("Report the errors of the profile file", func() {
+			tmpdir, err := os.MkdirTemp("", "macaronictl-profiles")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+
+			file := filepath.Join(tmpdir, "bad.yml")
+			Expect(os.WriteFile(file, []byte(
+				"name: Bad\ntype: vanilla\nsuffix: \"c++(\"\ninitrd_builder: foo\nunknown: 1\n"),
+				0644)).Should(BeNil())
+
+			errs := ValidateKernelProfileFile(file)
+			Expect(len(errs)).To(Equal(3))
+
+			_, err = LoadKernelProfileFile(file)
+			Expect(err).ShouldNot(BeNil())
+		})
+
+	})
+
+})
@@ -0,0 +1,202 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package secureboot
+
+import (
+	"crypto"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/macaroni-os/macaronictl/pkg/utils/pecoff"
+)
+
+const (
+	winCertRevision2  = 0x0200
+	winCertTypePKCS7  = 0x0002
+	winCertHeaderSize = 8
+	winCertAlignment  = 8
+)
+
+var ErrNotPEImage = pecoff.ErrNotPEImage
+
+type peSection struct {
+	Offset uint32
+	Size   uint32
+}
+
+// PE/COFF image with the offsets of the fields excluded
+// from the Authenticode digest.
+type PEImage struct {
+	Data []byte
+
+	checksumOffset  int
+	certDirOffset   int
+	sizeOfHeaders   int
+	sections        []peSection
+	CertTableOffset uint32
+	CertTableSize   uint32
+}
+
+func align8(n int) int {
+	return (n + winCertAlignment - 1) &^ (winCertAlignment - 1)
+}
+
+func ParsePEImage(data []byte) (*PEImage, error) {
+	layout, err := pecoff.ParseLayout(data)
+	if err != nil {
+		return nil, err
+	}
+
+	ans := &PEImage{
+		Data:           data,
+		checksumOffset: layout.ChecksumOffset(),
+		certDirOffset:  layout.DataDirEntryOffset(pecoff.CertTableIndex),
+		sizeOfHeaders:  int(layout.SizeOfHeaders),
+		sections:       []peSection{},
+	}
+
+	if ans.certDirOffset < 0 {
+		return nil, errors.New("No certificate table directory available")
+	}
+	ans.CertTableOffset = binary.LittleEndian.Uint32(data[ans.certDirOffset:])
+	ans.CertTableSize = binary.LittleEndian.Uint32(data[ans.certDirOffset+4:])
+
+	if ans.CertTableSize > 0 &&
+		int(ans.CertTableOffset)+int(ans.CertTableSize) > len(data) {
+		return nil, errors.New("Invalid certificate table")
+	}
+
+	if ans.sizeOfHeaders > len(data) || ans.sizeOfHeaders < ans.certDirOffset+8 {
+		return nil, errors.New("Invalid PE headers size")
+	}
+
+	for i, sec := range layout.Sections(data) {
+		if sec.RawSize == 0 {
+			continue
+		}
+		if int(sec.RawOffset)+int(sec.RawSize) > len(data) {
+			return nil, fmt.Errorf("Section %d out of the file", i)
+		}
+		ans.sections = append(ans.sections, peSection{
+			Offset: sec.RawOffset,
+			Size:   sec.RawSize,
+		})
+	}
+
+	sort.Slice(ans.sections, func(i, j int) bool {
+		return ans.sections[i].Offset < ans.sections[j].Offset
+	})
+
+	return ans, nil
+}
+
+// Return true if the image contains a certificate table.
+func (p *PEImage) IsSigned() bool {
+	return p.CertTableSize > 0
+}
+
+// Calculate the Authenticode digest of the image. The checksum, the
+// certificate table directory and the certificate table are excluded.
+func (p *PEImage) Digest(h crypto.Hash) ([]byte, error) {
+	if !h.Available() {
+		return nil, fmt.Errorf("Hash algorithm %s not available", h.String())
+	}
+
+	d := h.New()
+	d.Write(p.Data[:p.checksumOffset])
+	d.Write(p.Data[p.checksumOffset+4 : p.certDirOffset])
+	d.Write(p.Data[p.certDirOffset+8 : p.sizeOfHeaders])
+
+	hashed := p.sizeOfHeaders
+	for _, s := range p.sections {
+		d.Write(p.Data[s.Offset : s.Offset+s.Size])
+		if int(s.Offset+s.Size) > hashed {
+			hashed = int(s.Offset + s.Size)
+		}
+	}
+
+	end := len(p.Data)
+	if p.IsSigned() {
+		end = int(p.CertTableOffset)
+	}
+	if end > hashed {
+		d.Write(p.Data[hashed:end])
+	}
+
+	return d.Sum(nil), nil
+}
+
+// Return the PKCS#7 blobs of the certificate table.
+func (p *PEImage) Signatures() ([][]byte, error) {
+	ans := [][]byte{}
+	if !p.IsSigned() {
+		return ans, nil
+	}
+
+	off := int(p.CertTableOffset)
+	end := off + int(p.CertTableSize)
+	for off+winCertHeaderSize <= end {
+		length := int(binary.LittleEndian.Uint32(p.Data[off:]))
+		certType := binary.LittleEndian.Uint16(p.Data[off+6:])
+		if length < winCertHeaderSize || off+length > end {
+			return ans, errors.New("Invalid certificate table entry")
+		}
+
+		if certType == winCertTypePKCS7 {
+			ans = append(ans, p.Data[off+winCertHeaderSize:off+length])
+		}
+
+		off += align8(length)
+	}
+
+	return ans, nil
+}
+
+// Pad the image to 8 bytes before the creation of the certificate
+// table. The padding is included in the Authenticode digest.
+func (p *PEImage) PrepareForSigning() error {
+	if p.IsSigned() {
+		if int(p.CertTableOffset+p.CertTableSize) != len(p.Data) {
+			return errors.New("The certificate table is not at the end of the image")
+		}
+		return nil
+	}
+
+	if pad := align8(len(p.Data)) - len(p.Data); pad > 0 {
+		p.Data = append(p.Data, make([]byte, pad)...)
+	}
+
+	return nil
+}
+
+// Append a PKCS#7 signature to the certificate table and update the
+// checksum of the image. The existing signatures are maintained.
+func (p *PEImage) AppendSignature(sig []byte) error {
+	err := p.PrepareForSigning()
+	if err != nil {
+		return err
+	}
+
+	if !p.IsSigned() {
+		p.CertTableOffset = uint32(len(p.Data))
+	}
+
+	entry := make([]byte, align8(winCertHeaderSize+len(sig)))
+	binary.LittleEndian.PutUint32(entry[0:], uint32(winCertHeaderSize+len(sig)))
+	binary.LittleEndian.PutUint16(entry[4:], winCertRevision2)
+	binary.LittleEndian.PutUint16(entry[6:], winCertTypePKCS7)
+	copy(entry[winCertHeaderSize:], sig)
+
+	p.Data = append(p.Data, entry...)
+	p.CertTableSize += uint32(len(entry))
+
+	binary.LittleEndian.PutUint32(p.Data[p.certDirOffset:], p.CertTableOffset)
+	binary.LittleEndian.PutUint32(p.Data[p.certDirOffset+4:], p.CertTableSize)
+	binary.LittleEndian.PutUint32(p.Data[p.checksumOffset:], pecoff.Checksum(p.Data, p.checksumOffset))
+
+	return nil
+}
@@ -0,0 +1,523 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package secureboot
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"unicode/utf16"
+)
+
+var (
+	oidSignedData      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidSpcIndirectData = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 2, 1, 4}
+	oidSpcPeImageData  = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 2, 1, 15}
+	oidContentType     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+
+	oidSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+
+	oidRSAEncryption   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidECDSAWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 1}
+	oidECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidECDSAWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidECDSAWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+)
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type rawCertificates struct {
+	Raw asn1.RawContent
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      contentInfo
+	Certificates     rawCertificates `asn1:"optional,tag:0"`
+	SignerInfos      []signerInfo    `asn1:"set"`
+}
+
+type issuerAndSerial struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type attribute struct {
+	Type  asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+type signerInfo struct {
+	Raw                       asn1.RawContent
+	Version                   int
+	IssuerAndSerialNumber     issuerAndSerial
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   []attribute `asn1:"optional,omitempty,tag:0"`
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+	UnauthenticatedAttributes []attribute `asn1:"optional,omitempty,tag:1"`
+}
+
+// SignerInfo with the authenticated attributes as encoded
+// by the signer.
+type rawSignerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     asn1.RawValue
+	DigestAlgorithm           asn1.RawValue
+	AuthenticatedAttributes   asn1.RawValue `asn1:"optional,tag:0"`
+	DigestEncryptionAlgorithm asn1.RawValue
+	EncryptedDigest           asn1.RawValue
+	UnauthenticatedAttributes asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type spcAttributeTypeAndValue struct {
+	Type  asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+type digestInfo struct {
+	DigestAlgorithm pkix.AlgorithmIdentifier
+	Digest          []byte
+}
+
+type spcIndirectDataContent struct {
+	Data          spcAttributeTypeAndValue
+	MessageDigest digestInfo
+}
+
+// Authenticode signature parsed from the certificate table.
+type Signature struct {
+	Hash         crypto.Hash
+	Digest       []byte
+	Certificates []*x509.Certificate
+	Signer       *x509.Certificate
+
+	signerInfo  signerInfo
+	signedAttrs []byte
+	contentData []byte
+}
+
+// Wrap the DER data with the explicit [0] tag of the ContentInfo.
+// The encoder ignores the explicit tag of a RawValue with FullBytes.
+func explicitContent(data []byte) (asn1.RawValue, error) {
+	wrapped, err := asn1.Marshal(asn1.RawValue{
+		Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true,
+		Bytes: data,
+	})
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+	return asn1.RawValue{FullBytes: wrapped}, nil
+}
+
+func hashOID(h crypto.Hash) (asn1.ObjectIdentifier, error) {
+	switch h {
+	case crypto.SHA1:
+		return oidSHA1, nil
+	case crypto.SHA256:
+		return oidSHA256, nil
+	case crypto.SHA384:
+		return oidSHA384, nil
+	case crypto.SHA512:
+		return oidSHA512, nil
+	}
+	return nil, fmt.Errorf("Unsupported hash %s", h.String())
+}
+
+// Return the ECDSA signature algorithm of the hash used to sign.
+func ecdsaOID(h crypto.Hash) (asn1.ObjectIdentifier, error) {
+	switch h {
+	case crypto.SHA1:
+		return oidECDSAWithSHA1, nil
+	case crypto.SHA256:
+		return oidECDSAWithSHA256, nil
+	case crypto.SHA384:
+		return oidECDSAWithSHA384, nil
+	case crypto.SHA512:
+		return oidECDSAWithSHA512, nil
+	}
+	return nil, fmt.Errorf("Unsupported hash %s for ECDSA", h.String())
+}
+
+func oidHash(oid asn1.ObjectIdentifier) (crypto.Hash, error) {
+	switch {
+	case oid.Equal(oidSHA1):
+		return crypto.SHA1, nil
+	case oid.Equal(oidSHA256):
+		return crypto.SHA256, nil
+	case oid.Equal(oidSHA384):
+		return crypto.SHA384, nil
+	case oid.Equal(oidSHA512):
+		return crypto.SHA512, nil
+	}
+	return 0, fmt.Errorf("Unsupported digest algorithm %s", oid.String())
+}
+
+func newAttribute(oid asn1.ObjectIdentifier, value interface{}) (attribute, error) {
+	data, err := asn1.Marshal(value)
+	if err != nil {
+		return attribute{}, err
+	}
+	return attribute{
+		Type:  oid,
+		Value: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: data},
+	}, nil
+}
+
+// Return the DER of the attributes as SET OF. This is the data signed.
+func marshalAttributes(attrs []attribute) ([]byte, error) {
+	encoded := [][]byte{}
+	for _, a := range attrs {
+		data, err := asn1.Marshal(a)
+		if err != nil {
+			return nil, err
+		}
+		encoded = append(encoded, data)
+	}
+
+	// DER requires the elements of SET OF sorted.
+	sort.Slice(encoded, func(i, j int) bool {
+		return bytes.Compare(encoded[i], encoded[j]) < 0
+	})
+
+	return asn1.Marshal(asn1.RawValue{
+		Tag:        asn1.TagSet,
+		IsCompound: true,
+		Bytes:      bytes.Join(encoded, nil),
+	})
+}
+
+// The SpcPeImageData with the obsolete file link used by the
+// Microsoft tools.
+func spcPeImageData() ([]byte, error) {
+	obsolete := []byte{}
+	for _, c := range utf16.Encode([]rune("<<<Obsolete>>>")) {
+		obsolete = append(obsolete, byte(c>>8), byte(c))
+	}
+
+	link, err := asn1.Marshal(asn1.RawValue{
+		Class: asn1.ClassContextSpecific, Tag: 2, IsCompound: true,
+		Bytes: append([]byte{0x80, byte(len(obsolete))}, obsolete...),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := asn1.Marshal(asn1.RawValue{
+		Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true,
+		Bytes: link,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	flags, err := asn1.Marshal(asn1.BitString{})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(asn1.RawValue{
+		Tag: asn1.TagSequence, IsCompound: true,
+		Bytes: append(flags, file...),
+	})
+}
+
+// Create the Authenticode PKCS#7 SignedData of the digest of a PE image.
+func CreateSignature(digest []byte, h crypto.Hash,
+	cert *x509.Certificate, key crypto.Signer) ([]byte, error) {
+
+	hOID, err := hashOID(h)
+	if err != nil {
+		return nil, err
+	}
+	hAlg := pkix.AlgorithmIdentifier{
+		Algorithm:  hOID,
+		Parameters: asn1.NullRawValue,
+	}
+
+	peData, err := spcPeImageData()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := asn1.Marshal(spcIndirectDataContent{
+		Data: spcAttributeTypeAndValue{
+			Type:  oidSpcPeImageData,
+			Value: asn1.RawValue{FullBytes: peData},
+		},
+		MessageDigest: digestInfo{
+			DigestAlgorithm: hAlg,
+			Digest:          digest,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The message digest is calculated over the content of the
+	// SpcIndirectDataContent without the tag and the length.
+	var raw asn1.RawValue
+	if _, err := asn1.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+	d := h.New()
+	d.Write(raw.Bytes)
+
+	attrContentType, err := newAttribute(oidContentType, oidSpcIndirectData)
+	if err != nil {
+		return nil, err
+	}
+	attrDigest, err := newAttribute(oidMessageDigest, d.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	attrs := []attribute{attrContentType, attrDigest}
+
+	signedAttrs, err := marshalAttributes(attrs)
+	if err != nil {
+		return nil, err
+	}
+	d = h.New()
+	d.Write(signedAttrs)
+
+	var encAlg pkix.AlgorithmIdentifier
+	switch key.Public().(type) {
+	case *rsa.PublicKey:
+		encAlg = pkix.AlgorithmIdentifier{
+			Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue,
+		}
+	case *ecdsa.PublicKey:
+		eOID, err := ecdsaOID(h)
+		if err != nil {
+			return nil, err
+		}
+		encAlg = pkix.AlgorithmIdentifier{Algorithm: eOID}
+	default:
+		return nil, errors.New("Unsupported private key type")
+	}
+
+	signature, err := key.Sign(rand.Reader, d.Sum(nil), h)
+	if err != nil {
+		return nil, fmt.Errorf("Error on sign the digest: %s", err.Error())
+	}
+
+	spcContent, err := explicitContent(content)
+	if err != nil {
+		return nil, err
+	}
+
+	certs, err := asn1.Marshal(asn1.RawValue{
+		Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true,
+		Bytes: cert.Raw,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sd := signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{hAlg},
+		ContentInfo: contentInfo{
+			ContentType: oidSpcIndirectData,
+			Content:     spcContent,
+		},
+		Certificates: rawCertificates{Raw: certs},
+		SignerInfos: []signerInfo{
+			{
+				Version: 1,
+				IssuerAndSerialNumber: issuerAndSerial{
+					Issuer:       asn1.RawValue{FullBytes: cert.RawIssuer},
+					SerialNumber: cert.SerialNumber,
+				},
+				DigestAlgorithm:           hAlg,
+				AuthenticatedAttributes:   attrs,
+				DigestEncryptionAlgorithm: encAlg,
+				EncryptedDigest:           signature,
+			},
+		},
+	}
+
+	sdData, err := asn1.Marshal(sd)
+	if err != nil {
+		return nil, err
+	}
+
+	sdContent, err := explicitContent(sdData)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     sdContent,
+	})
+}
+
+// Parse the Authenticode PKCS#7 SignedData.
+func ParseSignature(data []byte) (*Signature, error) {
+	var ci contentInfo
+	_, err := asn1.Unmarshal(data, &ci)
+	if err != nil {
+		return nil, fmt.Errorf("Error on parse PKCS#7 data: %s", err.Error())
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, errors.New("The PKCS#7 data is not SignedData")
+	}
+
+	var sd signedData
+	_, err = asn1.Unmarshal(ci.Content.Bytes, &sd)
+	if err != nil {
+		return nil, fmt.Errorf("Error on parse SignedData: %s", err.Error())
+	}
+
+	if !sd.ContentInfo.ContentType.Equal(oidSpcIndirectData) {
+		return nil, errors.New("The SignedData is not an Authenticode signature")
+	}
+	if len(sd.SignerInfos) != 1 {
+		return nil, fmt.Errorf("Unexpected number of signers: %d", len(sd.SignerInfos))
+	}
+
+	var content spcIndirectDataContent
+	_, err = asn1.Unmarshal(sd.ContentInfo.Content.Bytes, &content)
+	if err != nil {
+		return nil, fmt.Errorf("Error on parse SpcIndirectDataContent: %s", err.Error())
+	}
+
+	h, err := oidHash(content.MessageDigest.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw asn1.RawValue
+	_, err = asn1.Unmarshal(sd.ContentInfo.Content.Bytes, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	ans := &Signature{
+		Hash:         h,
+		Digest:       content.MessageDigest.Digest,
+		Certificates: []*x509.Certificate{},
+		signerInfo:   sd.SignerInfos[0],
+		contentData:  raw.Bytes,
+	}
+
+	// The signature is calculated over the attributes as encoded by
+	// the signer with the SET OF tag. Some signers don't sort them.
+	var rsi rawSignerInfo
+	_, err = asn1.Unmarshal(ans.signerInfo.Raw, &rsi)
+	if err != nil {
+		return nil, fmt.Errorf("Error on parse SignerInfo: %s", err.Error())
+	}
+	if len(rsi.AuthenticatedAttributes.FullBytes) > 0 {
+		ans.signedAttrs = append([]byte{}, rsi.AuthenticatedAttributes.FullBytes...)
+		ans.signedAttrs[0] = 0x20 | asn1.TagSet
+	}
+
+	if len(sd.Certificates.Raw) > 0 {
+		var certs asn1.RawValue
+		_, err = asn1.Unmarshal(sd.Certificates.Raw, &certs)
+		if err != nil {
+			return nil, err
+		}
+		ans.Certificates, err = x509.ParseCertificates(certs.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("Error on parse certificates: %s", err.Error())
+		}
+	}
+
+	si := ans.signerInfo
+	for idx, c := range ans.Certificates {
+		if bytes.Equal(c.RawIssuer, si.IssuerAndSerialNumber.Issuer.FullBytes) &&
+			c.SerialNumber.Cmp(si.IssuerAndSerialNumber.SerialNumber) == 0 {
+			ans.Signer = ans.Certificates[idx]
+			break
+		}
+	}
+
+	if ans.Signer == nil {
+		return nil, errors.New("No signer certificate found")
+	}
+
+	return ans, nil
+}
+
+// Check the signature of the signer over the authenticated attributes
+// and the digest of the content. The digest of the PE image must be
+// compared with the Digest field.
+func (s *Signature) CheckSignature() error {
+	si := s.signerInfo
+
+	h, err := oidHash(si.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	var messageDigest []byte
+	for _, a := range si.AuthenticatedAttributes {
+		if a.Type.Equal(oidMessageDigest) {
+			_, err = asn1.Unmarshal(a.Value.Bytes, &messageDigest)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if messageDigest == nil {
+		return errors.New("No message digest attribute found")
+	}
+
+	d := h.New()
+	d.Write(s.contentData)
+	if !bytes.Equal(d.Sum(nil), messageDigest) {
+		return errors.New("The message digest doesn't match the signed content")
+	}
+
+	d = h.New()
+	d.Write(s.signedAttrs)
+	hashed := d.Sum(nil)
+
+	switch pub := s.Signer.PublicKey.(type) {
+	case *rsa.PublicKey:
+		err = rsa.VerifyPKCS1v15(pub, h, hashed, si.EncryptedDigest)
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(pub, hashed, si.EncryptedDigest) {
+			err = errors.New("ECDSA verification failure")
+		}
+	default:
+		err = errors.New("Unsupported public key of the signer")
+	}
+
+	if err != nil {
+		return fmt.Errorf("Invalid signature: %s", err.Error())
+	}
+
+	return nil
+}
+
+// Check that the signer is the certificate in input or that
+// it's signed by the certificate in input.
+func (s *Signature) IsTrustedBy(cert *x509.Certificate) bool {
+	if s.Signer.Equal(cert) {
+		return true
+	}
+	return s.Signer.CheckSignatureFrom(cert) == nil
+}
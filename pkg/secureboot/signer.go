@@ -0,0 +1,260 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package secureboot
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type Signer struct {
+	Cert *x509.Certificate
+	Key  crypto.Signer
+	Hash crypto.Hash
+}
+
+type SignatureStatus struct {
+	File     string   `json:"file" yaml:"file"`
+	Signed   bool     `json:"signed" yaml:"signed"`
+	Valid    bool     `json:"valid" yaml:"valid"`
+	Trusted  bool     `json:"trusted" yaml:"trusted"`
+	Subjects []string `json:"subjects,omitempty" yaml:"subjects,omitempty"`
+	Error    string   `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func NewSigner(keyFile, certFile string) (*Signer, error) {
+	cert, err := LoadCertificate(certFile)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := LoadPrivateKey(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Signer{
+		Cert: cert,
+		Key:  key,
+		Hash: crypto.SHA256,
+	}, nil
+}
+
+func LoadCertificate(file string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("Error on read certificate %s: %s", file, err.Error())
+	}
+
+	// The certificates of the UEFI db are often in DER format.
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+
+	cert, err := x509.ParseCertificate(data)
+	if err != nil {
+		return nil, fmt.Errorf("Error on parse certificate %s: %s", file, err.Error())
+	}
+
+	return cert, nil
+}
+
+func LoadPrivateKey(file string) (crypto.Signer, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("Error on read private key %s: %s", file, err.Error())
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("No PEM data found in %s", file)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error on parse private key %s: %s", file, err.Error())
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("Unsupported private key %s", file)
+	}
+
+	return signer, nil
+}
+
+// Sign the PE image in memory. It returns false if the image is
+// already signed with the certificate of the signer.
+func (s *Signer) Sign(p *PEImage) (bool, error) {
+	status := Verify(p, []*x509.Certificate{s.Cert})
+	if status.Trusted {
+		return false, nil
+	}
+
+	err := p.PrepareForSigning()
+	if err != nil {
+		return false, err
+	}
+
+	digest, err := p.Digest(s.Hash)
+	if err != nil {
+		return false, err
+	}
+
+	sig, err := CreateSignature(digest, s.Hash, s.Cert, s.Key)
+	if err != nil {
+		return false, err
+	}
+
+	return true, p.AppendSignature(sig)
+}
+
+// Sign the PE image of the file in place. It returns false if the
+// file is already signed with the certificate of the signer.
+func (s *Signer) SignFile(file string) (bool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return false, fmt.Errorf("Error on read file %s: %s", file, err.Error())
+	}
+
+	p, err := ParsePEImage(data)
+	if err != nil {
+		return false, err
+	}
+
+	signed, err := s.Sign(p)
+	if err != nil || !signed {
+		return false, err
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return false, err
+	}
+
+	// Write the signed image with a temporary file to avoid
+	// a broken kernel image on errors.
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".macaronictl-sign-*")
+	if err != nil {
+		return false, fmt.Errorf("Error on create temporary file: %s", err.Error())
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(p.Data)
+	if err == nil {
+		err = tmp.Chmod(info.Mode().Perm())
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return false, fmt.Errorf("Error on write signed image: %s", err.Error())
+	}
+
+	err = os.Rename(tmp.Name(), file)
+	if err != nil {
+		return false, fmt.Errorf("Error on replace %s: %s", file, err.Error())
+	}
+
+	return true, nil
+}
+
+// Verify the Authenticode signatures of the PE image. Without
+// certificates a valid signature is considered trusted.
+func Verify(p *PEImage, certs []*x509.Certificate) *SignatureStatus {
+	ans := &SignatureStatus{
+		Signed:   p.IsSigned(),
+		Subjects: []string{},
+	}
+
+	blobs, err := p.Signatures()
+	if err != nil {
+		ans.Error = err.Error()
+		return ans
+	}
+	if len(blobs) == 0 {
+		if ans.Signed {
+			ans.Error = "No Authenticode signatures found"
+		}
+		return ans
+	}
+
+	digests := make(map[crypto.Hash][]byte, 0)
+
+	for _, blob := range blobs {
+		sig, err := ParseSignature(blob)
+		if err == nil {
+			err = sig.CheckSignature()
+		}
+		if err == nil {
+			digest, ok := digests[sig.Hash]
+			if !ok {
+				digest, err = p.Digest(sig.Hash)
+				digests[sig.Hash] = digest
+			}
+			if err == nil && string(digest) != string(sig.Digest) {
+				err = errors.New("The digest of the image doesn't match the signature")
+			}
+		}
+
+		if err != nil {
+			ans.Error = err.Error()
+			continue
+		}
+
+		ans.Valid = true
+		ans.Subjects = append(ans.Subjects, sig.Signer.Subject.String())
+
+		if len(certs) == 0 {
+			ans.Trusted = true
+		}
+		for _, c := range certs {
+			if sig.IsTrustedBy(c) {
+				ans.Trusted = true
+				break
+			}
+		}
+	}
+
+	if ans.Trusted {
+		ans.Error = ""
+	} else if ans.Valid && ans.Error == "" {
+		ans.Error = "No signature trusted by the certificates"
+	}
+
+	return ans
+}
+
+func VerifyFile(file string, certs []*x509.Certificate) *SignatureStatus {
+	var ans *SignatureStatus
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		ans = &SignatureStatus{Error: err.Error()}
+	} else {
+		p, err := ParsePEImage(data)
+		if err != nil {
+			ans = &SignatureStatus{Error: err.Error()}
+		} else {
+			ans = Verify(p, certs)
+		}
+	}
+
+	ans.File = file
+	return ans
+}
@@ -0,0 +1,255 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package secureboot_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"debug/pe"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/macaroni-os/macaronictl/pkg/secureboot"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// The images of the testdata directory are a minimal PE32+ image with
+// a single .text section (kernel.efi) and the same image signed by the
+// Authenticode implementation of relic (kernel.signed.efi) with the
+// certificate db.crt. The digest is the one calculated by relic.
+const fixtureDigest = "a8196cc70b48f6d69f15fc36e62932a239a186e442ca55669781f47bb7031c27"
+
+// Copy a file of the testdata directory to the directory in input.
+func copyFixture(name, dir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		return "", err
+	}
+	file := filepath.Join(dir, name)
+	return file, os.WriteFile(file, data, 0644)
+}
+
+// Write a RSA key and a self-signed certificate in PEM format.
+func writeTestKey(dir, name string) (string, string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", "", err
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return "", "", err
+	}
+
+	keyFile := filepath.Join(dir, name+".key")
+	certFile := filepath.Join(dir, name+".crt")
+
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}), 0600)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: der,
+	}), 0644)
+
+	return keyFile, certFile, err
+}
+
+var _ = Describe("Secure Boot", func() {
+
+	Context("Sign and verify", func() {
+
+		It("Sign a PE image", func() {
+			tmpdir, err := os.MkdirTemp("", "macaronictl-secureboot")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+
+			keyFile, certFile, err := writeTestKey(tmpdir, "db")
+			Expect(err).Should(BeNil())
+			_, otherCertFile, err := writeTestKey(tmpdir, "other")
+			Expect(err).Should(BeNil())
+
+			image, err := copyFixture("kernel.efi", tmpdir)
+			Expect(err).Should(BeNil())
+
+			signer, err := NewSigner(keyFile, certFile)
+			Expect(err).Should(BeNil())
+			otherCert, err := LoadCertificate(otherCertFile)
+			Expect(err).Should(BeNil())
+
+			status := VerifyFile(image, nil)
+			Expect(status.Signed).To(BeFalse())
+			Expect(status.Trusted).To(BeFalse())
+
+			signed, err := signer.SignFile(image)
+			Expect(err).Should(BeNil())
+			Expect(signed).To(BeTrue())
+
+			status = VerifyFile(image, []*x509.Certificate{signer.Cert})
+			Expect(status.Error).To(Equal(""))
+			Expect(status.Signed).To(BeTrue())
+			Expect(status.Valid).To(BeTrue())
+			Expect(status.Trusted).To(BeTrue())
+			Expect(status.Subjects).To(Equal([]string{"CN=db"}))
+
+			status = VerifyFile(image, []*x509.Certificate{otherCert})
+			Expect(status.Valid).To(BeTrue())
+			Expect(status.Trusted).To(BeFalse())
+
+			// The image is parsable by the PE reader and already signed.
+			f, err := os.Open(image)
+			Expect(err).Should(BeNil())
+			_, err = pe.NewFile(f)
+			f.Close()
+			Expect(err).Should(BeNil())
+
+			signed, err = signer.SignFile(image)
+			Expect(err).Should(BeNil())
+			Expect(signed).To(BeFalse())
+
+			// A modified image invalidates the signature.
+			data, err := os.ReadFile(image)
+			Expect(err).Should(BeNil())
+			data[0x401] = 'E'
+			p, err := ParsePEImage(data)
+			Expect(err).Should(BeNil())
+			status = Verify(p, []*x509.Certificate{signer.Cert})
+			Expect(status.Valid).To(BeFalse())
+			Expect(status.Trusted).To(BeFalse())
+		})
+
+		It("Sign with an ECDSA key", func() {
+			tmpdir, err := os.MkdirTemp("", "macaronictl-secureboot")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+
+			key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+			Expect(err).Should(BeNil())
+			tmpl := &x509.Certificate{
+				SerialNumber: big.NewInt(1),
+				Subject:      pkix.Name{CommonName: "db"},
+				NotBefore:    time.Now().Add(-time.Hour),
+				NotAfter:     time.Now().Add(time.Hour),
+			}
+			der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+			Expect(err).Should(BeNil())
+			cert, err := x509.ParseCertificate(der)
+			Expect(err).Should(BeNil())
+
+			image, err := copyFixture("kernel.efi", tmpdir)
+			Expect(err).Should(BeNil())
+
+			signer := &Signer{Cert: cert, Key: key, Hash: crypto.SHA384}
+			signed, err := signer.SignFile(image)
+			Expect(err).Should(BeNil())
+			Expect(signed).To(BeTrue())
+
+			status := VerifyFile(image, []*x509.Certificate{cert})
+			Expect(status.Error).To(Equal(""))
+			Expect(status.Valid).To(BeTrue())
+			Expect(status.Trusted).To(BeTrue())
+		})
+
+		It("Verify an image signed by relic", func() {
+			cert, err := LoadCertificate(filepath.Join("testdata", "db.crt"))
+			Expect(err).Should(BeNil())
+
+			status := VerifyFile(filepath.Join("testdata", "kernel.signed.efi"),
+				[]*x509.Certificate{cert})
+			Expect(status.Error).To(Equal(""))
+			Expect(status.Signed).To(BeTrue())
+			Expect(status.Valid).To(BeTrue())
+			Expect(status.Trusted).To(BeTrue())
+			Expect(status.Subjects).To(Equal([]string{"CN=Macaroni OS Test db"}))
+
+			data, err := os.ReadFile(filepath.Join("testdata", "kernel.signed.efi"))
+			Expect(err).Should(BeNil())
+			p, err := ParsePEImage(data)
+			Expect(err).Should(BeNil())
+			blobs, err := p.Signatures()
+			Expect(err).Should(BeNil())
+			Expect(len(blobs)).To(Equal(1))
+			sig, err := ParseSignature(blobs[0])
+			Expect(err).Should(BeNil())
+			Expect(sig.Hash).To(Equal(crypto.SHA256))
+			Expect(hex.EncodeToString(sig.Digest)).To(Equal(fixtureDigest))
+
+			digest, err := p.Digest(crypto.SHA256)
+			Expect(err).Should(BeNil())
+			Expect(digest).To(Equal(sig.Digest))
+
+			// The digest of the unsigned image and of our signature
+			// match the digest of relic.
+			data, err = os.ReadFile(filepath.Join("testdata", "kernel.efi"))
+			Expect(err).Should(BeNil())
+			p, err = ParsePEImage(data)
+			Expect(err).Should(BeNil())
+			digest, err = p.Digest(crypto.SHA256)
+			Expect(err).Should(BeNil())
+			Expect(hex.EncodeToString(digest)).To(Equal(fixtureDigest))
+
+			tmpdir, err := os.MkdirTemp("", "macaronictl-secureboot")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+			keyFile, certFile, err := writeTestKey(tmpdir, "db")
+			Expect(err).Should(BeNil())
+			signer, err := NewSigner(keyFile, certFile)
+			Expect(err).Should(BeNil())
+
+			_, err = signer.Sign(p)
+			Expect(err).Should(BeNil())
+			blobs, err = p.Signatures()
+			Expect(err).Should(BeNil())
+			Expect(len(blobs)).To(Equal(1))
+			sig, err = ParseSignature(blobs[0])
+			Expect(err).Should(BeNil())
+			Expect(hex.EncodeToString(sig.Digest)).To(Equal(fixtureDigest))
+		})
+
+		It("Skip files that aren't PE images", func() {
+			tmpdir, err := os.MkdirTemp("", "macaronictl-secureboot")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(tmpdir)
+
+			keyFile, certFile, err := writeTestKey(tmpdir, "db")
+			Expect(err).Should(BeNil())
+
+			image := filepath.Join(tmpdir, "vmlinuz")
+			Expect(os.WriteFile(image, make([]byte, 0x200), 0644)).Should(BeNil())
+
+			signer, err := NewSigner(keyFile, certFile)
+			Expect(err).Should(BeNil())
+
+			_, err = signer.SignFile(image)
+			Expect(err).To(Equal(ErrNotPEImage))
+		})
+	})
+})
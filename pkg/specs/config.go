@@ -1,5 +1,5 @@
 /*
-Copyright © 2021 Macaroni OS Linux
+Copyright © 2021-2026 Macaroni OS Linux
 See AUTHORS and LICENSE for the license details and contributors.
 */
 package specs
@@ -21,12 +21,15 @@ type MacaroniCtlConfig struct {
 	General   MacaroniCtlGeneral   `mapstructure:"general" json:"general,omitempty" yaml:"general,omitempty"`
 	Logging   MacaroniCtlLogging   `mapstructure:"logging" json:"logging,omitempty" yaml:"logging,omitempty"`
 	EnvUpdate MacaroniCtlEnvUpdate `mapstructure:"env-update,omitempty" json:"env-update,omitempty" yaml:"env-update,omitempty"`
+	Kernel    MacaroniCtlKernel    `mapstructure:"kernel,omitempty" json:"kernel,omitempty" yaml:"kernel,omitempty"`
 
 	KernelProfilesDir string `mapstructure:"kernel-profiles-dir,omitempty" json:"kernel-profiles-dir,omitempty" yaml:"kernel-profiles-dir,omitempty"`
 }
 
 type MacaroniCtlGeneral struct {
 	Debug bool `mapstructure:"debug,omitempty" json:"debug,omitempty" yaml:"debug,omitempty"`
+	// Root directory of the system to manage. It's used to manage chroots and images.
+	Rootfs string `mapstructure:"rootfs,omitempty" json:"rootfs,omitempty" yaml:"rootfs,omitempty"`
 }
 
 type MacaroniCtlEnvUpdate struct {
@@ -36,6 +39,55 @@ type MacaroniCtlEnvUpdate struct {
 	Systemd  bool `mapstructure:"systemd,omitempty" json:"systemd,omitempty" yaml:"systemd,omitempty"`
 }
 
+type MacaroniCtlKernel struct {
+	// Directory where are installed the kernel files.
+	BootDir string `mapstructure:"bootdir,omitempty" json:"bootdir,omitempty" yaml:"bootdir,omitempty"`
+	// Default backend used to build the initrd images: dracut, mkinitcpio, booster.
+	InitrdBuilder string `mapstructure:"initrd-builder,omitempty" json:"initrd-builder,omitempty" yaml:"initrd-builder,omitempty"`
+	// Default dracut options used when the kernel profile doesn't define them.
+	DracutArgs string `mapstructure:"dracut-args,omitempty" json:"dracut-args,omitempty" yaml:"dracut-args,omitempty"`
+	// Path of the grub.cfg file. Default is <bootdir>/grub/grub.cfg.
+	GrubCfg string `mapstructure:"grub-cfg,omitempty" json:"grub-cfg,omitempty" yaml:"grub-cfg,omitempty"`
+	// Directory of the kernel profiles. It overrides the kernel-profiles-dir option.
+	ProfilesDir string `mapstructure:"profiles-dir,omitempty" json:"profiles-dir,omitempty" yaml:"profiles-dir,omitempty"`
+	// Additional directories of kernel profiles layered in order over
+	// the profiles directory.
+	ExtraProfilesDirs []string `mapstructure:"extra-profiles-dirs,omitempty" json:"extra-profiles-dirs,omitempty" yaml:"extra-profiles-dirs,omitempty"`
+	// Retention policy of the kernels available in the boot directory.
+	Retention MacaroniCtlKernelRetention `mapstructure:"retention,omitempty" json:"retention,omitempty" yaml:"retention,omitempty"`
+	// Secure Boot signing of the kernel images and of the UKIs.
+	SecureBoot MacaroniCtlKernelSecureBoot `mapstructure:"secureboot,omitempty" json:"secureboot,omitempty" yaml:"secureboot,omitempty"`
+	// Early microcode stage of the initrd generation.
+	Microcode MacaroniCtlKernelMicrocode `mapstructure:"microcode,omitempty" json:"microcode,omitempty" yaml:"microcode,omitempty"`
+}
+
+type MacaroniCtlKernelRetention struct {
+	// Number of the newest kernels to keep for every kernel type.
+	Keep int `mapstructure:"keep" json:"keep" yaml:"keep"`
+	// Keep the kernel selected by the bzImage link.
+	KeepCurrent bool `mapstructure:"keep-current" json:"keep-current" yaml:"keep-current"`
+	// Keep the kernel selected by the bzImage.old link.
+	KeepOld bool `mapstructure:"keep-old" json:"keep-old" yaml:"keep-old"`
+}
+
+type MacaroniCtlKernelSecureBoot struct {
+	// Sign the kernel images after the switch and the initrd generation.
+	Sign bool `mapstructure:"sign" json:"sign" yaml:"sign"`
+	// Path of the PEM private key used to sign.
+	Key string `mapstructure:"key,omitempty" json:"key,omitempty" yaml:"key,omitempty"`
+	// Path of the PEM certificate of the signing key.
+	Cert string `mapstructure:"cert,omitempty" json:"cert,omitempty" yaml:"cert,omitempty"`
+}
+
+type MacaroniCtlKernelMicrocode struct {
+	// Mode of the early microcode: none, prepend, separate.
+	Mode string `mapstructure:"mode,omitempty" json:"mode,omitempty" yaml:"mode,omitempty"`
+	// Path of the cpuinfo file used to detect the CPU vendor.
+	Cpuinfo string `mapstructure:"cpuinfo,omitempty" json:"cpuinfo,omitempty" yaml:"cpuinfo,omitempty"`
+	// Directory of the microcode firmware files.
+	FirmwareDir string `mapstructure:"firmware-dir,omitempty" json:"firmware-dir,omitempty" yaml:"firmware-dir,omitempty"`
+}
+
 type MacaroniCtlLogging struct {
 	// Path of the logfile
 	Path string `mapstructure:"path,omitempty" json:"path,omitempty" yaml:"path,omitempty"`
@@ -74,6 +126,10 @@ func (c *MacaroniCtlConfig) GetEnvUpdate() *MacaroniCtlEnvUpdate {
 	return &c.EnvUpdate
 }
 
+func (c *MacaroniCtlConfig) GetKernel() *MacaroniCtlKernel {
+	return &c.Kernel
+}
+
 func (c *MacaroniCtlConfig) Unmarshal() error {
 	var err error
 
@@ -102,6 +158,7 @@ func (c *MacaroniCtlConfig) Yaml() ([]byte, error) {
 
 func GenDefault(viper *v.Viper) {
 	viper.SetDefault("general.debug", false)
+	viper.SetDefault("general.rootfs", "/")
 	viper.SetDefault("kernel-profiles-dir", "/etc/macaroni/kernels-profiles/")
 
 	viper.SetDefault("logging.level", "info")
@@ -115,10 +172,63 @@ func GenDefault(viper *v.Viper) {
 	viper.SetDefault("env-update.ldconfig", true)
 	viper.SetDefault("env-update.systemd", false)
 	viper.SetDefault("env-update.prelink", false)
+
+	viper.SetDefault("kernel.bootdir", "/boot")
+	viper.SetDefault("kernel.initrd-builder", "dracut")
+	viper.SetDefault("kernel.dracut-args",
+		"-H -q -f -o systemd -o systemd-initrd -o systemd-networkd -o dracut-systemd")
+	viper.SetDefault("kernel.grub-cfg", "")
+	viper.SetDefault("kernel.profiles-dir", "")
+	viper.SetDefault("kernel.retention.keep", 2)
+	viper.SetDefault("kernel.retention.keep-current", true)
+	viper.SetDefault("kernel.retention.keep-old", true)
+	viper.SetDefault("kernel.secureboot.sign", false)
+	viper.SetDefault("kernel.secureboot.key", "/etc/macaroni/secureboot/db.key")
+	viper.SetDefault("kernel.secureboot.cert", "/etc/macaroni/secureboot/db.crt")
+	viper.SetDefault("kernel.microcode.mode", "none")
+	viper.SetDefault("kernel.microcode.cpuinfo", "/proc/cpuinfo")
+	viper.SetDefault("kernel.microcode.firmware-dir", "/lib/firmware")
 }
 
 func (g *MacaroniCtlGeneral) HasDebug() bool {
 	return g.Debug
 }
 
-func (c *MacaroniCtlConfig) GetKernelProfilesDir() string { return c.KernelProfilesDir }
+func (g *MacaroniCtlGeneral) GetRootfs() string {
+	if g.Rootfs == "" {
+		return "/"
+	}
+	return g.Rootfs
+}
+
+func (c *MacaroniCtlConfig) GetKernelProfilesDir() string {
+	if c.Kernel.ProfilesDir != "" {
+		return c.Kernel.ProfilesDir
+	}
+	return c.KernelProfilesDir
+}
+
+func (k *MacaroniCtlKernel) GetInitrdBuilder() string { return k.InitrdBuilder }
+func (k *MacaroniCtlKernel) GetDracutArgs() string    { return k.DracutArgs }
+func (k *MacaroniCtlKernel) GetGrubCfg() string       { return k.GrubCfg }
+
+func (k *MacaroniCtlKernel) GetExtraProfilesDirs() []string { return k.ExtraProfilesDirs }
+
+func (k *MacaroniCtlKernel) GetRetention() *MacaroniCtlKernelRetention {
+	return &k.Retention
+}
+
+func (k *MacaroniCtlKernel) GetSecureBoot() *MacaroniCtlKernelSecureBoot {
+	return &k.SecureBoot
+}
+
+func (k *MacaroniCtlKernel) GetMicrocode() *MacaroniCtlKernelMicrocode {
+	return &k.Microcode
+}
+
+func (k *MacaroniCtlKernel) GetBootDir() string {
+	if k.BootDir == "" {
+		return "/boot"
+	}
+	return k.BootDir
+}
@@ -0,0 +1,122 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package uki
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/macaroni-os/macaronictl/pkg/utils/pecoff"
+)
+
+const (
+	// IMAGE_SCN_CNT_INITIALIZED_DATA | IMAGE_SCN_MEM_READ
+	peSectionDataFlags = 0x40000040
+)
+
+type PESection struct {
+	Name string
+	Data []byte
+}
+
+func NewPESection(name string, data []byte) *PESection {
+	return &PESection{
+		Name: name,
+		Data: data,
+	}
+}
+
+func alignUp(v, a uint32) uint32 {
+	if a == 0 {
+		return v
+	}
+	return (v + a - 1) / a * a
+}
+
+// Append the sections to the PE image in input and return the new
+// image. The section table of the input image must have enough space
+// to store the new section headers. An existing Authenticode signature
+// is dropped because it's no more valid.
+func AddPESections(image []byte, sections []*PESection) ([]byte, error) {
+	layout, err := pecoff.ParseLayout(image)
+	if err != nil {
+		return nil, err
+	}
+
+	tableEnd := layout.SectionsOffset +
+		(layout.NumSections+len(sections))*pecoff.SectionHeaderSize
+	if uint32(tableEnd) > layout.SizeOfHeaders {
+		return nil, errors.New("No space available on PE headers for the new sections")
+	}
+
+	var lastVA, lastRaw uint32 = 0, 0
+	for _, sec := range layout.Sections(image) {
+		if sec.RawOffset != 0 && uint32(tableEnd) > sec.RawOffset {
+			return nil, errors.New("No space available on PE headers for the new sections")
+		}
+
+		if sec.VirtualAddress+sec.VirtualSize > lastVA {
+			lastVA = sec.VirtualAddress + sec.VirtualSize
+		}
+		if sec.RawOffset+sec.RawSize > lastRaw {
+			lastRaw = sec.RawOffset + sec.RawSize
+		}
+	}
+
+	if lastRaw == 0 {
+		lastRaw = layout.SizeOfHeaders
+	}
+	if lastVA == 0 {
+		lastVA = alignUp(layout.SizeOfHeaders, layout.SectionAlignment)
+	}
+
+	if int(lastRaw) > len(image) {
+		return nil, errors.New("Invalid PE image size")
+	}
+
+	// Drop data after the last section (for example the certificate table).
+	ans := make([]byte, lastRaw)
+	copy(ans, image[:lastRaw])
+
+	if certDir := layout.DataDirEntryOffset(pecoff.CertTableIndex); certDir >= 0 {
+		binary.LittleEndian.PutUint32(ans[certDir:], 0)
+		binary.LittleEndian.PutUint32(ans[certDir+4:], 0)
+	}
+
+	va := alignUp(lastVA, layout.SectionAlignment)
+	for idx, s := range sections {
+		if len(s.Name) > 8 {
+			return nil, fmt.Errorf("Invalid section name %s", s.Name)
+		}
+
+		rawPtr := alignUp(uint32(len(ans)), layout.FileAlignment)
+		rawSize := alignUp(uint32(len(s.Data)), layout.FileAlignment)
+
+		ans = append(ans, make([]byte, int(rawPtr)-len(ans))...)
+		ans = append(ans, s.Data...)
+		ans = append(ans, make([]byte, int(rawSize)-len(s.Data))...)
+
+		hdr := make([]byte, pecoff.SectionHeaderSize)
+		copy(hdr[0:8], s.Name)
+		binary.LittleEndian.PutUint32(hdr[8:], uint32(len(s.Data)))
+		binary.LittleEndian.PutUint32(hdr[12:], va)
+		binary.LittleEndian.PutUint32(hdr[16:], rawSize)
+		binary.LittleEndian.PutUint32(hdr[20:], rawPtr)
+		binary.LittleEndian.PutUint32(hdr[36:], peSectionDataFlags)
+
+		copy(ans[layout.SectionsOffset+(layout.NumSections+idx)*pecoff.SectionHeaderSize:], hdr)
+
+		va = alignUp(va+uint32(len(s.Data)), layout.SectionAlignment)
+	}
+
+	binary.LittleEndian.PutUint16(ans[layout.CoffOffset+2:],
+		uint16(layout.NumSections+len(sections)))
+	binary.LittleEndian.PutUint32(ans[layout.SizeOfImageOffset():], va)
+	binary.LittleEndian.PutUint32(ans[layout.ChecksumOffset():],
+		pecoff.Checksum(ans, layout.ChecksumOffset()))
+
+	return ans, nil
+}
@@ -0,0 +1,91 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package uki_test
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+
+	. "github.com/macaroni-os/macaronictl/pkg/uki"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// Create a minimal PE32+ image with a single .text section.
+func newTestPEImage() []byte {
+	data := make([]byte, 0x600)
+	data[0] = 'M'
+	data[1] = 'Z'
+	binary.LittleEndian.PutUint32(data[0x3c:], 0x80)
+	copy(data[0x80:], "PE\x00\x00")
+
+	coff := 0x84
+	binary.LittleEndian.PutUint16(data[coff:], pe.IMAGE_FILE_MACHINE_AMD64)
+	binary.LittleEndian.PutUint16(data[coff+2:], 1)
+	binary.LittleEndian.PutUint16(data[coff+16:], 240)
+	binary.LittleEndian.PutUint16(data[coff+18:], 0x22)
+
+	opt := coff + 20
+	binary.LittleEndian.PutUint16(data[opt:], 0x20b)
+	binary.LittleEndian.PutUint32(data[opt+32:], 0x1000)
+	binary.LittleEndian.PutUint32(data[opt+36:], 0x200)
+	binary.LittleEndian.PutUint32(data[opt+56:], 0x2000)
+	binary.LittleEndian.PutUint32(data[opt+60:], 0x400)
+	binary.LittleEndian.PutUint16(data[opt+68:], 10)
+	binary.LittleEndian.PutUint32(data[opt+108:], 16)
+
+	sec := opt + 240
+	copy(data[sec:], ".text")
+	binary.LittleEndian.PutUint32(data[sec+8:], 0x10)
+	binary.LittleEndian.PutUint32(data[sec+12:], 0x1000)
+	binary.LittleEndian.PutUint32(data[sec+16:], 0x200)
+	binary.LittleEndian.PutUint32(data[sec+20:], 0x400)
+	binary.LittleEndian.PutUint32(data[sec+36:], 0x60000020)
+
+	return data
+}
+
+var _ = Describe("PE Test", func() {
+
+	Context("Add sections", func() {
+
+		It("Append UKI sections", func() {
+			image, err := AddPESections(newTestPEImage(), []*PESection{
+				NewPESection(".cmdline", []byte("root=/dev/sda2\x00")),
+				NewPESection(".linux", bytes.Repeat([]byte{0xaa}, 0x1234)),
+			})
+			Expect(err).Should(BeNil())
+
+			f, err := pe.NewFile(bytes.NewReader(image))
+			Expect(err).Should(BeNil())
+			Expect(len(f.Sections)).To(Equal(3))
+
+			cmdline := f.Section(".cmdline")
+			Expect(cmdline).ShouldNot(BeNil())
+			Expect(cmdline.VirtualAddress).To(Equal(uint32(0x2000)))
+			data, err := cmdline.Data()
+			Expect(err).Should(BeNil())
+			Expect(string(data[:cmdline.VirtualSize])).To(Equal("root=/dev/sda2\x00"))
+
+			linux := f.Section(".linux")
+			Expect(linux).ShouldNot(BeNil())
+			Expect(linux.VirtualAddress).To(Equal(uint32(0x3000)))
+			Expect(linux.VirtualSize).To(Equal(uint32(0x1234)))
+			Expect(linux.Offset % 0x200).To(Equal(uint32(0)))
+
+			opt := f.OptionalHeader.(*pe.OptionalHeader64)
+			Expect(opt.SizeOfImage).To(Equal(uint32(0x5000)))
+			Expect(opt.CheckSum).ShouldNot(Equal(uint32(0)))
+		})
+
+		It("Reject invalid images", func() {
+			_, err := AddPESections([]byte("not a pe"), nil)
+			Expect(err).ShouldNot(BeNil())
+		})
+	})
+
+})
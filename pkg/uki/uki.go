@@ -0,0 +1,144 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package uki
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/macaroni-os/macaronictl/pkg/kernel"
+	kernelspecs "github.com/macaroni-os/macaronictl/pkg/kernel/specs"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+)
+
+const (
+	DefaultEfiStub = "/usr/lib/systemd/boot/efi/linuxx64.efi.stub"
+)
+
+// Unified Kernel Image builder. The UKI is created adding the
+// kernel, initrd, cmdline and os-release sections to the EFI stub.
+type UKIBuilder struct {
+	Stub          string
+	EspDir        string
+	Cmdline       string
+	OsReleaseFile string
+	// Root directory of the system where read the default cmdline.
+	Rootfs string
+	DryRun bool
+}
+
+func NewUKIBuilder(stub, espDir string, dryRun bool) *UKIBuilder {
+	ans := &UKIBuilder{
+		Stub:          stub,
+		EspDir:        espDir,
+		OsReleaseFile: "/etc/os-release",
+		DryRun:        dryRun,
+	}
+
+	if ans.Stub == "" {
+		ans.Stub = DefaultEfiStub
+	}
+
+	return ans
+}
+
+// Return the path of the UKI file related to the kernel files in input.
+func (u *UKIBuilder) GetUKIFile(kf *kernelspecs.KernelFiles) string {
+	osId := "linux"
+	fields, err := utils.ParseOsReleaseFile(u.OsReleaseFile)
+	if err == nil && fields["ID"] != "" {
+		osId = fields["ID"]
+	}
+
+	return filepath.Join(u.EspDir, "EFI", "Linux",
+		osId+"-"+kf.Kernel.GetFilename()+".efi")
+}
+
+func (u *UKIBuilder) Build(kf *kernelspecs.KernelFiles, bootDir string) (string, error) {
+	if kf == nil || kf.Kernel == nil {
+		return "", errors.New("Invalid kernel file")
+	}
+
+	if kf.Initrd == nil {
+		return "", fmt.Errorf("No initrd image available for kernel %s",
+			kf.Kernel.GetFilename())
+	}
+
+	ukiFile := u.GetUKIFile(kf)
+
+	cmdline := u.Cmdline
+	if cmdline == "" {
+		cmdline = kernel.NewCmdline(u.Rootfs).ResolveKernelFiles(kf)
+	}
+
+	if u.DryRun {
+		fmt.Println(fmt.Sprintf(
+			"[dry-run mode] creating UKI %s with stub %s and cmdline '%s'",
+			ukiFile, u.Stub, cmdline))
+		return ukiFile, nil
+	}
+
+	stub, err := os.ReadFile(u.Stub)
+	if err != nil {
+		return "", fmt.Errorf("Error on read EFI stub %s: %s", u.Stub, err.Error())
+	}
+
+	kernelData, err := os.ReadFile(filepath.Join(bootDir, kf.Kernel.GetFilename()))
+	if err != nil {
+		return "", err
+	}
+
+	initrdData, err := os.ReadFile(filepath.Join(bootDir, kf.Initrd.GetFilename()))
+	if err != nil {
+		return "", err
+	}
+
+	// The separate early microcode is prepended to the initrd section.
+	if kf.GetMicrocodeFile() != "" {
+		ucode, err := os.ReadFile(filepath.Join(bootDir, kf.GetMicrocodeFile()))
+		if err != nil {
+			return "", err
+		}
+		initrdData = append(ucode, initrdData...)
+	}
+
+	osrel, err := os.ReadFile(u.OsReleaseFile)
+	if err != nil {
+		return "", fmt.Errorf("Error on read os-release file %s: %s",
+			u.OsReleaseFile, err.Error())
+	}
+
+	// The sections order follows the systemd ukify tool.
+	sections := []*PESection{
+		NewPESection(".osrel", osrel),
+		NewPESection(".cmdline", []byte(cmdline+"\x00")),
+		NewPESection(".uname", []byte(kf.Kernel.GetRelease())),
+		NewPESection(".initrd", initrdData),
+		NewPESection(".linux", kernelData),
+	}
+
+	data, err := AddPESections(stub, sections)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Print(fmt.Sprintf("Creating UKI %s...", ukiFile))
+
+	err = os.MkdirAll(filepath.Dir(ukiFile), 0755)
+	if err != nil {
+		return "", err
+	}
+
+	err = os.WriteFile(ukiFile, data, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Println("DONE")
+
+	return ukiFile, nil
+}
@@ -1,5 +1,5 @@
 /*
-Copyright © 2021-2023 Macaroni OS Linux
+Copyright © 2021-2026 Macaroni OS Linux
 See AUTHORS and LICENSE for the license details and contributors.
 */
 package utils
@@ -12,6 +12,13 @@ import (
 // Return itself if the binary is not present in the
 // default paths (/sbin, /bin, /usr/sbin, /usr/bin)
 func TryResolveBinaryAbsPath(b string) string {
+	return TryResolveBinaryAbsPathRootfs("/", b)
+}
+
+// Try to resolve the abs path of the binary available
+// under the rootfs. The path returned is the path
+// inside the rootfs.
+func TryResolveBinaryAbsPathRootfs(rootfs, b string) string {
 	ans := b
 	possiblePaths := []string{
 		"/sbin",
@@ -22,7 +29,7 @@ func TryResolveBinaryAbsPath(b string) string {
 
 	for _, s := range possiblePaths {
 		abs := filepath.Join(s, b)
-		if Exists(abs) {
+		if Exists(RootfsPath(rootfs, abs)) {
 			ans = filepath.Join(abs)
 			break
 		}
@@ -0,0 +1,88 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package compress
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
+)
+
+const (
+	CompressionNone  = "none"
+	CompressionGzip  = "gzip"
+	CompressionXz    = "xz"
+	CompressionZstd  = "zstd"
+	CompressionLz4   = "lz4"
+	CompressionBzip2 = "bzip2"
+)
+
+type Magic struct {
+	Compression string
+	Magic       []byte
+}
+
+// Magic bytes of the supported compressions.
+var Magics = []Magic{
+	{CompressionGzip, []byte{0x1f, 0x8b}},
+	{CompressionXz, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+	{CompressionZstd, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	// Legacy format used by the kernel and the frame format.
+	{CompressionLz4, []byte{0x02, 0x21, 0x4c, 0x18}},
+	{CompressionLz4, []byte{0x04, 0x22, 0x4d, 0x18}},
+	{CompressionBzip2, []byte{'B', 'Z', 'h'}},
+}
+
+// Return the compression of the data from the magic bytes.
+func DetectCompression(magic []byte) (string, error) {
+	for _, m := range Magics {
+		if bytes.HasPrefix(magic, m.Magic) {
+			return m.Compression, nil
+		}
+	}
+	return "", fmt.Errorf("Unknown data format with magic %x", magic)
+}
+
+// Return the reader of the decompressed data. All formats are
+// decompressed natively without external tools.
+func NewDecompressor(compression string, r io.Reader) (io.ReadCloser, error) {
+	switch compression {
+	case CompressionNone:
+		return io.NopCloser(r), nil
+	case CompressionGzip:
+		z, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		// The padding after the stream isn't a gzip member.
+		z.Multistream(false)
+		return z, nil
+	case CompressionBzip2:
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	case CompressionXz:
+		z, err := xz.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("Error on decompress xz data: %s", err.Error())
+		}
+		return io.NopCloser(z), nil
+	case CompressionZstd:
+		z, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("Error on decompress zstd data: %s", err.Error())
+		}
+		return z.IOReadCloser(), nil
+	case CompressionLz4:
+		// The reader supports also the legacy format of the kernel.
+		return io.NopCloser(lz4.NewReader(r)), nil
+	default:
+		return nil, fmt.Errorf("Unsupported compression %s", compression)
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package compress_test
+
+import (
+	"bytes"
+	"io"
+
+	. "github.com/macaroni-os/macaronictl/pkg/utils/compress"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// The string "macaroni\n" compressed by the command line tools
+// gzip, bzip2, xz, zstd and lz4 (frame and legacy format).
+var compressedData = map[string][][]byte{
+	CompressionGzip: {{
+		0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x03, 0xcb, 0x4d,
+		0x4c, 0x4e, 0x2c, 0xca, 0xcf, 0xcb, 0xe4, 0x02, 0x00, 0xdf, 0xd3, 0x66,
+		0xd8, 0x09, 0x00, 0x00, 0x00,
+	}},
+	CompressionBzip2: {{
+		0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x92, 0xdd,
+		0xa3, 0xee, 0x00, 0x00, 0x02, 0xc1, 0x80, 0x00, 0x10, 0x28, 0x23, 0x90,
+		0x00, 0x20, 0x00, 0x22, 0x03, 0xd2, 0x61, 0x0c, 0x08, 0xe0, 0x12, 0xf6,
+		0x41, 0x77, 0x24, 0x53, 0x85, 0x09, 0x09, 0x2d, 0xda, 0x3e, 0xe0,
+	}},
+	CompressionXz: {{
+		0xfd, 0x37, 0x7a, 0x58, 0x5a, 0x00, 0x00, 0x04, 0xe6, 0xd6, 0xb4, 0x46,
+		0x04, 0xc0, 0x0d, 0x09, 0x21, 0x01, 0x16, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x5f, 0x4f, 0x33, 0xe4, 0x01, 0x00, 0x08, 0x6d,
+		0x61, 0x63, 0x61, 0x72, 0x6f, 0x6e, 0x69, 0x0a, 0x00, 0x00, 0x00, 0x00,
+		0xd6, 0x9b, 0x7e, 0x7c, 0x4b, 0x4f, 0x47, 0x95, 0x00, 0x01, 0x29, 0x09,
+		0x64, 0x92, 0x1c, 0x1d, 0x1f, 0xb6, 0xf3, 0x7d, 0x01, 0x00, 0x00, 0x00,
+		0x00, 0x04, 0x59, 0x5a,
+	}},
+	CompressionZstd: {{
+		0x28, 0xb5, 0x2f, 0xfd, 0x04, 0x58, 0x49, 0x00, 0x00, 0x6d, 0x61, 0x63,
+		0x61, 0x72, 0x6f, 0x6e, 0x69, 0x0a, 0x77, 0x1b, 0x3c, 0x31,
+	}},
+	CompressionLz4: {{
+		0x04, 0x22, 0x4d, 0x18, 0x64, 0x40, 0xa7, 0x09, 0x00, 0x00, 0x80, 0x6d,
+		0x61, 0x63, 0x61, 0x72, 0x6f, 0x6e, 0x69, 0x0a, 0x00, 0x00, 0x00, 0x00,
+		0x5c, 0x5a, 0x4f, 0x6a,
+	}, {
+		0x02, 0x21, 0x4c, 0x18, 0x0a, 0x00, 0x00, 0x00, 0x90, 0x6d, 0x61, 0x63,
+		0x61, 0x72, 0x6f, 0x6e, 0x69, 0x0a,
+	}},
+}
+
+func decompress(compression string, data []byte) ([]byte, error) {
+	r, err := NewDecompressor(compression, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	ans, err := io.ReadAll(r)
+	if cerr := r.Close(); err == nil {
+		err = cerr
+	}
+	return ans, err
+}
+
+var _ = Describe("Compress Test", func() {
+
+	Context("Decompressors", func() {
+
+		It("Detect compression", func() {
+			for compression, blobs := range compressedData {
+				for _, data := range blobs {
+					c, err := DetectCompression(data[:8])
+					Expect(err).Should(BeNil())
+					Expect(c).To(Equal(compression))
+				}
+			}
+
+			_, err := DetectCompression([]byte("070701"))
+			Expect(err).ShouldNot(BeNil())
+		})
+
+		It("Decompress the data of the tools", func() {
+			for compression, blobs := range compressedData {
+				for _, data := range blobs {
+					out, err := decompress(compression, data)
+					Expect(err).Should(BeNil(), compression)
+					Expect(string(out)).To(Equal("macaroni\n"), compression)
+				}
+			}
+
+			out, err := decompress(CompressionNone, []byte("macaroni\n"))
+			Expect(err).Should(BeNil())
+			Expect(string(out)).To(Equal("macaroni\n"))
+		})
+
+		It("Decompress large data", func() {
+			data := bytes.Repeat([]byte("macaroni os linux\n"), 100000)
+
+			buf := bytes.NewBuffer(nil)
+			xw, err := xz.NewWriter(buf)
+			Expect(err).Should(BeNil())
+			xw.Write(data)
+			Expect(xw.Close()).Should(BeNil())
+			out, err := decompress(CompressionXz, buf.Bytes())
+			Expect(err).Should(BeNil())
+			Expect(out).To(Equal(data))
+
+			buf.Reset()
+			zw, err := zstd.NewWriter(buf)
+			Expect(err).Should(BeNil())
+			zw.Write(data)
+			Expect(zw.Close()).Should(BeNil())
+			out, err = decompress(CompressionZstd, buf.Bytes())
+			Expect(err).Should(BeNil())
+			Expect(out).To(Equal(data))
+
+			buf.Reset()
+			lw := lz4.NewWriter(buf)
+			Expect(lw.Apply(lz4.LegacyOption(true))).Should(BeNil())
+			lw.Write(data)
+			Expect(lw.Close()).Should(BeNil())
+			out, err = decompress(CompressionLz4, buf.Bytes())
+			Expect(err).Should(BeNil())
+			Expect(out).To(Equal(data))
+		})
+
+		It("Reject invalid data", func() {
+			_, err := decompress(CompressionXz, []byte("invalid data"))
+			Expect(err).ShouldNot(BeNil())
+			_, err = decompress(CompressionZstd, []byte("invalid data"))
+			Expect(err).ShouldNot(BeNil())
+			_, err = NewDecompressor("rar", bytes.NewReader(nil))
+			Expect(err).ShouldNot(BeNil())
+		})
+	})
+
+})
@@ -0,0 +1,177 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package pecoff
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	Signature         = "PE\x00\x00"
+	CoffHeaderSize    = 20
+	SectionHeaderSize = 40
+
+	Magic32     = 0x10b
+	Magic32Plus = 0x20b
+
+	// Index of the certificate table in the data directories.
+	CertTableIndex = 4
+)
+
+var ErrNotPEImage = errors.New("The file is not a PE image")
+
+// Offsets and fields of the PE/COFF headers used to patch
+// an image.
+type Layout struct {
+	CoffOffset       int
+	OptOffset        int
+	OptSize          int
+	SectionsOffset   int
+	NumSections      int
+	SectionAlignment uint32
+	FileAlignment    uint32
+	SizeOfHeaders    uint32
+	DataDirOffset    int
+	NumDataDirs      uint32
+}
+
+type SectionHeader struct {
+	Name           string
+	VirtualSize    uint32
+	VirtualAddress uint32
+	RawSize        uint32
+	RawOffset      uint32
+}
+
+func ParseLayout(data []byte) (*Layout, error) {
+	if len(data) < 0x40 || data[0] != 'M' || data[1] != 'Z' {
+		return nil, ErrNotPEImage
+	}
+
+	ans := &Layout{}
+	peOffset := int(binary.LittleEndian.Uint32(data[0x3c:]))
+	if peOffset+4+CoffHeaderSize > len(data) ||
+		string(data[peOffset:peOffset+4]) != Signature {
+		return nil, ErrNotPEImage
+	}
+
+	ans.CoffOffset = peOffset + 4
+	ans.NumSections = int(binary.LittleEndian.Uint16(data[ans.CoffOffset+2:]))
+	ans.OptSize = int(binary.LittleEndian.Uint16(data[ans.CoffOffset+16:]))
+	ans.OptOffset = ans.CoffOffset + CoffHeaderSize
+	ans.SectionsOffset = ans.OptOffset + ans.OptSize
+
+	if ans.SectionsOffset+ans.NumSections*SectionHeaderSize > len(data) {
+		return nil, errors.New("Invalid PE section table")
+	}
+
+	if ans.OptSize < 2 {
+		return nil, errors.New("Invalid PE optional header")
+	}
+
+	var numDataDirsOffset int
+	magic := binary.LittleEndian.Uint16(data[ans.OptOffset:])
+	switch magic {
+	case Magic32Plus:
+		numDataDirsOffset = ans.OptOffset + 108
+		ans.DataDirOffset = ans.OptOffset + 112
+	case Magic32:
+		numDataDirsOffset = ans.OptOffset + 92
+		ans.DataDirOffset = ans.OptOffset + 96
+	default:
+		return nil, fmt.Errorf("Unsupported optional header magic 0x%x", magic)
+	}
+
+	if ans.DataDirOffset > ans.SectionsOffset {
+		return nil, errors.New("Invalid PE optional header")
+	}
+
+	// The offsets of these fields are equal for PE32 and PE32+.
+	ans.SectionAlignment = binary.LittleEndian.Uint32(data[ans.OptOffset+32:])
+	ans.FileAlignment = binary.LittleEndian.Uint32(data[ans.OptOffset+36:])
+	ans.SizeOfHeaders = binary.LittleEndian.Uint32(data[ans.OptOffset+60:])
+
+	// Ignore the data directories that don't fit the optional header.
+	ans.NumDataDirs = binary.LittleEndian.Uint32(data[numDataDirsOffset:])
+	if maxDirs := uint32(ans.SectionsOffset-ans.DataDirOffset) / 8; ans.NumDataDirs > maxDirs {
+		ans.NumDataDirs = maxDirs
+	}
+
+	return ans, nil
+}
+
+// Return the offset of the checksum field.
+func (l *Layout) ChecksumOffset() int {
+	return l.OptOffset + 64
+}
+
+// Return the offset of the SizeOfImage field.
+func (l *Layout) SizeOfImageOffset() int {
+	return l.OptOffset + 56
+}
+
+// Return the offset of the data directory entry or -1 if the
+// image doesn't have it.
+func (l *Layout) DataDirEntryOffset(idx int) int {
+	if idx < 0 || uint32(idx) >= l.NumDataDirs {
+		return -1
+	}
+	return l.DataDirOffset + idx*8
+}
+
+// Read the headers of the section table. The size of the table is
+// already validated by ParseLayout.
+func (l *Layout) Sections(data []byte) []SectionHeader {
+	ans := make([]SectionHeader, 0, l.NumSections)
+
+	for i := 0; i < l.NumSections; i++ {
+		off := l.SectionsOffset + i*SectionHeaderSize
+		name := data[off : off+8]
+		for n := range name {
+			if name[n] == 0 {
+				name = name[:n]
+				break
+			}
+		}
+
+		ans = append(ans, SectionHeader{
+			Name:           string(name),
+			VirtualSize:    binary.LittleEndian.Uint32(data[off+8:]),
+			VirtualAddress: binary.LittleEndian.Uint32(data[off+12:]),
+			RawSize:        binary.LittleEndian.Uint32(data[off+16:]),
+			RawOffset:      binary.LittleEndian.Uint32(data[off+20:]),
+		})
+	}
+
+	return ans
+}
+
+// Compute the PE image checksum as done by the Windows imagehlp
+// CheckSumMappedFile function.
+func Checksum(data []byte, checksumOffset int) uint32 {
+	var sum uint64 = 0
+
+	for i := 0; i < len(data); i += 2 {
+		if i == checksumOffset || i == checksumOffset+2 {
+			continue
+		}
+
+		var w uint64
+		if i+1 < len(data) {
+			w = uint64(binary.LittleEndian.Uint16(data[i:]))
+		} else {
+			w = uint64(data[i])
+		}
+
+		sum += w
+		sum = (sum & 0xffff) + (sum >> 16)
+	}
+
+	sum = (sum & 0xffff) + (sum >> 16)
+
+	return uint32(sum) + uint32(len(data))
+}
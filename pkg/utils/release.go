@@ -1,5 +1,5 @@
 /*
-Copyright © 2021 Macaroni OS Linux
+Copyright © 2021-2026 Macaroni OS Linux
 See AUTHORS and LICENSE for the license details and contributors.
 */
 package utils
@@ -11,7 +11,12 @@ import (
 )
 
 func OsRelease() (string, error) {
-	mosReleaseFile := "/etc/macaroni/release"
+	return OsReleaseRootfs("/")
+}
+
+// Read the Macaroni OS release of the system under the rootfs in input.
+func OsReleaseRootfs(rootfs string) (string, error) {
+	mosReleaseFile := RootfsPath(rootfs, "/etc/macaroni/release")
 	release := ""
 
 	_, err := os.Stat(mosReleaseFile)
@@ -29,3 +34,41 @@ func OsRelease() (string, error) {
 
 	return release, nil
 }
+
+// Parse an os-release file (for example /etc/os-release)
+// and return the map of the defined fields.
+func ParseOsReleaseFile(file string) (map[string]string, error) {
+	ans := make(map[string]string, 0)
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return ans, err
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		idx := strings.Index(line, "=")
+		if idx <= 0 {
+			continue
+		}
+
+		ans[line[0:idx]] = strings.Trim(line[idx+1:], `"'`)
+	}
+
+	return ans, nil
+}
+
+// Retrieve the release of the running kernel
+// (the same value returned by uname -r).
+func RunningKernelRelease() (string, error) {
+	content, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(content)), nil
+}
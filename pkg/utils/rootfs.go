@@ -0,0 +1,66 @@
+/*
+Copyright © 2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package utils
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Return true if the rootfs in input is not the root of the running system.
+func IsChrootRootfs(rootfs string) bool {
+	return rootfs != "" && filepath.Clean(rootfs) != "/"
+}
+
+// Return the path of the file under the rootfs.
+func RootfsPath(rootfs, path string) string {
+	if !IsChrootRootfs(rootfs) || path == "" {
+		return path
+	}
+
+	rootfs = filepath.Clean(rootfs)
+	if path == rootfs || strings.HasPrefix(path, rootfs+"/") {
+		// POST: the path is already under the rootfs.
+		return path
+	}
+
+	return filepath.Join(rootfs, path)
+}
+
+// Return the path of the file as visible inside the rootfs.
+func RootfsRelPath(rootfs, path string) string {
+	if !IsChrootRootfs(rootfs) {
+		return path
+	}
+
+	rootfs = filepath.Clean(rootfs)
+	if path == rootfs {
+		return "/"
+	}
+	if strings.HasPrefix(path, rootfs+"/") {
+		return strings.TrimPrefix(path, rootfs)
+	}
+
+	return path
+}
+
+// Return the command to run inside the rootfs with chroot.
+// The paths of the arguments must be relative to the rootfs.
+func ChrootArgs(rootfs string, args []string) []string {
+	if !IsChrootRootfs(rootfs) {
+		return args
+	}
+
+	ans := []string{
+		TryResolveBinaryAbsPath("chroot"), filepath.Clean(rootfs),
+	}
+	if len(args) > 0 {
+		// Resolve the binary with the paths of the rootfs.
+		ans = append(ans, TryResolveBinaryAbsPathRootfs(rootfs, filepath.Base(args[0])))
+		ans = append(ans, args[1:]...)
+	}
+
+	return ans
+}
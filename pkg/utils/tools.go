@@ -8,6 +8,7 @@ import (
 	"io"
 	"io/fs"
 	"os"
+	"path/filepath"
 )
 
 func KeyInList(key string, arr *[]string) bool {
@@ -95,3 +96,33 @@ func CopyFile(srcpath, dstpath string) (err error) {
 	_, err = io.Copy(w, r)
 	return err
 }
+
+// CopyDir copies recursively the directory srcdir to dstdir.
+// The symbolic links are recreated with the same target.
+func CopyDir(srcdir, dstdir string) error {
+	return filepath.WalkDir(srcdir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(srcdir, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dstdir, rel)
+
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		if d.Type()&fs.ModeSymlink != 0 {
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		}
+
+		return CopyFile(path, target)
+	})
+}